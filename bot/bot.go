@@ -1,47 +1,74 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"os/signal"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/commands"
+	"github.com/nerdwerx/daggerbot/bot/discordapi"
 	"github.com/nerdwerx/daggerbot/bot/handlers"
+	"github.com/nerdwerx/daggerbot/bot/scheduler"
 	"github.com/nerdwerx/daggerbot/config"
 )
 
-func startup() {
+func startup() error {
 	// Load configuration from environment variables or other sources
 	config.Token = os.Getenv("DISCORD_AUTH_TOKEN")
 	if config.Token == "" {
-		log.Fatal("DISCORD_AUTH_TOKEN is not set")
+		return errors.New("DISCORD_AUTH_TOKEN is not set")
 	}
 	config.Prefix = os.Getenv("DISCORD_BOT_PREFIX")
 	if config.Prefix == "" {
 		config.Prefix = "!" // Default prefix
 	}
 	log.Printf("Bot Prefix set to %q", config.Prefix)
+
+	backend, dir := os.Getenv("DAGGERBOT_STORAGE"), os.Getenv("DAGGERBOT_DATA_DIR")
+	if err := config.OpenStorage(backend, dir); err != nil {
+		return fmt.Errorf("error opening storage: %w", err)
+	}
 	log.Printf("Configuration loaded")
+	return nil
 }
 
-func Run() error {
+// Run connects to Discord and serves commands until ctx is cancelled.
+func Run(ctx context.Context) error {
 	// Initialize our config vars
-	startup()
+	if err := startup(); err != nil {
+		return err
+	}
 
 	// create a session
 	discord, err := discordgo.New("Bot " + config.Token)
 	if err != nil {
-		return fmt.Errorf("error creating Discord session: %w", err)
+		return errors.Join(fmt.Errorf("error creating Discord session: %w", err), closeStorage())
 	}
 
 	if config.Debug {
 		discord.Debug = true
 	}
 
+	// set up session reminders before any command can schedule one, picking up jobs from before a restart
+	sched := scheduler.New(scheduler.RealClock())
+	if err := sched.Load(); err != nil {
+		log.Printf("Error loading scheduled jobs: %v", err)
+	}
+	commands.RegisterReminders(sched, discordapi.Wrap(discord))
+
 	// add a event handlers
 	discord.AddHandler(handlers.OnReady)
-	discord.AddHandler(handlers.OnMessage)
+	discord.AddHandler(handlers.OnMessage(sched))
+	discord.AddHandler(handlers.OnInteraction(sched))
+	discord.AddHandler(handlers.OnGuildCreate)
+	discord.AddHandler(handlers.OnGuildUpdate)
+	discord.AddHandler(handlers.OnGuildDelete)
+	discord.AddHandler(handlers.OnGuildRoleCreate)
+	discord.AddHandler(handlers.OnGuildRoleUpdate)
+	discord.AddHandler(handlers.OnGuildRoleDelete)
 
 	// Set our permissions
 	discord.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsAllWithoutPrivileged)
@@ -50,24 +77,40 @@ func Run() error {
 
 	// open session
 	if err := discord.Open(); err != nil {
-		return fmt.Errorf("error opening connection: %w", err)
+		return errors.Join(fmt.Errorf("error opening connection: %w", err), closeStorage())
 	}
 
-	defer func() {
-		if err := discord.Close(); err != nil { // close session, after function termination
-			log.Fatal("Error closing Discord session")
-		}
+	// start the scheduler for session reminders
+	schedCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		sched.Run(schedCtx)
+		close(done)
 	}()
 
-	// keep bot running untill we're interrupted (ctrl + C)
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	<-stop
+	<-ctx.Done()
 
 	log.Println("Bot gracefully shutting down...")
+	cancel()
+	<-done
+
+	// Stop taking events before the storage they write to goes away
+	var errs []error
+	if err := discord.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("error closing Discord session: %w", err))
+	}
 	if err := config.SaveGuilds(); err != nil {
 		log.Printf("Error saving guild configurations: %v", err)
 	}
+	errs = append(errs, closeStorage())
 
+	return errors.Join(errs...)
+}
+
+// closeStorage closes the storage opened by startup.
+func closeStorage() error {
+	if err := config.Storage.Close(); err != nil {
+		return fmt.Errorf("error closing storage: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/discordtest"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+func TestRunWithoutToken(t *testing.T) {
+	t.Setenv("DISCORD_AUTH_TOKEN", "")
+
+	err := Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "DISCORD_AUTH_TOKEN") {
+		t.Errorf("Run() = %v, want an error naming the missing token", err)
+	}
+}
+
+// Runs the whole bot against the stand-in: it connects, joins a guild,
+// answers commands, resumes after losing its connection and stops when cancelled.
+func TestRun(t *testing.T) {
+	const token = "test-token"
+	t.Setenv("DISCORD_AUTH_TOKEN", token)
+	t.Setenv("DISCORD_BOT_PREFIX", "!")
+	t.Setenv("DAGGERBOT_STORAGE", config.StorageJSON)
+	t.Setenv("DAGGERBOT_DATA_DIR", t.TempDir())
+
+	srv := discordtest.NewServer()
+	srv.Token = token
+	defer srv.Close()
+
+	player := &discordgo.User{ID: "400", Username: "Tam"}
+	srv.AddGuild(&discordgo.Guild{
+		ID:       "200",
+		Name:     "Harness",
+		OwnerID:  player.ID,
+		Members:  []*discordgo.Member{{User: player}},
+		Channels: []*discordgo.Channel{{ID: "300", Name: "general", Type: discordgo.ChannelTypeGuildText}},
+	})
+	restore := srv.Redirect()
+	defer restore() // Deferred first, so it runs after Run has closed the session
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	done := make(chan error, 1)
+	go func() { done <- Run(runCtx) }()
+
+	// READY, then the GUILD_CREATE that registers the guild
+	if err := srv.WaitReady(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	roll := func(expr string) {
+		t.Helper()
+		skip := len(srv.Sent())
+		for {
+			if _, ok := config.Guilds.Get("200"); ok {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatal("the guild was never registered")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		if _, err := srv.SendMessage("300", player, "!roll "+expr); err != nil {
+			t.Fatal(err)
+		}
+		sent, err := srv.WaitSent(ctx, skip, func(s discordtest.Sent) bool { return s.ChannelID == "300" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(sent.Content+embedText(sent), expr) {
+			t.Errorf("replied %+v to !roll %s", sent, expr)
+		}
+	}
+	roll("1d6")
+
+	// The connection drops and a message arrives before the bot is back; it
+	// resumes and gets the message replayed
+	srv.Disconnect()
+	skip := len(srv.Sent())
+	if _, err := srv.SendMessage("300", player, "!roll 2d8"); err != nil {
+		t.Fatal(err)
+	}
+	sent, err := srv.WaitSent(ctx, skip, func(s discordtest.Sent) bool { return s.ChannelID == "300" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sent.Content+embedText(sent), "2d8") {
+		t.Errorf("replied %+v to the replayed !roll 2d8", sent)
+	}
+	if srv.Resumes() != 1 || srv.Identifies() != 1 {
+		t.Errorf("identified %d times and resumed %d, want a single resume", srv.Identifies(), srv.Resumes())
+	}
+
+	stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() = %v after cancelling", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Run did not return after cancelling")
+	}
+}
+
+func embedText(s discordtest.Sent) string {
+	var b strings.Builder
+	for _, e := range s.Embeds {
+		b.WriteString(e.Title + e.Description)
+	}
+	return b.String()
+}
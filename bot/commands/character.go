@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+func Character(c *Command, ctx *Context) error {
+	var (
+		args  = ctx.Args
+		guild = ctx.Guild
+		chars = guild.Characters()
+	)
+
+	if len(args) < 1 {
+		args = []string{"show"}
+	}
+
+	switch strings.ToLower(args[0]) {
+
+	case "create", "new":
+		name := strings.Join(args[1:], " ")
+		if strings.TrimSpace(name) == "" {
+			return ctx.Reply("Usage: !char create <name>")
+		}
+		if err := chars.Create(config.NewCharacter(name, ctx.Author.ID)); err != nil {
+			return ctx.Reply(fmt.Sprintf("Could not create character: %v", err))
+		}
+		return ctx.Reply(fmt.Sprintf("Created **%s**, now your active character. Fill in their sheet with `%schar set <field> <value>`", strings.TrimSpace(name), guild.Prefix()))
+
+	case "show":
+		char, err := findCharacter(ctx, withoutMentions(args[1:]))
+		if err != nil {
+			return ctx.Reply(characterError(ctx, err))
+		}
+		return ctx.Reply(formatCharacter(ctx, char))
+
+	case "list":
+		ownerID := ctx.Author.ID
+		if len(ctx.Mentions) > 0 {
+			ownerID = ctx.Mentions[0].ID
+		} else if len(args) > 1 && strings.EqualFold(args[1], "all") {
+			ownerID = ""
+		}
+		list := chars.List(ownerID)
+		if len(list) == 0 {
+			return ctx.Reply("No characters found")
+		}
+		response := "Characters:\n"
+		for _, char := range list {
+			response += fmt.Sprintf("**%s** - Level %d %s (%s)\n", char.Name, char.Level, orDash(char.Class), memberName(ctx.Session, guild.ID, char.OwnerID))
+		}
+		return ctx.Reply(response)
+
+	case "use", "switch":
+		name := strings.Join(args[1:], " ")
+		if err := chars.SetActive(ctx.Author.ID, name); err != nil {
+			return ctx.Reply(fmt.Sprintf("Could not switch character: %v", err))
+		}
+		return ctx.Reply(fmt.Sprintf("**%s** is now your active character", strings.TrimSpace(name)))
+
+	case "set":
+		if len(args) < 3 {
+			return ctx.Reply(fmt.Sprintf("Usage: !char set <field> <value>\nFields: `%s`, `hope`, `stress`", strings.Join(config.CharacterFields, "`, `")))
+		}
+		char, err := chars.Active(ctx.Author.ID)
+		if err != nil {
+			return ctx.Reply(characterError(ctx, err))
+		}
+		field, value := strings.ToLower(args[1]), strings.Join(args[2:], " ")
+
+		// Hope and marked Stress are tracked in the guild's pools
+		if field == "hope" || field == "stress" {
+			n, err := strconv.Atoi(strings.TrimPrefix(value, "+"))
+			if err != nil {
+				return ctx.Reply(fmt.Sprintf("%s must be a number, not %q", field, value))
+			}
+			set := guild.Pools().SetHope
+			if field == "stress" {
+				set = guild.Pools().SetStress
+			}
+			pp, err := set(char.OwnerID, n)
+			if err != nil {
+				log.Printf("Failed to save %s for guild %q: %v", field, guild.Name(), err)
+			}
+			if field == "stress" {
+				n = pp.Stress
+			} else {
+				n = pp.Hope
+			}
+			return ctx.Reply(fmt.Sprintf("**%s** %s set to %d", char.Name, field, n))
+		}
+
+		if field == "damage" {
+			if _, err := dice.Parse(value); err != nil {
+				return ctx.Reply(fmt.Sprintf("Invalid damage: %v", err))
+			}
+		}
+
+		updated, err := chars.Update(char.OwnerID, char.Name, func(c *config.Character) error { return c.Set(field, value) })
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("Could not update **%s**: %v", char.Name, err))
+		}
+		if updated.StressSlots < char.StressSlots {
+			if _, err := guild.Pools().ClampStress(char.OwnerID); err != nil {
+				log.Printf("Failed to save Stress for guild %q: %v", guild.Name(), err)
+			}
+		}
+		return ctx.Reply(fmt.Sprintf("**%s** %s set to %s", char.Name, field, value))
+
+	case "exp", "experience":
+		if len(args) < 3 {
+			return ctx.Reply("Usage: !char exp <name> <bonus> or !char exp remove <name>")
+		}
+		char, err := chars.Active(ctx.Author.ID)
+		if err != nil {
+			return ctx.Reply(characterError(ctx, err))
+		}
+
+		if strings.EqualFold(args[1], "remove") {
+			name := strings.Join(args[2:], " ")
+			var removed bool
+			if _, err := chars.Update(char.OwnerID, char.Name, func(c *config.Character) error {
+				removed = c.RemoveExperience(name)
+				return nil
+			}); err != nil {
+				log.Printf("Failed to save characters for guild %q: %v", guild.Name(), err)
+			}
+			if !removed {
+				return ctx.Reply(fmt.Sprintf("**%s** has no Experience called %q", char.Name, name))
+			}
+			return ctx.Reply(fmt.Sprintf("Removed Experience %q from **%s**", name, char.Name))
+		}
+
+		name, bonusArg := strings.Join(args[1:len(args)-1], " "), args[len(args)-1]
+		bonus, err := strconv.Atoi(strings.TrimPrefix(bonusArg, "+"))
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("%q is not a valid Experience bonus", bonusArg))
+		}
+		if _, err := chars.Update(char.OwnerID, char.Name, func(c *config.Character) error {
+			c.SetExperience(name, bonus)
+			return nil
+		}); err != nil {
+			log.Printf("Failed to save characters for guild %q: %v", guild.Name(), err)
+		}
+		return ctx.Reply(fmt.Sprintf("**%s** has Experience %s %+d", char.Name, name, bonus))
+
+	case "delete", "remove":
+		name := strings.Join(withoutMentions(args[1:]), " ")
+		owner := hopeTarget(ctx)
+		if owner.ID != ctx.Author.ID && !guild.IsGM(ctx.Member) {
+			return ctx.Reply(fmt.Sprintf("you can only delete your own characters, %s", ctx.Author))
+		}
+		char, err := chars.Get(owner.ID, name)
+		if err != nil {
+			return ctx.Reply(characterError(ctx, err))
+		}
+		if err := chars.Delete(char.OwnerID, char.Name); err != nil {
+			return ctx.Reply(fmt.Sprintf("Could not delete **%s**: %v", char.Name, err))
+		}
+		return ctx.Reply(fmt.Sprintf("Deleted **%s**", char.Name))
+
+	default:
+		return ctx.Reply(CommandHelp(c, guild.Prefix()))
+	}
+}
+
+// findCharacter returns the named character of the mentioned player, or of
+// the author when nobody is mentioned. Without a name it is their active one.
+func findCharacter(ctx *Context, args []string) (config.Character, error) {
+	owner := hopeTarget(ctx)
+	if name := strings.Join(args, " "); strings.TrimSpace(name) != "" {
+		return ctx.Guild.Characters().Get(owner.ID, name)
+	}
+	return ctx.Guild.Characters().Active(owner.ID)
+}
+
+func characterError(ctx *Context, err error) string {
+	if errors.Is(err, config.ErrNoActiveCharacter) {
+		return fmt.Sprintf("You don't have an active character. Create one with `%schar create <name>`", ctx.Guild.Prefix())
+	}
+	return fmt.Sprintf("Sorry, %v", err)
+}
+
+func formatCharacter(ctx *Context, c config.Character) string {
+	pp := ctx.Guild.Pools().Player(c.OwnerID)
+
+	heritage := []string{fmt.Sprintf("Level %d %s", c.Level, orDash(c.Class))}
+	if c.Subclass != "" {
+		heritage[0] += fmt.Sprintf(" (%s)", c.Subclass)
+	}
+	if c.Ancestry != "" {
+		heritage = append(heritage, c.Ancestry)
+	}
+	if c.Community != "" {
+		heritage = append(heritage, c.Community)
+	}
+
+	traits := make([]string, 0, len(config.Traits))
+	for _, t := range config.Traits {
+		traits = append(traits, fmt.Sprintf("%s %+d", strings.ToUpper(t[:1])+t[1:], c.Traits[t]))
+	}
+
+	experiences := make([]string, 0, len(c.Experiences))
+	for _, e := range c.Experiences {
+		experiences = append(experiences, fmt.Sprintf("%s %+d", e.Name, e.Bonus))
+	}
+
+	sheet := fmt.Sprintf("**%s** - %s\n", c.Name, strings.Join(heritage, " · "))
+	sheet += fmt.Sprintf("Player: %s\n", memberName(ctx.Session, ctx.Guild.ID, c.OwnerID))
+	sheet += strings.Join(traits, " | ") + "\n"
+	sheet += fmt.Sprintf("Evasion %d · Armor %d · Thresholds %d/%d · Proficiency %d\n", c.Evasion, c.Armor, c.MajorThreshold, c.SevereThreshold, c.Proficiency)
+	sheet += fmt.Sprintf("HP %d/%d · Stress %d/%d · Hope %d/%d\n", c.HP, c.HPSlots, pp.Stress, c.StressSlots, pp.Hope, config.MaxHope)
+	if len(experiences) > 0 {
+		sheet += "Experiences: " + strings.Join(experiences, ", ") + "\n"
+	}
+	if w := c.Weapon; w.Name != "" || w.Damage != "" {
+		sheet += fmt.Sprintf("Weapon: %s (%s, %s)\n", orDash(w.Name), orDash(w.Trait), orDash(damageExpression(w.Damage, c.Proficiency)))
+	}
+	return sheet
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	cmd := NewCommand("Char", "Creates and manages Daggerheart characters", Character)
+	cmd.Usage = []string{
+		"char create <name> - Creates a character and makes it your active one",
+		"char show [name] [@player] - Shows your (or a player's) active character, or a named one",
+		"char list [all|@player] - Lists your (or everyone's) characters",
+		"char use <name> - Switches your active character",
+		"char set <field> <value> - Changes a field on your active character",
+		"char exp <name> <bonus> - Adds or updates an Experience",
+		"char exp remove <name> - Removes an Experience",
+		"char delete <name> [@player] - Deletes a character",
+	}
+	cmd.Examples = []string{"char create Marlowe Fenn", "char set class Ranger", "char set agility +2", "char exp Tracking +2", "char set damage d8+1"}
+	cmd.Aliases = []string{"character"}
+	cmd.SetOptions(
+		SubcommandOption("create", "Creates a character", StringOption("name", "Character name", true)),
+		SubcommandOption("show", "Shows a character", StringOption("name", "Character name (default: the active one)", false), UserOption("player", "Player who owns the character", false)),
+		SubcommandOption("list", "Lists characters", StringOption("scope", "Use `all` to list everyone's", false)),
+		SubcommandOption("use", "Switches your active character", StringOption("name", "Character name", true)),
+		SubcommandOption("set", "Changes a field on your active character", StringOption("field", "Field to change", true), StringOption("value", "New value", true)),
+		SubcommandOption("exp", "Adds or updates an Experience", StringOption("name", "Experience name", true), IntegerOption("bonus", "Experience bonus", true)),
+		SubcommandOption("delete", "Deletes a character", StringOption("name", "Character name", true), UserOption("player", "Player who owns the character", false)),
+	)
+	RegisterCommand(cmd)
+}
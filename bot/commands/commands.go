@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -10,20 +9,26 @@ import (
 	"github.com/nerdwerx/daggerbot/config"
 )
 
-var Commands = map[string]*Command{}
+var (
+	Commands = map[string]*Command{} // Registered commands by lowercase name
+	Aliases  = map[string]string{}   // Alternate names mapped to command names
+)
 
-type Handler func(c *Command, s *discordgo.Session, m *discordgo.MessageCreate) error
+type Handler func(c *Command, ctx *Context) error
 
+// Data is fixed when the command is registered and shared by every invocation.
 type Data struct {
-	admin   bool          // Whether the command is admin-only
-	args    []string      // Arguments for the command
-	guild   *config.Guild // Guild this command is registered for (optional)
-	handler Handler       // Function to handle the command
+	permission config.Permission                     // Lowest permission level allowed to run the command
+	handler    Handler                               // Function to handle the command
+	options    []*discordgo.ApplicationCommandOption // Typed options for the slash command
 }
 
 type Command struct {
-	Name        string // Name of the command
-	Description string // Description of the command
+	Name        string   // Name of the command
+	Description string   // Description of the command
+	Usage       []string // Syntax lines without the prefix, e.g. "roll [dice]", optionally followed by " - explanation"
+	Examples    []string // Example invocations without the prefix
+	Aliases     []string // Alternate names the command answers to
 	data        Data
 }
 
@@ -31,42 +36,30 @@ func (c *Command) String() string {
 	return fmt.Sprintf("%s: %s", c.Name, c.Description)
 }
 
-func (c *Command) Admin() bool {
-	return c.data.admin
-}
-
-func (c *Command) Args() []string {
-	if c.data.args == nil {
-		return []string{}
-	}
-
-	return c.data.args
+func (c *Command) Permission() config.Permission {
+	return c.data.permission
 }
 
-func (c *Command) Guild() *config.Guild {
-	return c.data.guild
+func (c *Command) Options() []*discordgo.ApplicationCommandOption {
+	return c.data.options
 }
 
-func (c *Command) Run(s *discordgo.Session, m *discordgo.MessageCreate) error {
+func (c *Command) Run(ctx *Context) error {
 	if c.data.handler == nil {
 		return fmt.Errorf("no handler defined for command %s", c.Name)
 	}
-	return c.data.handler(c, s, m)
+	return c.data.handler(c, ctx)
 }
 
-func (c *Command) SetAdmin() {
-	c.data.admin = true
+func (c *Command) SetPermission(p config.Permission) {
+	c.data.permission = p
 }
 
-func (c *Command) SetArgs(args []string) {
-	c.data.args = args
+func (c *Command) SetOptions(options ...*discordgo.ApplicationCommandOption) {
+	c.data.options = options
 }
 
-func (c *Command) SetGuild(guild *config.Guild) {
-	c.data.guild = guild
-}
-
-// RegisterCommand registers a new command in the global commands map
+// RegisterCommand registers a new command and its aliases in the global commands map
 func RegisterCommand(command *Command) {
 	name := strings.ToLower(command.Name)
 
@@ -77,6 +70,28 @@ func RegisterCommand(command *Command) {
 
 	Commands[name] = command
 	fmt.Printf("Registered command: %s\n", name)
+
+	for _, alias := range command.Aliases {
+		alias = strings.ToLower(alias)
+		if _, exists := Lookup(alias); exists {
+			fmt.Printf("Alias %s already in use, not registering for %s.\n", alias, name)
+			continue
+		}
+		Aliases[alias] = name
+	}
+}
+
+// Lookup finds a command by name or alias, ignoring case
+func Lookup(name string) (*Command, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if cmd, ok := Commands[name]; ok {
+		return cmd, true
+	}
+	if target, ok := Aliases[name]; ok {
+		cmd, ok := Commands[target]
+		return cmd, ok
+	}
+	return nil, false
 }
 
 func NewCommand(name, description string, handler Handler) *Command {
@@ -84,51 +99,34 @@ func NewCommand(name, description string, handler Handler) *Command {
 		Name:        name,
 		Description: description,
 		data: Data{
-			admin:   false, // Default to non-admin
-			args:    make([]string, 0),
-			handler: handler,
+			permission: config.PermEveryone, // Default to anyone
+			handler:    handler,
 		},
 	}
 }
 
-func MessageSend(s *discordgo.Session, m *discordgo.MessageCreate, message string) error {
-	if len(message) > 2000 {
-		message := fmt.Sprintf("Message exceeds Discord's 2000 character limit: %d characters", len(message))
-		log.Println(message)
-		return errors.New(message)
-	}
+// Execute checks the caller is allowed to run the command and runs it.
+func Execute(cmd *Command, ctx *Context) error {
+	guild := ctx.Guild
 
-	if _, err := s.ChannelMessageSend(m.ChannelID, message); err != nil {
-		log.Printf("failed to send message: %s", err.Error())
-		return err
+	if !canRun(cmd, ctx) {
+		log.Printf("[%s] user @%s (%s) is not a %s, denying access to %s command", guild.Name(), ctx.Author.DisplayName(), ctx.Author, cmd.Permission(), cmd.Name)
+		return ctx.Reply(fmt.Sprintf("you must be %s to use this command, %s", withArticle(cmd.Permission()), ctx.Author))
 	}
 
-	if config.Debug {
-		log.Printf("Sent message to channel %s: %s", m.ChannelID, message)
-	}
-	return nil
-}
+	log.Printf("[%s] @%s (%s) executing command %q with args %v in channel %s", guild.Name(), ctx.Author.DisplayName(), ctx.Author, cmd.Name, ctx.Args, ctx.ChannelID)
 
-func MessagePrivateSend(s *discordgo.Session, m *discordgo.MessageCreate, message string) error {
-	if len(message) > 2000 {
-		message := fmt.Sprintf("Message exceeds Discord's 2000 character limit: %d characters", len(message))
-		log.Println(message)
-		return errors.New(message)
-	}
-
-	userChannel, err := s.UserChannelCreate(m.Author.ID)
-	if err != nil {
-		log.Printf("failed to open channel to user %q: %s", m.Author.DisplayName(), err.Error())
-		return err
-	}
-
-	if _, err := s.ChannelMessageSend(userChannel.ID, message); err != nil {
-		log.Printf("failed to send message: %s", err.Error())
-		return err
-	}
+	return cmd.Run(ctx)
+}
 
-	if config.Debug {
-		log.Printf("Sent message to channel %s: %s", m.ChannelID, message)
+// withArticle names a permission level for messages, e.g. "a GM" or "an admin".
+func withArticle(p config.Permission) string {
+	switch p {
+	case config.PermAdmin:
+		return "an admin"
+	case config.PermOwner:
+		return "the server owner"
+	default:
+		return "a " + p.String()
 	}
-	return nil
 }
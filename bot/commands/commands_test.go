@@ -0,0 +1,321 @@
+package commands_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/commands"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/bot/discordtest"
+)
+
+func newHarness(t *testing.T) *discordtest.Harness {
+	t.Helper()
+
+	h, err := discordtest.NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.Close)
+	return h
+}
+
+// only returns the single message sent, failing the test if there were none or several.
+func only(t *testing.T, sent []discordtest.Sent) discordtest.Sent {
+	t.Helper()
+
+	if len(sent) != 1 {
+		t.Fatalf("got %d messages, want 1: %+v", len(sent), sent)
+	}
+	return sent[0]
+}
+
+func TestRollCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		say     string
+		faces   []int
+		private bool
+		title   string
+		color   int
+	}{
+		{name: "duality with Hope", say: "!roll", faces: []int{9, 4}, title: "Rolled 13 with Hope", color: commands.ColorHope},
+		{name: "duality with Fear", say: "!roll duality +1", faces: []int{2, 8}, title: "Rolled 11 with Fear", color: commands.ColorFear},
+		{name: "difficulty", say: "!r duality dc 15", faces: []int{8, 5}, title: "Rolled 13: Failure with Hope", color: commands.ColorHope},
+		{name: "critical", say: "!roll duality", faces: []int{7, 7}, title: "Critical Success! Rolled 14", color: commands.ColorCritical},
+		{name: "expression", say: "!roll 2d6+3", faces: []int{4, 5}, title: "2d6+3: 12", color: commands.ColorDice},
+		{name: "natural one", say: "!roll d20", faces: []int{1}, title: "d20: 1 :cry:", color: commands.ColorDice},
+		{name: "private", say: "!proll 1d8", faces: []int{6}, private: true, title: "1d8: 6", color: commands.ColorDice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.RNG = dice.NewFixedRNG(tt.faces...)
+			h.AddMember("1", "Tam")
+
+			sent := only(t, h.Say("1", tt.say))
+			if sent.DM != tt.private {
+				t.Errorf("sent to a DM = %v, want %v", sent.DM, tt.private)
+			}
+			if len(sent.Embeds) != 1 {
+				t.Fatalf("got %d embeds, want 1: %+v", len(sent.Embeds), sent)
+			}
+			embed := sent.Embeds[0]
+			if embed.Title != tt.title {
+				t.Errorf("title = %q, want %q", embed.Title, tt.title)
+			}
+			if embed.Color != tt.color {
+				t.Errorf("color = %#x, want %#x", embed.Color, tt.color)
+			}
+			if embed.Author == nil || embed.Author.Name != "Tam" {
+				t.Errorf("author = %+v, want Tam", embed.Author)
+			}
+		})
+	}
+}
+
+func TestRollWithoutEmbedLinks(t *testing.T) {
+	h := newHarness(t)
+	h.RNG = dice.NewFixedRNG(4, 5)
+	h.AddMember("1", "Tam")
+	h.Fake.SetPermissions(discordtest.ChannelID, discordgo.PermissionSendMessages)
+
+	sent := only(t, h.Say("1", "!roll 2d6+3"))
+	if len(sent.Embeds) != 0 {
+		t.Errorf("got %d embeds in a channel without Embed Links", len(sent.Embeds))
+	}
+	if want := "Tam 2d6+3 result is 12\n> 2d6 [4, 5] + 3"; !strings.Contains(sent.Content, want) {
+		t.Errorf("content = %q, want it to contain %q", sent.Content, want)
+	}
+}
+
+func TestRollErrorsAreReplied(t *testing.T) {
+	const invalid = "Invalid roll: unknown modifier \"banana\""
+
+	t.Run("roll", func(t *testing.T) {
+		h := newHarness(t)
+		h.AddMember("1", "Tam")
+		sent := only(t, h.Say("1", "!roll banana"))
+		if sent.ChannelID != discordtest.ChannelID || !strings.HasPrefix(sent.Content, invalid) {
+			t.Errorf("got %+v, want the error in the channel", sent)
+		}
+	})
+
+	t.Run("proll", func(t *testing.T) {
+		h := newHarness(t)
+		h.AddMember("1", "Tam")
+		sent := only(t, h.Say("1", "!proll banana 2d"))
+		if !sent.DM || !strings.HasPrefix(sent.Content, invalid) || !strings.Contains(sent.Content, "\nInvalid roll:") {
+			t.Errorf("got %+v, want both errors in a direct message", sent)
+		}
+	})
+
+	t.Run("slash", func(t *testing.T) {
+		h := newHarness(t)
+		sent := h.Slash("1", "roll", &discordgo.ApplicationCommandInteractionDataOption{
+			Name: "dice", Type: discordgo.ApplicationCommandOptionString, Value: "banana",
+		})
+		if reply := response(t, sent); !strings.HasPrefix(reply.Content, invalid) {
+			t.Errorf("got %+v, want the error as the response", sent)
+		}
+	})
+}
+
+func TestRollFieldsFitEmbed(t *testing.T) {
+	h := newHarness(t)
+	h.AddMember("1", "Tam")
+
+	sent := only(t, h.Say("1", "!roll "+strings.Repeat("1d4+", 29)+"1d4"))
+	if len(sent.Embeds) != 1 {
+		t.Fatalf("got %d embeds, want 1", len(sent.Embeds))
+	}
+	fields := sent.Embeds[0].Fields
+	if len(fields) != 25 || fields[23].Value != "7 more" || fields[24].Name != "Total" {
+		t.Errorf("30 dice terms gave %d fields, ending %+v %+v", len(fields), fields[len(fields)-2], fields[len(fields)-1])
+	}
+}
+
+func TestRollIsRepeatable(t *testing.T) {
+	roll := func() string {
+		h := newHarness(t)
+		h.RNG = dice.NewSeededRNG(7)
+		sent := only(t, h.Say(discordtest.OwnerID, "!roll 10d20"))
+		if len(sent.Embeds) != 1 {
+			t.Fatalf("got %d embeds, want 1", len(sent.Embeds))
+		}
+		return sent.Embeds[0].Description
+	}
+
+	if first, second := roll(), roll(); first != second {
+		t.Errorf("the same seed rolled %q and %q", first, second)
+	}
+}
+
+func TestPrivateRollsAreHidden(t *testing.T) {
+	h := newHarness(t)
+	gm := h.AddRole("GM")
+	h.AddMember("1", "Tam", gm.ID)
+	h.AddMember("2", "Mira", gm.ID)
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+	h.Say("1", "!roll 1d20")
+	h.Say("2", "!proll 3d6")
+
+	listed := only(t, h.Say("2", "!rolls"))
+	if strings.Contains(listed.Content, "3d6") || !strings.Contains(listed.Content, "1d20") {
+		t.Errorf("the listing shows a private roll:\n%s", listed.Content)
+	}
+
+	export := func(userID string) string {
+		t.Helper()
+		for _, s := range h.Say(userID, "!rolls export json") {
+			if file, ok := s.Files["rolls.json"]; ok {
+				return file
+			}
+		}
+		t.Fatalf("no export was sent to %s", userID)
+		return ""
+	}
+	if file := export("1"); strings.Contains(file, "3d6") {
+		t.Errorf("Tam's export holds Mira's private roll:\n%s", file)
+	}
+	if file := export("2"); !strings.Contains(file, "3d6") || !strings.Contains(file, "1d20") {
+		t.Errorf("Mira's export is missing her own private roll:\n%s", file)
+	}
+}
+
+func TestStatsSkipPrivateRolls(t *testing.T) {
+	h := newHarness(t)
+	h.AddMember("1", "Tam")
+	h.Say("1", "!roll 1d20")
+	h.Say("1", "!proll 3d6")
+
+	sent := h.Say("1", "!stats")
+	if len(sent) == 0 {
+		t.Fatal("no stats were sent")
+	}
+	text := sent[0].Content
+	if !strings.Contains(text, ", 1 rolls") || strings.Contains(text, "d6") {
+		t.Errorf("stats count the private roll:\n%s", text)
+	}
+}
+
+func TestRollQueriesNamePlayers(t *testing.T) {
+	h := newHarness(t)
+	gm := h.AddRole("GM")
+	h.AddMember("1", "Tam", gm.ID)
+	h.AddMember("2", "Mira")
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+	h.Say("1", "!roll 1d20")
+	h.Say("2", "!roll 2d6")
+
+	tests := []struct {
+		say, want string
+	}{
+		{"!rolls <@2>", "Rolls by Mira"},
+		{"!stats <@2>", "for Mira"},
+		{"!rolls @Mira", "pick the player from Discord's @ suggestions"},
+		{"!stats @Mira 7d", "pick the player from Discord's @ suggestions"},
+		{"!rolls <@1> <@2>", "one player at a time"},
+		{"!stats <@1> 7d <@2>", "one player at a time"},
+	}
+	for _, tt := range tests {
+		if sent := h.Say("1", tt.say); len(sent) == 0 || !strings.Contains(sent[0].Content, tt.want) {
+			t.Errorf("%s replied %+v, want %q", tt.say, sent, tt.want)
+		}
+	}
+}
+
+func TestSessionRollsFollowTheSession(t *testing.T) {
+	h := newHarness(t)
+	gm := h.AddRole("GM")
+	h.AddMember("1", "Tam", gm.ID)
+	h.AddMember("2", "Mira")
+	h.AddMember("3", "Bram")
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+
+	sessions := h.Guild.Sessions()
+	sess, err := sessions.Create("Into the Mire", "1", time.Now().Add(-time.Minute), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.SetAnnouncement(sess.ID, discordtest.ChannelID, "900"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sessions.Join(sess.ID, "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Say("2", "!roll 1d20")
+	h.Say("3", "!roll 2d6") // Not on the roster
+	if sent := only(t, h.Say("1", "!session end 1")); !strings.Contains(sent.Content, "has ended") {
+		t.Fatalf("ending the session replied %q", sent.Content)
+	}
+	h.Say("2", "!roll 3d8") // After the end
+
+	listed := only(t, h.Say("1", "!rolls session 1"))
+	if !strings.Contains(listed.Content, "1d20") || strings.Contains(listed.Content, "2d6") || strings.Contains(listed.Content, "3d8") {
+		t.Errorf("session rolls should only hold Mira's 1d20:\n%s", listed.Content)
+	}
+}
+
+func TestCharacterSetClampsPools(t *testing.T) {
+	h := newHarness(t)
+	h.AddMember("1", "Tam")
+	h.Say("1", "!char create Aria")
+
+	if sent := only(t, h.Say("1", "!char set hope 99")); !strings.Contains(sent.Content, "hope set to 6") {
+		t.Errorf("setting Hope past the cap replied %q", sent.Content)
+	}
+	if sent := only(t, h.Say("1", "!char set stress 5")); !strings.Contains(sent.Content, "stress set to 5") {
+		t.Errorf("setting Stress replied %q", sent.Content)
+	}
+
+	h.Say("1", "!char set stress_slots 3")
+	if pp := h.Guild.Pools().Player("1"); pp.Stress != 3 {
+		t.Errorf("after lowering Stress slots to 3, Stress = %d, want 3", pp.Stress)
+	}
+}
+
+func TestHugeRollsFitDiscordLimits(t *testing.T) {
+	const roll = "!roll 4d2! 4d2! 4d2! 4d2!" // Every die explodes the most it may
+
+	t.Run("text", func(t *testing.T) {
+		h := newHarness(t)
+		h.RNG = dice.NewFixedRNG(2)
+		h.AddMember("1", "Tam")
+		h.Fake.SetPermissions(discordtest.ChannelID, discordgo.PermissionSendMessages)
+
+		sent := only(t, h.Say("1", roll))
+		if len(sent.Content) > 2000 || !strings.Contains(sent.Content, "more") {
+			t.Errorf("sent %d characters, want at most 2000 with the rest summarized", len(sent.Content))
+		}
+	})
+
+	t.Run("embeds", func(t *testing.T) {
+		h := newHarness(t)
+		h.RNG = dice.NewFixedRNG(2)
+		h.AddMember("1", "Tam")
+
+		sent := h.Say("1", roll)
+		var embeds int
+		for _, s := range sent {
+			size := 0
+			for _, e := range s.Embeds {
+				size += len([]rune(e.Title)) + len([]rune(e.Description)) + len([]rune(e.Author.Name))
+				for _, f := range e.Fields {
+					size += len([]rune(f.Name)) + len([]rune(f.Value))
+				}
+			}
+			if size > 6000 {
+				t.Errorf("a message carried %d characters of embeds, more than Discord allows", size)
+			}
+			embeds += len(s.Embeds)
+		}
+		if embeds != 4 {
+			t.Errorf("sent %d embeds across %d messages, want 4", embeds, len(sent))
+		}
+	})
+}
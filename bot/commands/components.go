@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+ * Message components, such as buttons, that the bot attaches to its own
+ * messages. A component's custom ID is its handler's prefix followed by
+ * colon-separated arguments, e.g. "session:join:3".
+ */
+
+// ComponentHandler handles a press of one of the bot's components. The
+// context's Args are the parts of the custom ID after the prefix.
+type ComponentHandler func(ctx *Context) error
+
+var Components = map[string]ComponentHandler{} // Registered component handlers by prefix
+
+// RegisterComponent registers the handler for custom IDs starting with prefix.
+func RegisterComponent(prefix string, handler ComponentHandler) {
+	if _, exists := Components[prefix]; exists {
+		fmt.Printf("Component %s already exists, not registering again.\n", prefix)
+		return
+	}
+	Components[prefix] = handler
+}
+
+// ComponentID builds a custom ID for a component handled by prefix.
+func ComponentID(prefix string, args ...string) string {
+	return strings.Join(append([]string{prefix}, args...), ":")
+}
+
+// LookupComponent finds the handler for a custom ID and returns it with the ID's arguments.
+func LookupComponent(customID string) (ComponentHandler, []string, bool) {
+	parts := strings.Split(customID, ":")
+	handler, ok := Components[parts[0]]
+	return handler, parts[1:], ok
+}
@@ -9,14 +9,14 @@ import (
 	"github.com/nerdwerx/daggerbot/config"
 )
 
-func Config(cmd *Command, s *discordgo.Session, m *discordgo.MessageCreate) error {
+func Config(c *Command, ctx *Context) error {
 	var (
-		args  = cmd.Args()
-		guild = cmd.Guild()
+		args  = ctx.Args
+		guild = ctx.Guild
 	)
 
 	if len(args) < 1 {
-		return MessageSend(s, m, "Usage: !config <command> [args]\nAvailable commands: `get`, `set`, `list`, `clear`, `help`")
+		return ctx.Reply(CommandHelp(c, guild.Prefix()))
 	}
 
 	// Handle the config command
@@ -24,36 +24,37 @@ func Config(cmd *Command, s *discordgo.Session, m *discordgo.MessageCreate) erro
 
 	case "get":
 		if len(args) < 2 {
-			return MessageSend(s, m, "Usage: !config get <key>")
+			return ctx.Reply("Usage: !config get <key>")
 		}
 		key := args[1]
 		value, exists := guild.GetConfigMap()[key]
 		if !exists {
-			return MessageSend(s, m, fmt.Sprintf("Config key `%s` does not exist", key))
+			return ctx.Reply(fmt.Sprintf("Config key `%s` does not exist", key))
 		}
-		return MessageSend(s, m, fmt.Sprintf("Config `%s`: %v", key, value))
+		return ctx.Reply(fmt.Sprintf("Config `%s`: %v", key, value))
 
 	case "set":
 		if len(args) < 3 {
-			return MessageSend(s, m, "Usage: !config set <key> <value>")
+			return ctx.Reply("Usage: !config set <key> <value>")
 		}
 		key := args[1]
-		values := strings.Split(strings.Join(args[2:], ","), ",")
+		// Role names may contain spaces, so only commas separate values
+		values := strings.Split(strings.Join(args[2:], " "), ",")
 		if key == "" || len(values) == 0 {
-			return MessageSend(s, m, "Key and value cannot be empty")
+			return ctx.Reply("Key and value cannot be empty")
 		}
 		cmap := guild.GetConfigMap()
 		if _, ok := cmap[key]; !ok {
-			return MessageSend(s, m, fmt.Sprintf("Key must exist and must be one of %v", config.ConfigKeys))
+			return ctx.Reply(fmt.Sprintf("Key must exist and must be one of %v", config.ConfigKeys))
 		}
 
 		// Special handling for prefix
 		if strings.TrimSpace(strings.ToLower(key)) == "prefix" {
 			if len(values) != 1 || len(values[0]) == 0 {
-				return MessageSend(s, m, "Prefix must be a single non-empty string")
+				return ctx.Reply("Prefix must be a single non-empty string")
 			}
 			guild.SetPrefix(strings.TrimSpace(values[0]))
-			return MessageSend(s, m, fmt.Sprintf("Prefix set to `%s`", guild.Prefix()))
+			return ctx.Reply(fmt.Sprintf("Prefix set to `%s`", guild.Prefix()))
 		}
 
 		// Handle roles
@@ -67,7 +68,7 @@ func Config(cmd *Command, s *discordgo.Session, m *discordgo.MessageCreate) erro
 			if role := guild.FindRole(v); role != nil {
 				newValues = append(newValues, role) // Use the role ID if a role is found
 			} else {
-				if err := MessageSend(s, m, fmt.Sprintf("Could not find a role associated with %s", v)); err != nil {
+				if err := ctx.Reply(fmt.Sprintf("Could not find a role associated with %s", v)); err != nil {
 					log.Printf("Failed to send message: %v", err)
 					return err
 				}
@@ -76,11 +77,11 @@ func Config(cmd *Command, s *discordgo.Session, m *discordgo.MessageCreate) erro
 		}
 
 		if err := guild.SetRoleConfig(key, newValues); err != nil {
-			log.Printf("Failed to set config for guild %q: %v", guild.Name, err)
-			return MessageSend(s, m, "Failed to set config")
+			log.Printf("Failed to set config for guild %q: %v", guild.Name(), err)
+			return ctx.Reply("Failed to set config")
 		}
 
-		return MessageSend(s, m, fmt.Sprintf("Config `%s` set to `%s`", key, strings.Join(guild.RolesToNames(newValues), ", ")))
+		return ctx.Reply(fmt.Sprintf("Config `%s` set to `%s`", key, strings.Join(guild.RolesToNames(newValues), ", ")))
 
 	case "list":
 		var response string
@@ -103,45 +104,55 @@ func Config(cmd *Command, s *discordgo.Session, m *discordgo.MessageCreate) erro
 			}
 		}
 
-		return MessageSend(s, m, response)
+		return ctx.Reply(response)
 
 	case "clear":
 		if len(args) < 2 {
-			return MessageSend(s, m, "Usage: !config clear <key>")
+			return ctx.Reply("Usage: !config clear <key>")
 		}
 		key := args[1]
 		if _, exists := guild.GetConfigMap()[key]; !exists {
-			return MessageSend(s, m, fmt.Sprintf("Config key `%s` does not exist", key))
+			return ctx.Reply(fmt.Sprintf("Config key `%s` does not exist", key))
 		}
 		if key == "prefix" {
-			return MessageSend(s, m, "You cannot clear the `prefix` key")
+			return ctx.Reply("You cannot clear the `prefix` key")
 		}
 		if err := guild.ClearConfig(key); err != nil {
-			log.Printf("Failed to clear config for guild %q: %v", guild.Name, err)
-			if merr := MessageSend(s, m, "Failed to clear config"); merr != nil {
+			log.Printf("Failed to clear config for guild %q: %v", guild.Name(), err)
+			if merr := ctx.Reply("Failed to clear config"); merr != nil {
 				log.Printf("Failed to send message: %v", merr)
 			}
 			return err
 		}
-		return MessageSend(s, m, fmt.Sprintf("Config key `%s` cleared", key))
+		return ctx.Reply(fmt.Sprintf("Config key `%s` cleared", key))
 
 	default:
 		keys := make([]string, 0, len(config.ConfigKeys))
 		for _, key := range config.ConfigKeys {
 			keys = append(keys, fmt.Sprintf("`%s`", strings.TrimSpace(key)))
 		}
-		return MessageSend(s, m, "Config Command Help:\n"+
-			"`!config get <key>` - Retrieves the value of a config key\n"+
-			"`!config set <key> <value>` - Sets a config key to a value\n"+
-			"`!config list` - Lists all config keys and their values\n"+
-			"`!config clear <key>` - Clears a config key\n"+
-			"`!config help` - Displays this help message"+
-			"\n\nAvailable config keys: "+strings.Join(keys, ", "))
+		return ctx.Reply(CommandHelp(c, guild.Prefix()) + "\n\nAvailable config keys: " + strings.Join(keys, ", "))
 	}
 }
 
 func init() {
 	cmd := NewCommand("Config", "Sets or retrieves config variables", Config)
-	cmd.SetAdmin()
+	cmd.Usage = []string{
+		"config get <key> - Retrieves the value of a config key",
+		"config set <key> <value> - Sets a config key to a value",
+		"config list - Lists all config keys and their values",
+		"config clear <key> - Clears a config key",
+		"config help - Displays this help message",
+	}
+	cmd.Examples = []string{"config set prefix ?", "config set gms Game Master, Assistant GM"}
+	cmd.Aliases = []string{"cfg"}
+	cmd.SetPermission(config.PermAdmin)
+	cmd.SetOptions(
+		SubcommandOption("get", "Retrieves the value of a config key", configKeyOption()),
+		SubcommandOption("set", "Sets a config key to a value", configKeyOption(), StringOption("value", "Prefix, or comma separated role names or IDs", true)),
+		SubcommandOption("list", "Lists all config keys and their values"),
+		SubcommandOption("clear", "Clears a config key", configKeyOption()),
+		SubcommandOption("help", "Displays config help"),
+	)
 	RegisterCommand(cmd)
 }
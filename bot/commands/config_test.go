@@ -0,0 +1,43 @@
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/discordtest"
+)
+
+func TestConfigSetMultiWordRoles(t *testing.T) {
+	want := []string{"Game Master", "Assistant GM"}
+
+	check := func(t *testing.T, h *discordtest.Harness) {
+		t.Helper()
+		roles, err := h.Guild.GetRoleConfig("gms")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := h.Guild.RolesToNames(roles)
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("gms = %q, want %q", got, want)
+		}
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		h := newHarness(t)
+		h.AddRole("Game Master")
+		h.AddRole("Assistant GM")
+		h.Say(discordtest.OwnerID, "!config set gms Game Master, Assistant GM")
+		check(t, h)
+	})
+
+	t.Run("slash", func(t *testing.T) {
+		h := newHarness(t)
+		h.AddRole("Game Master")
+		h.AddRole("Assistant GM")
+		response(t, h.Slash(discordtest.OwnerID, "config", subcommand("set",
+			option("key", discordgo.ApplicationCommandOptionString, "gms"),
+			option("value", discordgo.ApplicationCommandOptionString, "Game Master,Assistant GM"),
+		)))
+		check(t, h)
+	})
+}
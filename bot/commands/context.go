@@ -0,0 +1,331 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/bot/discordapi"
+	"github.com/nerdwerx/daggerbot/bot/scheduler"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+const (
+	maxMessageLength = 2000 // Longest message content Discord accepts
+	maxEmbeds        = 10   // Most embeds Discord accepts in one message
+	maxEmbedsSize    = 6000 // Most characters Discord accepts across all the embeds in one message
+)
+
+/*
+ * Per-invocation state. A Context is built for every message or interaction,
+ * so concurrent invocations of the same command never share state
+ */
+
+type Context struct {
+	Session     discordapi.Session           // Connection the invocation arrived on
+	Guild       *config.Guild                // Guild the command was invoked in
+	Args        []string                     // Arguments following the command name
+	Author      *discordgo.User              // User who invoked the command
+	Member      *discordgo.Member            // Guild member who invoked the command
+	ChannelID   string                       // Channel the command was invoked in
+	Mentions    []*discordgo.User            // Users mentioned in the invocation
+	Message     *discordgo.MessageCreate     // Triggering message, nil for slash commands
+	Interaction *discordgo.InteractionCreate // Triggering interaction, nil for prefix commands
+	RNG         dice.RNG                     // Random source for any dice the command rolls
+	Scheduler   *scheduler.Scheduler         // Runs session reminders, nil to skip them
+
+	mu      sync.Mutex
+	replied bool // Whether the interaction response has been used
+}
+
+// NewMessageContext builds the context for a prefix command sent as a message.
+func NewMessageContext(s discordapi.Session, rng dice.RNG, sched *scheduler.Scheduler, guild *config.Guild, m *discordgo.MessageCreate, args []string) *Context {
+	return &Context{
+		Session:   s,
+		RNG:       rng,
+		Scheduler: sched,
+		Guild:     guild,
+		Args:      args,
+		Author:    m.Author,
+		Member:    m.Member,
+		ChannelID: m.ChannelID,
+		Mentions:  m.Mentions,
+		Message:   m,
+	}
+}
+
+// NewInteractionContext builds the context for a slash command.
+func NewInteractionContext(s discordapi.Session, rng dice.RNG, sched *scheduler.Scheduler, guild *config.Guild, i *discordgo.InteractionCreate, args []string, mentions []*discordgo.User) *Context {
+	return &Context{
+		Session:     s,
+		RNG:         rng,
+		Scheduler:   sched,
+		Guild:       guild,
+		Args:        args,
+		Author:      i.Member.User,
+		Member:      i.Member,
+		ChannelID:   i.ChannelID,
+		Mentions:    mentions,
+		Interaction: i,
+	}
+}
+
+// NewComponentContext builds the context for a button press on one of the bot's
+// messages. The interaction must already be acknowledged with a deferred update,
+// so replies are sent as follow-ups and the message itself is never removed.
+func NewComponentContext(s discordapi.Session, rng dice.RNG, sched *scheduler.Scheduler, guild *config.Guild, i *discordgo.InteractionCreate, args []string) *Context {
+	return &Context{
+		Session:     s,
+		RNG:         rng,
+		Scheduler:   sched,
+		Guild:       guild,
+		Args:        args,
+		Author:      i.Member.User,
+		Member:      i.Member,
+		ChannelID:   i.ChannelID,
+		Interaction: i,
+		replied:     true,
+	}
+}
+
+// Reply sends a message to the channel the command was invoked in. For slash
+// commands the first reply answers the interaction and later ones follow it up.
+func (ctx *Context) Reply(message string) error {
+	if err := checkLength(message); err != nil {
+		return err
+	}
+
+	if ctx.Interaction != nil {
+		return ctx.interactionReply(message)
+	}
+
+	if _, err := ctx.Session.ChannelMessageSend(ctx.ChannelID, message); err != nil {
+		log.Printf("failed to send message: %s", err.Error())
+		return err
+	}
+
+	if config.Debug {
+		log.Printf("Sent message to channel %s: %s", ctx.ChannelID, message)
+	}
+	return nil
+}
+
+// ReplyEmbeds sends embeds, with optional content, to the channel the command
+// was invoked in. Discord allows ten embeds a message, so more are split
+// across several messages. With no embeds the content is sent on its own.
+func (ctx *Context) ReplyEmbeds(content string, embeds ...*discordgo.MessageEmbed) error {
+	if len(embeds) == 0 {
+		return ctx.Reply(content)
+	}
+	if err := checkLength(content); err != nil {
+		return err
+	}
+
+	for _, batch := range embedBatches(embeds) {
+		var err error
+		if ctx.Interaction != nil {
+			err = ctx.interactionSend(content, batch)
+		} else {
+			_, err = ctx.Session.ChannelMessageSendComplex(ctx.ChannelID, &discordgo.MessageSend{Content: content, Embeds: batch})
+		}
+		if err != nil {
+			log.Printf("failed to send embeds: %s", err.Error())
+			return err
+		}
+		content = "" // Only the first message carries the content
+	}
+
+	if config.Debug {
+		log.Printf("Sent %d embeds to channel %s", len(embeds), ctx.ChannelID)
+	}
+	return nil
+}
+
+// ReplyPrivate sends a direct message to the user who invoked the command.
+func (ctx *Context) ReplyPrivate(message string) error {
+	if err := checkLength(message); err != nil {
+		return err
+	}
+
+	userChannel, err := ctx.Session.UserChannelCreate(ctx.Author.ID)
+	if err != nil {
+		log.Printf("failed to open channel to user %q: %s", ctx.Author.DisplayName(), err.Error())
+		return err
+	}
+
+	if _, err := ctx.Session.ChannelMessageSend(userChannel.ID, message); err != nil {
+		log.Printf("failed to send message: %s", err.Error())
+		return err
+	}
+
+	if config.Debug {
+		log.Printf("Sent private message to %s: %s", ctx.Author.DisplayName(), message)
+	}
+	return nil
+}
+
+// ReplyPrivateEmbeds sends embeds, with optional content, in a direct message
+// to the user who invoked the command. With no embeds the content is sent on its own.
+func (ctx *Context) ReplyPrivateEmbeds(content string, embeds ...*discordgo.MessageEmbed) error {
+	if len(embeds) == 0 {
+		return ctx.ReplyPrivate(content)
+	}
+	if err := checkLength(content); err != nil {
+		return err
+	}
+
+	userChannel, err := ctx.Session.UserChannelCreate(ctx.Author.ID)
+	if err != nil {
+		log.Printf("failed to open channel to user %q: %s", ctx.Author.DisplayName(), err.Error())
+		return err
+	}
+
+	for _, batch := range embedBatches(embeds) {
+		if _, err := ctx.Session.ChannelMessageSendComplex(userChannel.ID, &discordgo.MessageSend{Content: content, Embeds: batch}); err != nil {
+			log.Printf("failed to send embeds: %s", err.Error())
+			return err
+		}
+		content = ""
+	}
+
+	if config.Debug {
+		log.Printf("Sent %d private embeds to %s", len(embeds), ctx.Author.DisplayName())
+	}
+	return nil
+}
+
+// SendPrivateFile sends a direct message with a file attached to the user who invoked the command.
+func (ctx *Context) SendPrivateFile(message string, file *discordgo.File) error {
+	if err := checkLength(message); err != nil {
+		return err
+	}
+
+	userChannel, err := ctx.Session.UserChannelCreate(ctx.Author.ID)
+	if err != nil {
+		log.Printf("failed to open channel to user %q: %s", ctx.Author.DisplayName(), err.Error())
+		return err
+	}
+
+	if _, err := ctx.Session.ChannelMessageSendComplex(userChannel.ID, &discordgo.MessageSend{
+		Content: message,
+		Files:   []*discordgo.File{file},
+	}); err != nil {
+		log.Printf("failed to send file: %s", err.Error())
+		return err
+	}
+
+	if config.Debug {
+		log.Printf("Sent file %s to %s", file.Name, ctx.Author.DisplayName())
+	}
+	return nil
+}
+
+// ReplyEphemeral sends a message only the invoking user can see. Prefix
+// commands have no such message, so they fall back to a direct message.
+func (ctx *Context) ReplyEphemeral(message string) error {
+	if ctx.Interaction == nil {
+		return ctx.ReplyPrivate(message)
+	}
+	if err := checkLength(message); err != nil {
+		return err
+	}
+
+	if _, err := ctx.Session.FollowupMessageCreate(ctx.Interaction.Interaction, true, &discordgo.WebhookParams{
+		Content: message,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}); err != nil {
+		log.Printf("failed to send ephemeral reply: %s", err.Error())
+		return err
+	}
+
+	if config.Debug {
+		log.Printf("Sent ephemeral reply to %s: %s", ctx.Author.DisplayName(), message)
+	}
+	return nil
+}
+
+// Finish cleans up after the command has run. A slash command that never
+// replied publicly has its pending acknowledgement removed.
+func (ctx *Context) Finish() {
+	if ctx.Interaction == nil {
+		return
+	}
+
+	ctx.mu.Lock()
+	replied := ctx.replied
+	ctx.mu.Unlock()
+
+	if replied {
+		return
+	}
+	if err := ctx.Session.InteractionResponseDelete(ctx.Interaction.Interaction); err != nil {
+		log.Printf("failed removing interaction acknowledgement: %v", err)
+	}
+}
+
+func (ctx *Context) interactionReply(message string) error {
+	if err := ctx.interactionSend(message, nil); err != nil {
+		log.Printf("failed to send interaction reply: %s", err.Error())
+		return err
+	}
+
+	if config.Debug {
+		log.Printf("Sent interaction reply in channel %s: %s", ctx.ChannelID, message)
+	}
+	return nil
+}
+
+// interactionSend answers the interaction with the first message and follows it up with the rest.
+func (ctx *Context) interactionSend(content string, embeds []*discordgo.MessageEmbed) error {
+	ctx.mu.Lock()
+	first := !ctx.replied
+	ctx.replied = true
+	ctx.mu.Unlock()
+
+	var err error
+	if first {
+		edit := &discordgo.WebhookEdit{Content: &content}
+		if len(embeds) > 0 {
+			edit.Embeds = &embeds
+		}
+		_, err = ctx.Session.InteractionResponseEdit(ctx.Interaction.Interaction, edit)
+	} else {
+		_, err = ctx.Session.FollowupMessageCreate(ctx.Interaction.Interaction, true, &discordgo.WebhookParams{Content: content, Embeds: embeds})
+	}
+	return err
+}
+
+// embedBatches splits embeds into groups small enough for one message, by
+// both their number and their combined size.
+func embedBatches(embeds []*discordgo.MessageEmbed) [][]*discordgo.MessageEmbed {
+	var (
+		batches [][]*discordgo.MessageEmbed
+		batch   []*discordgo.MessageEmbed
+		size    int
+	)
+	for _, e := range embeds {
+		n := embedSize(e)
+		if len(batch) == maxEmbeds || (len(batch) > 0 && size+n > maxEmbedsSize) {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, e)
+		size += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func checkLength(message string) error {
+	if len(message) > maxMessageLength {
+		message := fmt.Sprintf("Message exceeds Discord's %d character limit: %d characters", maxMessageLength, len(message))
+		log.Println(message)
+		return errors.New(message)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+func Fear(c *Command, ctx *Context) error {
+	var (
+		args  = ctx.Args
+		guild = ctx.Guild
+		pools = guild.Pools()
+	)
+
+	if len(args) < 1 || strings.EqualFold(args[0], "show") {
+		return ctx.Reply(fmt.Sprintf("The GM has **%d/%d** Fear in campaign `%s`", pools.Fear(), config.MaxFear, pools.Campaign()))
+	}
+
+	subcommand := strings.ToLower(args[0])
+	if subcommand != "help" && !guild.IsGM(ctx.Member) {
+		log.Printf("[%s] user @%s (%s) is not a GM, denying access to fear %s", guild.Name(), ctx.Author.DisplayName(), ctx.Author, subcommand)
+		return ctx.Reply(fmt.Sprintf("you must be a GM to change the Fear pool, %s", ctx.Author))
+	}
+
+	switch subcommand {
+
+	case "add", "gain":
+		n, err := parseAmount(args[1:], 1)
+		if err != nil {
+			return ctx.Reply(err.Error())
+		}
+		change, err := pools.AddFear(n)
+		if err != nil {
+			log.Printf("Failed to save Fear for guild %q: %v", guild.Name(), err)
+		}
+		return ctx.Reply(fmt.Sprintf("The GM gains %d Fear (%d/%d)", change.Fear, change.TotalFear, config.MaxFear))
+
+	case "spend", "use":
+		n, err := parseAmount(args[1:], 1)
+		if err != nil {
+			return ctx.Reply(err.Error())
+		}
+		fear, err := pools.SpendFear(n)
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("The GM %s", err))
+		}
+		return ctx.Reply(fmt.Sprintf("The GM spends %d Fear (%d/%d)", n, fear, config.MaxFear))
+
+	case "set":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !fear set <amount>")
+		}
+		n, err := parseAmount(args[1:], 0)
+		if err != nil {
+			return ctx.Reply(err.Error())
+		}
+		fear, err := pools.SetFear(n)
+		if err != nil {
+			log.Printf("Failed to save Fear for guild %q: %v", guild.Name(), err)
+		}
+		return ctx.Reply(fmt.Sprintf("The GM now has %d/%d Fear", fear, config.MaxFear))
+
+	case "campaign":
+		if len(args) < 2 {
+			return ctx.Reply(fmt.Sprintf("Active campaign: `%s`\nKnown campaigns: `%s`", pools.Campaign(), strings.Join(pools.Campaigns(), "`, `")))
+		}
+		if err := pools.SetCampaign(strings.Join(args[1:], " ")); err != nil {
+			return ctx.Reply(fmt.Sprintf("Failed to switch campaign: %v", err))
+		}
+		return ctx.Reply(fmt.Sprintf("Active campaign set to `%s`", pools.Campaign()))
+
+	default:
+		return ctx.Reply(CommandHelp(c, guild.Prefix()))
+	}
+}
+
+// parseAmount reads an optional positive count from the first argument, falling back to def.
+func parseAmount(args []string, def int) (int, error) {
+	if len(args) < 1 || strings.TrimSpace(args[0]) == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args[0]), "+"))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a valid amount", args[0])
+	}
+	return n, nil
+}
+
+func init() {
+	cmd := NewCommand("Fear", "Shows or manages the GM's Fear pool", Fear)
+	cmd.Usage = []string{
+		"fear - Shows the GM's current Fear",
+		"fear add [amount] - Gives the GM Fear (default 1)",
+		"fear spend [amount] - Spends the GM's Fear (default 1)",
+		"fear set <amount> - Sets the GM's Fear",
+		"fear campaign [name] - Shows or switches the active campaign",
+		"fear help - Displays this help message",
+	}
+	cmd.Examples = []string{"fear spend 2", "fear campaign westmarches"}
+	cmd.SetOptions(
+		SubcommandOption("show", "Shows the GM's current Fear"),
+		SubcommandOption("add", "Gives the GM Fear", IntegerOption("amount", "Fear to add (default 1)", false)),
+		SubcommandOption("spend", "Spends the GM's Fear", IntegerOption("amount", "Fear to spend (default 1)", false)),
+		SubcommandOption("set", "Sets the GM's Fear", IntegerOption("amount", "New Fear total", true)),
+		SubcommandOption("campaign", "Shows or switches the active campaign", StringOption("name", "Campaign to switch to", false)),
+		SubcommandOption("help", "Displays Fear help"),
+	)
+	RegisterCommand(cmd)
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+func Help(c *Command, ctx *Context) error {
+	prefix := ctx.Guild.Prefix()
+
+	if len(ctx.Args) > 0 {
+		cmd, ok := Lookup(ctx.Args[0])
+		if !ok || !canRun(cmd, ctx) {
+			return ctx.Reply(fmt.Sprintf("Sorry, I don't know a command called %q. Try `%shelp` for a list.", ctx.Args[0], prefix))
+		}
+		return ctx.Reply(CommandHelp(cmd, prefix))
+	}
+
+	names := make([]string, 0, len(Commands))
+	for name, cmd := range Commands {
+		if canRun(cmd, ctx) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	response := "Available commands:\n"
+	for _, name := range names {
+		response += fmt.Sprintf("`%s%s` - %s\n", prefix, name, Commands[name].Description)
+	}
+	response += fmt.Sprintf("\nUse `%shelp <command>` for usage and examples.", prefix)
+
+	return ctx.Reply(response)
+}
+
+// CommandHelp renders a command's usage, examples and aliases from its declaration.
+func CommandHelp(cmd *Command, prefix string) string {
+	name := strings.ToLower(cmd.Name)
+	help := fmt.Sprintf("**%s%s** - %s\n", prefix, name, cmd.Description)
+
+	usage := cmd.Usage
+	if len(usage) == 0 {
+		usage = []string{name}
+	}
+	help += "\nUsage:\n"
+	for _, line := range usage {
+		if syntax, explanation, ok := strings.Cut(line, " - "); ok {
+			help += fmt.Sprintf("`%s%s` - %s\n", prefix, syntax, explanation)
+		} else {
+			help += fmt.Sprintf("`%s%s`\n", prefix, line)
+		}
+	}
+
+	if len(cmd.Examples) > 0 {
+		help += "\nExamples:\n"
+		for _, example := range cmd.Examples {
+			help += fmt.Sprintf("`%s%s`\n", prefix, example)
+		}
+	}
+
+	if len(cmd.Aliases) > 0 {
+		aliases := make([]string, 0, len(cmd.Aliases))
+		for _, alias := range cmd.Aliases {
+			aliases = append(aliases, fmt.Sprintf("`%s%s`", prefix, strings.ToLower(alias)))
+		}
+		help += "\nAliases: " + strings.Join(aliases, ", ") + "\n"
+	}
+
+	if p := cmd.Permission(); p != config.PermEveryone {
+		help += fmt.Sprintf("\n_Requires %s_\n", withArticle(p))
+	}
+
+	return strings.TrimSuffix(help, "\n")
+}
+
+// canRun reports whether the caller is allowed to run the command.
+func canRun(cmd *Command, ctx *Context) bool {
+	return ctx.Guild.HasPermission(ctx.Member, cmd.Permission())
+}
+
+func init() {
+	cmd := NewCommand("Help", "Lists commands, or shows usage for one", Help)
+	cmd.Usage = []string{
+		"help - Lists the commands you can use",
+		"help <command> - Shows usage, examples and aliases for a command",
+	}
+	cmd.Examples = []string{"help roll"}
+	cmd.Aliases = []string{"commands"}
+	cmd.SetOptions(StringOption("command", "Command to show usage for", false))
+	RegisterCommand(cmd)
+}
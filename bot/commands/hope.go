@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/discordapi"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+func Hope(c *Command, ctx *Context) error {
+	var (
+		args   = withoutMentions(ctx.Args)
+		guild  = ctx.Guild
+		pools  = guild.Pools()
+		target = hopeTarget(ctx)
+	)
+
+	if len(args) < 1 || strings.EqualFold(args[0], "show") {
+		return ctx.Reply(formatPlayerPool(target.DisplayName(), pools.Player(target.ID), pools.StressSlots(target.ID)))
+	}
+
+	subcommand := strings.ToLower(args[0])
+	if target.ID != ctx.Author.ID && !guild.IsGM(ctx.Member) {
+		log.Printf("[%s] user @%s (%s) is not a GM, denying access to hope %s for %s", guild.Name(), ctx.Author.DisplayName(), ctx.Author, subcommand, target)
+		return ctx.Reply(fmt.Sprintf("you must be a GM to change another player's Hope, %s", ctx.Author))
+	}
+
+	switch subcommand {
+
+	case "all":
+		players := pools.Players()
+		if len(players) == 0 {
+			return ctx.Reply(fmt.Sprintf("No Hope is being tracked in campaign `%s`", pools.Campaign()))
+		}
+		ids := make([]string, 0, len(players))
+		for id := range players {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		response := fmt.Sprintf("Hope in campaign `%s`:\n", pools.Campaign())
+		for _, id := range ids {
+			response += formatPlayerPool(memberName(ctx.Session, ctx.Guild.ID, id), players[id], pools.StressSlots(id)) + "\n"
+		}
+		return ctx.Reply(response)
+
+	case "add", "gain":
+		n, err := parseAmount(args[1:], 1)
+		if err != nil {
+			return ctx.Reply(err.Error())
+		}
+		change, err := pools.AddHope(target.ID, n)
+		if err != nil {
+			log.Printf("Failed to save Hope for guild %q: %v", guild.Name(), err)
+		}
+		return ctx.Reply(fmt.Sprintf("%s gains %d Hope (%d/%d)", target.DisplayName(), change.Hope, change.Player.Hope, config.MaxHope))
+
+	case "spend", "use":
+		n, err := parseAmount(args[1:], 1)
+		if err != nil {
+			return ctx.Reply(err.Error())
+		}
+		pp, err := pools.SpendHope(target.ID, n)
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("%s %s", target.DisplayName(), err))
+		}
+		return ctx.Reply(fmt.Sprintf("%s spends %d Hope (%d/%d)", target.DisplayName(), n, pp.Hope, config.MaxHope))
+
+	case "set":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !hope set <amount> [@player]")
+		}
+		n, err := parseAmount(args[1:], 0)
+		if err != nil {
+			return ctx.Reply(err.Error())
+		}
+		pp, err := pools.SetHope(target.ID, n)
+		if err != nil {
+			log.Printf("Failed to save Hope for guild %q: %v", guild.Name(), err)
+		}
+		return ctx.Reply(fmt.Sprintf("%s now has %d/%d Hope", target.DisplayName(), pp.Hope, config.MaxHope))
+
+	case "stress":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !hope stress <+amount|-amount> [@player]")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("%q is not a valid amount", args[1]))
+		}
+		pp, err := pools.AddStress(target.ID, n)
+		if err != nil {
+			log.Printf("Failed to save Stress for guild %q: %v", guild.Name(), err)
+		}
+		return ctx.Reply(fmt.Sprintf("%s has %d/%d Stress marked", target.DisplayName(), pp.Stress, pools.StressSlots(target.ID)))
+
+	default:
+		return ctx.Reply(CommandHelp(c, guild.Prefix()) + "\n\nOnly GMs can change another player's Hope or Stress")
+	}
+}
+
+// hopeTarget returns the first user mentioned in the message, or the author if nobody was.
+func hopeTarget(ctx *Context) *discordgo.User {
+	me := ctx.Session.Me()
+	for _, mention := range ctx.Mentions {
+		if me == nil || mention.ID != me.ID {
+			return mention
+		}
+	}
+	return ctx.Author
+}
+
+// withoutMentions drops user mentions from args so they can appear anywhere in the command.
+func withoutMentions(args []string) []string {
+	filtered := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "<@") {
+			filtered = append(filtered, arg)
+		}
+	}
+	return filtered
+}
+
+func formatPlayerPool(name string, pp config.PlayerPool, stressSlots int) string {
+	return fmt.Sprintf("**%s**: Hope %d/%d, Stress %d/%d", name, pp.Hope, config.MaxHope, pp.Stress, stressSlots)
+}
+
+// memberName returns the display name of a guild member, falling back to a mention.
+func memberName(s discordapi.Session, guildID, userID string) string {
+	if member, err := s.Member(guildID, userID); err == nil {
+		return member.DisplayName()
+	}
+	return fmt.Sprintf("<@%s>", userID)
+}
+
+func init() {
+	cmd := NewCommand("Hope", "Shows or manages player Hope and Stress", Hope)
+	cmd.Usage = []string{
+		"hope [@player] - Shows your (or a player's) Hope and Stress",
+		"hope all - Shows everyone's Hope and Stress",
+		"hope add [amount] [@player] - Gains Hope (default 1)",
+		"hope spend [amount] [@player] - Spends Hope (default 1)",
+		"hope set <amount> [@player] - Sets Hope",
+		"hope stress <+amount|-amount> [@player] - Marks or clears Stress",
+		"hope help - Displays this help message",
+	}
+	cmd.Examples = []string{"hope spend", "hope stress +1", "hope set 3 @player"}
+	cmd.SetOptions(
+		SubcommandOption("show", "Shows your (or a player's) Hope and Stress", UserOption("player", "Player to show", false)),
+		SubcommandOption("all", "Shows everyone's Hope and Stress"),
+		SubcommandOption("add", "Gains Hope", IntegerOption("amount", "Hope to gain (default 1)", false), UserOption("player", "Player to change", false)),
+		SubcommandOption("spend", "Spends Hope", IntegerOption("amount", "Hope to spend (default 1)", false), UserOption("player", "Player to change", false)),
+		SubcommandOption("set", "Sets Hope", IntegerOption("amount", "New Hope total", true), UserOption("player", "Player to change", false)),
+		SubcommandOption("stress", "Marks (positive) or clears (negative) Stress", IntegerOption("amount", "Stress to mark or clear", true), UserOption("player", "Player to change", false)),
+		SubcommandOption("help", "Displays Hope help"),
+	)
+	RegisterCommand(cmd)
+}
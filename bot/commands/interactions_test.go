@@ -0,0 +1,123 @@
+package commands_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/commands"
+	"github.com/nerdwerx/daggerbot/bot/discordtest"
+)
+
+func option(name string, typ discordgo.ApplicationCommandOptionType, value any) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: typ, Value: value}
+}
+
+func subcommand(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+}
+
+// response returns the reply that completed a deferred slash command.
+func response(t *testing.T, sent []discordtest.Sent) discordtest.Sent {
+	t.Helper()
+
+	if len(sent) > 0 && sent[0].Action == "respond" {
+		for _, s := range sent[1:] {
+			if s.Action == "response-edit" {
+				return s
+			}
+		}
+	}
+	t.Fatalf("got %+v, want a deferred response then its edit", sent)
+	return discordtest.Sent{}
+}
+
+func TestSlashSessionAndButtons(t *testing.T) {
+	h := newHarness(t)
+	gm := h.AddRole("GM")
+	h.AddMember("1", "Tam", gm.ID)
+	h.AddMember("2", "Mira")
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+
+	sent := h.Slash("1", "session", subcommand("create",
+		option("title", discordgo.ApplicationCommandOptionString, "Into the Mire"),
+		option("when", discordgo.ApplicationCommandOptionString, "friday 7pm"),
+		option("players", discordgo.ApplicationCommandOptionInteger, float64(4)),
+	))
+	created := response(t, sent)
+	if !strings.Contains(created.Content, "Scheduled session #1") {
+		t.Errorf("creating the session replied %q", created.Content)
+	}
+	var announcement discordtest.Sent
+	for _, s := range sent {
+		if len(s.Components) > 0 {
+			announcement = s
+		}
+	}
+	if announcement.MessageID == "" {
+		t.Fatalf("no announcement with sign-up buttons in %+v", sent)
+	}
+
+	sent = h.Press("2", announcement.MessageID, commands.ComponentID("session", "join", "1"))
+	var joined, refreshed bool
+	for _, s := range sent {
+		switch {
+		case s.Action == "edit" && s.MessageID == announcement.MessageID:
+			refreshed = true
+		case s.Ephemeral && strings.Contains(s.Content, "You're on the roster for session #1"):
+			joined = true
+		}
+	}
+	if !joined || !refreshed {
+		t.Errorf("pressing join: told Mira %v, refreshed the announcement %v\n%+v", joined, refreshed, sent)
+	}
+
+	sent = h.Press("2", announcement.MessageID, commands.ComponentID("session", "join", "1"))
+	if s := sent[len(sent)-1]; !s.Ephemeral || !strings.Contains(s.Content, "already signed up") {
+		t.Errorf("pressing join twice replied %+v", s)
+	}
+}
+
+func TestSlashHopeResolvesPlayers(t *testing.T) {
+	h := newHarness(t)
+	gm := h.AddRole("GM")
+	h.AddMember("1", "Tam", gm.ID)
+	h.AddMember("2", "Mira")
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+
+	reply := response(t, h.Slash("1", "hope", subcommand("add",
+		option("amount", discordgo.ApplicationCommandOptionInteger, float64(2)),
+		option("player", discordgo.ApplicationCommandOptionUser, "2"),
+	)))
+	if !strings.Contains(reply.Content, "Mira gains 2 Hope") {
+		t.Errorf("GM adding Hope for Mira replied %q", reply.Content)
+	}
+
+	reply = response(t, h.Slash("2", "hope", subcommand("spend",
+		option("player", discordgo.ApplicationCommandOptionUser, "1"),
+	)))
+	if !strings.Contains(reply.Content, "you must be a GM") {
+		t.Errorf("a player changing another's Hope replied %q", reply.Content)
+	}
+
+	reply = response(t, h.Slash("2", "hope", subcommand("show")))
+	if !strings.Contains(reply.Content, "**Mira**: Hope 2/") {
+		t.Errorf("showing Mira's Hope replied %q", reply.Content)
+	}
+}
+
+func TestSlashSessionTitleEndingInADay(t *testing.T) {
+	h := newHarness(t)
+	gm := h.AddRole("GM")
+	h.AddMember("1", "Tam", gm.ID)
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+
+	created := response(t, h.Slash("1", "session", subcommand("create",
+		option("title", discordgo.ApplicationCommandOptionString, "Raid on Friday"),
+		option("when", discordgo.ApplicationCommandOptionString, "8pm"),
+		option("players", discordgo.ApplicationCommandOptionInteger, float64(4)),
+	)))
+	if !strings.Contains(created.Content, "**Raid on Friday**") {
+		t.Errorf("creating the session replied %q, want the whole title kept", created.Content)
+	}
+}
@@ -2,12 +2,10 @@ package commands
 
 import (
 	"fmt"
-
-	"github.com/bwmarrin/discordgo"
 )
 
-func Ping(c *Command, s *discordgo.Session, m *discordgo.MessageCreate) error {
-	if _, err := s.ChannelMessageSend(m.ChannelID, "Pong!"); err != nil {
+func Ping(c *Command, ctx *Context) error {
+	if err := ctx.Reply("Pong!"); err != nil {
 		return fmt.Errorf("failed to send Pong response: %w", err)
 	}
 	return nil
@@ -1,13 +1,14 @@
 package commands
 
-import (
-	"github.com/bwmarrin/discordgo"
-)
-
-func proll(c *Command, s *discordgo.Session, m *discordgo.MessageCreate) error {
-	return MessagePrivateSend(s, m, parseRoll(c.Args(), "your"))
+func proll(c *Command, ctx *Context) error {
+	return replyRolls(ctx, parseRoll(ctx.RNG, ctx.Args, "your", activeCharacter(ctx), trackDuality(ctx.Guild, ctx.Author)), true)
 }
 
 func init() {
-	RegisterCommand(NewCommand("PRoll", "Privately replies with Roll!", proll))
+	cmd := NewCommand("PRoll", "Privately replies with Roll!", proll)
+	cmd.Usage = []string{"proll [dice or duality...] - Rolls like roll, but replies in a direct message"}
+	cmd.Examples = []string{"proll duality +1", "proll d20"}
+	cmd.Aliases = []string{"pr"}
+	cmd.SetOptions(StringOption("dice", "Dice expression such as 2d6+3, or duality +2 adv dc 14", false))
+	RegisterCommand(cmd)
 }
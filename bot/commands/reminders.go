@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/discordapi"
+	"github.com/nerdwerx/daggerbot/bot/scheduler"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+const reminderJob = "session-reminder" // Job kind for session reminders
+
+// ReminderLeads are how long before a session starts its reminders go out.
+var ReminderLeads = []time.Duration{24 * time.Hour, time.Hour}
+
+// RegisterReminders installs the session reminder handler, sending through discord.
+func RegisterReminders(s *scheduler.Scheduler, discord discordapi.Session) {
+	s.Handle(reminderJob, func(job scheduler.Job) error {
+		return sendReminder(discord, job)
+	})
+}
+
+// scheduleReminders queues a reminder for each lead time that is still in the
+// future. Reminders are skipped when sched is nil.
+func scheduleReminders(sched *scheduler.Scheduler, guild *config.Guild, sess config.Session) {
+	if sched == nil {
+		return
+	}
+	now := sched.Clock().Now()
+	for _, lead := range ReminderLeads {
+		due := sess.Start.Add(-lead)
+		if !due.After(now) {
+			continue
+		}
+		if err := sched.Schedule(scheduler.Job{
+			ID:      reminderPrefix(guild.ID, sess.ID) + lead.String(),
+			Kind:    reminderJob,
+			Due:     due,
+			Expires: sess.Start,
+			Data:    map[string]string{"guild": guild.ID, "session": sess.ID, "lead": lead.String()},
+		}); err != nil {
+			log.Printf("[%s] failed to schedule reminder for session #%s: %v", guild.Name(), sess.ID, err)
+		}
+	}
+}
+
+// cancelReminders drops any pending reminders for a session.
+func cancelReminders(sched *scheduler.Scheduler, guild *config.Guild, sess config.Session) {
+	if sched == nil {
+		return
+	}
+	if _, err := sched.Cancel(reminderPrefix(guild.ID, sess.ID)); err != nil {
+		log.Printf("[%s] failed to cancel reminders for session #%s: %v", guild.Name(), sess.ID, err)
+	}
+}
+
+// reminderPrefix is shared by every reminder job for a session. The trailing
+// separator keeps session #1 from matching session #10.
+func reminderPrefix(guildID, sessionID string) string {
+	return fmt.Sprintf("session-%s-%s-", guildID, sessionID)
+}
+
+// sendReminder posts a reminder in the session's channel, pinging the roster,
+// and sends each player a direct message. A failed post is returned so the
+// job is retried; direct messages are best effort.
+func sendReminder(discord discordapi.Session, job scheduler.Job) error {
+	guild, ok := config.Guilds.Get(job.Data["guild"])
+	if !ok {
+		return nil // The bot has left the guild
+	}
+	sess, err := guild.Sessions().Get(job.Data["session"])
+	if errors.Is(err, config.ErrSessionNotFound) || sess.Status == config.SessionCancelled {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	lead, err := time.ParseDuration(job.Data["lead"])
+	if err != nil {
+		return fmt.Errorf("reading reminder lead time: %w", err)
+	}
+	reminder := fmt.Sprintf("**Reminder:** session #%s, **%s**, starts in %s (%s)", sess.ID, sess.Title, formatLead(lead), formatSessionTime(sess.Start))
+
+	if sess.ChannelID != "" {
+		if _, err := discord.ChannelMessageSendComplex(sess.ChannelID, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("%s\n<@%s> %s", reminder, sess.GMID, mentions(sess.Players)),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: append([]string{sess.GMID}, sess.Players...)},
+		}); err != nil {
+			return fmt.Errorf("posting reminder for session #%s: %w", sess.ID, err)
+		}
+	}
+
+	for _, player := range sess.Players {
+		channel, err := discord.UserChannelCreate(player)
+		if err != nil {
+			log.Printf("[%s] failed to open a DM with %s: %v", guild.Name(), player, err)
+			continue
+		}
+		if _, err := discord.ChannelMessageSend(channel.ID, reminder); err != nil {
+			log.Printf("[%s] failed to send reminder to %s: %v", guild.Name(), player, err)
+		}
+	}
+
+	return nil
+}
+
+// formatLead renders a lead time as "24 hours" or "1 hour".
+func formatLead(lead time.Duration) string {
+	switch hours := int(lead.Hours()); {
+	case hours == 1:
+		return "1 hour"
+	case hours > 1:
+		return fmt.Sprintf("%d hours", hours)
+	default:
+		return fmt.Sprintf("%d minutes", int(lead.Minutes()))
+	}
+}
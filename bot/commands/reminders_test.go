@@ -0,0 +1,146 @@
+package commands_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerdwerx/daggerbot/bot/commands"
+	"github.com/nerdwerx/daggerbot/bot/discordtest"
+	"github.com/nerdwerx/daggerbot/bot/scheduler"
+)
+
+// reminderHarness runs session reminders on a fake clock, with Tam as the
+// GM and Mira as a player.
+func reminderHarness(t *testing.T) (*discordtest.Harness, *scheduler.FakeClock, *scheduler.Scheduler) {
+	t.Helper()
+
+	h := newHarness(t)
+	clock := scheduler.NewFakeClock(time.Now())
+	sched := scheduler.New(clock)
+	commands.RegisterReminders(sched, h.Fake)
+	h.Scheduler = sched
+
+	gm := h.AddRole("GM")
+	h.AddMember("1", "Tam", gm.ID)
+	h.AddMember("2", "Mira")
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+	return h, clock, sched
+}
+
+// createSession has Tam post a session three days out, with Mira signed up.
+func createSession(t *testing.T, h *discordtest.Harness) {
+	t.Helper()
+
+	sent := h.Say("1", "!session create Into the Mire in 3 days 4")
+	if len(sent) == 0 || !strings.Contains(sent[len(sent)-1].Content, "Scheduled session #1") {
+		t.Fatalf("creating the session replied %+v", sent)
+	}
+	h.Say("2", "!session join 1")
+}
+
+// advance moves the clock on and runs whatever came due, returning what was sent.
+func advance(h *discordtest.Harness, clock *scheduler.FakeClock, sched *scheduler.Scheduler, d time.Duration) []discordtest.Sent {
+	before := len(h.Fake.Sent())
+	clock.Advance(d)
+	sched.RunDue()
+	return h.Fake.Since(before)
+}
+
+func TestSessionReminders(t *testing.T) {
+	h, clock, sched := reminderHarness(t)
+	createSession(t, h)
+
+	if jobs := sched.Jobs(); len(jobs) != 2 {
+		t.Fatalf("scheduled %d reminders, want 2", len(jobs))
+	}
+
+	if sent := advance(h, clock, sched, 47*time.Hour); len(sent) != 0 {
+		t.Errorf("sent %d messages before the first reminder was due", len(sent))
+	}
+
+	sent := advance(h, clock, sched, time.Hour)
+	assertReminder(t, sent, "starts in 24 hours")
+
+	sent = advance(h, clock, sched, 23*time.Hour)
+	assertReminder(t, sent, "starts in 1 hour")
+
+	if jobs := sched.Jobs(); len(jobs) != 0 {
+		t.Errorf("%d reminders are still pending after they were sent", len(jobs))
+	}
+	if sent := advance(h, clock, sched, 2*time.Hour); len(sent) != 0 {
+		t.Errorf("sent %d messages after the session started", len(sent))
+	}
+}
+
+// assertReminder checks a reminder went to the session's channel, pinging the roster, and to Mira directly.
+func assertReminder(t *testing.T, sent []discordtest.Sent, want string) {
+	t.Helper()
+
+	var posted, messaged bool
+	for _, s := range sent {
+		if !strings.Contains(s.Content, want) {
+			t.Errorf("unexpected message %q, want %q", s.Content, want)
+			continue
+		}
+		switch s.ChannelID {
+		case discordtest.ChannelID:
+			posted = strings.Contains(s.Content, "<@1>") && strings.Contains(s.Content, "<@2>")
+		case "dm-2":
+			messaged = s.DM
+		}
+	}
+	if !posted || !messaged {
+		t.Errorf("reminder %q posted in the channel: %v, sent to Mira: %v\n%+v", want, posted, messaged, sent)
+	}
+}
+
+func TestCancelledSessionSendsNoReminders(t *testing.T) {
+	h, clock, sched := reminderHarness(t)
+	createSession(t, h)
+
+	h.Say("1", "!session cancel 1")
+	if jobs := sched.Jobs(); len(jobs) != 0 {
+		t.Errorf("%d reminders are pending for a cancelled session", len(jobs))
+	}
+	if sent := advance(h, clock, sched, 72*time.Hour); len(sent) != 0 {
+		t.Errorf("sent %+v for a cancelled session", sent)
+	}
+}
+
+func TestRemindersSurviveReload(t *testing.T) {
+	h, clock, _ := reminderHarness(t)
+	createSession(t, h)
+
+	// A restart: a new scheduler picks the jobs up from storage
+	reloaded := scheduler.New(clock)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	commands.RegisterReminders(reloaded, h.Fake)
+	h.Scheduler = reloaded
+
+	if jobs := reloaded.Jobs(); len(jobs) != 2 {
+		t.Fatalf("reloaded %d reminders, want 2", len(jobs))
+	}
+	assertReminder(t, advance(h, clock, reloaded, 48*time.Hour), "starts in 24 hours")
+	assertReminder(t, advance(h, clock, reloaded, 23*time.Hour), "starts in 1 hour")
+}
+
+func TestFailedReminderIsRetried(t *testing.T) {
+	h, clock, sched := reminderHarness(t)
+	createSession(t, h)
+
+	h.Fake.FailSends(discordtest.ChannelID, 1)
+	if sent := advance(h, clock, sched, 48*time.Hour); len(sent) != 0 {
+		t.Errorf("sent %+v while the channel post failed, want nothing until the retry", sent)
+	}
+	if jobs := sched.Jobs(); len(jobs) != 2 || jobs[0].Attempts != 1 {
+		t.Fatalf("after a failed post the pending reminders are %+v, want the 24 hour one kept for a retry", jobs)
+	}
+
+	assertReminder(t, advance(h, clock, sched, scheduler.RetryDelay), "starts in 24 hours")
+	if jobs := sched.Jobs(); len(jobs) != 1 {
+		t.Errorf("%d reminders are pending after the retry, want only the 1 hour one", len(jobs))
+	}
+}
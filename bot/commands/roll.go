@@ -1,123 +1,374 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
-	"math"
-	"math/rand"
-	"regexp"
+	"log"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/config"
 )
 
-func roll(c *Command, s *discordgo.Session, m *discordgo.MessageCreate) error {
-	return MessageSend(s, m, parseRoll(c.Args(), m.Author.DisplayName()))
+func roll(c *Command, ctx *Context) error {
+	return replyRolls(ctx, parseRoll(ctx.RNG, ctx.Args, ctx.Author.DisplayName(), activeCharacter(ctx), trackDuality(ctx.Guild, ctx.Author)), false)
 }
 
-func parseRoll(args []string, roller string) string {
-	if len(args) < 1 {
-		return rollDuality(roller)
+// activeCharacter returns the author's active character, or nil when they have none.
+func activeCharacter(ctx *Context) *config.Character {
+	if ctx.Guild == nil || ctx.Author == nil {
+		return nil
+	}
+	char, err := ctx.Guild.Characters().Active(ctx.Author.ID)
+	if err != nil {
+		return nil
 	}
+	return &char
+}
 
-	var results []string
+// A tracker records a duality roll and returns a note describing any change it made.
+type tracker func(result *dice.ActionResult) string
 
-	for _, roll := range args {
-		if strings.EqualFold(roll, "duality") || strings.EqualFold(roll, "duelity") {
-			results = append(results, rollDuality(roller))
-			continue
+// trackDuality records duality rolls against the guild's Hope and Fear pools.
+func trackDuality(guild *config.Guild, user *discordgo.User) tracker {
+	return func(result *dice.ActionResult) string {
+		if guild == nil || user == nil {
+			return ""
+		}
+		change, err := guild.Pools().RecordDuality(user.ID, result.WithHope(), result.Critical)
+		if err != nil {
+			log.Printf("[%s] failed to record duality roll for %s: %v", guild.Name(), user, err)
 		}
+		return change.Describe(user.DisplayName())
+	}
+}
+
+// parseRoll rolls every roll in args. Trait names and `attack` read their
+// bonuses from char, which may be nil when the roller has no character.
+func parseRoll(rng dice.RNG, args []string, roller string, char *config.Character, track tracker) []rollResult {
+	if len(args) < 1 {
+		return []rollResult{rollDuality(rng, roller, dice.Action{}, track)}
+	}
 
-		rollRegex := regexp.MustCompile("^[0-9]*d[0-9]+.*$")
-		isMultiDiceRoll := rollRegex.MatchString(roll)
+	var results []rollResult
 
-		diceNum, err := strconv.ParseFloat(roll, 64)
-		if err != nil && !isMultiDiceRoll {
-			results = append(results, fmt.Sprintf("%s is not a valid roll. A roll is a number, duality, or dice abbreviation", roll))
+	for i := 0; i < len(args); i++ {
+		roll := strings.TrimSpace(args[i])
+		if roll == "" {
 			continue
 		}
-		if isMultiDiceRoll {
-			results = append(results, rollMultiDice(roll, roller))
-		} else {
-			diceRollResultString := strconv.FormatFloat(rollDice(diceNum), 'f', -1, 64)
 
-			if diceRollResultString == "1" {
-				results = append(results, fmt.Sprintf("%s d%s result is %s :cry:\n", roller, roll, diceRollResultString))
+		if keyword := strings.ToLower(roll); isActionKeyword(keyword) {
+			start := i
+			action, damage, err := characterAction(rng, keyword, char)
+			if err != nil {
+				results = append(results, rollResult{text: err.Error()})
+				continue
+			}
+			action, consumed, err := parseAction(args[i+1:], char, action)
+			i += consumed
+			if err != nil {
+				results = append(results, rollResult{text: err.Error()})
 				continue
 			}
+			result := rollDuality(rng, roller, action, track, damage)
+			result.record.Expression = strings.Join(strings.Fields(strings.Join(args[start:i+1], " ")), " ")
+			results = append(results, result)
+			continue
+		}
 
-			results = append(results, fmt.Sprintf("%s d%s result is %s\n", roller, roll, diceRollResultString))
+		// Rejoin expressions that were split on spaces, e.g. `2d6 + 3`
+		for i+1 < len(args) && continuesExpression(roll, strings.TrimSpace(args[i+1])) {
+			i++
+			roll += strings.TrimSpace(args[i])
 		}
+
+		results = append(results, rollExpression(rng, roll, roller))
 	}
 
-	return strings.Join(results, "\n")
+	return results
 }
 
-func rollDuality(roller string) string {
-
-	hope := rollDice(12)
-	hopeString := strconv.FormatFloat(hope, 'f', -1, 64)
+// isActionKeyword reports whether a roll starts a Daggerheart action roll.
+func isActionKeyword(keyword string) bool {
+	switch keyword {
+	case "duality", "duelity", "attack":
+		return true
+	}
+	return slices.Contains(config.Traits, keyword)
+}
 
-	fear := rollDice(12)
-	fearString := strconv.FormatFloat(fear, 'f', -1, 64)
+// characterAction starts the action for a keyword, applying the character's
+// trait or weapon. Attacks also return a tracker that rolls the weapon's damage.
+func characterAction(rng dice.RNG, keyword string, char *config.Character) (dice.Action, tracker, error) {
+	var action dice.Action
 
-	result := hope + fear
-	resultString := strconv.FormatFloat(result, 'f', -1, 64)
+	switch keyword {
+	case "duality", "duelity":
+		return action, nil, nil
 
-	if hope == fear {
-		return fmt.Sprintf("# %s CRIT!!! :dagger: :heart:\n> with double %s", strings.ToUpper(roller), hopeString)
+	case "attack":
+		if char == nil {
+			return action, nil, errors.New("You need an active character to roll an attack, see `!char create`")
+		}
+		w := char.Weapon
+		if w.Trait == "" || w.Damage == "" {
+			return action, nil, fmt.Errorf("%s has no weapon, set one with `!char set weapon_trait <trait>` and `!char set damage <dice>`", char.Name)
+		}
+		trait, _ := char.Trait(w.Trait)
+		action.AddBonus(traitLabel(w.Trait), trait)
+		return action, rollDamage(rng, w, char.Proficiency), nil
 	}
 
-	dualityResult := fmt.Sprintf("%s rolled %s ", roller, resultString)
-	if hope > fear {
-		dualityResult += "with Hope :heart:"
-	} else {
-		dualityResult += "with Fear :dagger:"
+	if char == nil {
+		return action, nil, fmt.Errorf("You need an active character to roll %s, see `!char create`", keyword)
 	}
+	trait, _ := char.Trait(keyword)
+	action.AddBonus(traitLabel(keyword), trait)
+	return action, nil, nil
+}
+
+// rollDamage returns a tracker that rolls a weapon's damage when the attack
+// hits: a critical, or a success against a Difficulty. Without a Difficulty
+// the hit isn't known, so the damage is shown for the GM to confirm.
+func rollDamage(rng dice.RNG, w config.Weapon, proficiency int) tracker {
+	return func(result *dice.ActionResult) string {
+		hit := result.Critical || (result.Difficulty > 0 && result.Succeeded)
+		if !hit && result.Difficulty > 0 {
+			return ""
+		}
 
-	return fmt.Sprintf("%s\n> _Hope_ was %s and _Fear_ was %s", dualityResult, hopeString, fearString)
+		expr := damageExpression(w.Damage, proficiency)
+		damage, err := dice.Roll(rng, expr)
+		if err != nil {
+			return fmt.Sprintf("Invalid damage %q: %v", expr, err)
+		}
+
+		name := w.Name
+		if name == "" {
+			name = "weapon"
+		}
+		note := fmt.Sprintf("%s damage %s: **%d** (%s)", name, expr, damage.Total, breakdown(damage, maxBreakdown))
+		if !hit {
+			note = "If it hits, " + note
+		}
+		return note
+	}
 }
 
-func rollDice(diceSides float64) float64 {
-	return math.Ceil(rand.Float64() * diceSides)
+// damageExpression rolls one weapon die per point of Proficiency, e.g. d8+1 becomes 2d8+1.
+func damageExpression(damage string, proficiency int) string {
+	if strings.HasPrefix(damage, "d") && proficiency > 0 {
+		return strconv.Itoa(proficiency) + damage
+	}
+	return damage
 }
 
-func rollMultiDice(diceDesignation string, roller string) string {
-	var positiveModifier float64 = 0
-	var negativeModifier float64 = 0
-	var diceTotal float64 = 0
-	var diceDesignationString = diceDesignation
+func traitLabel(trait string) string {
+	return strings.ToUpper(trait[:1]) + trait[1:]
+}
 
-	if strings.Contains(diceDesignation, "+") {
-		modifierSplitStrings := strings.Split(diceDesignation, "+")
-		positiveModifier, _ = strconv.ParseFloat(modifierSplitStrings[1], 64)
-		diceDesignation = modifierSplitStrings[0]
-	} else if strings.Contains(diceDesignation, "-") {
-		modifierSplitStrings := strings.Split(diceDesignation, "-")
-		negativeModifier, _ = strconv.ParseFloat(modifierSplitStrings[1], 64)
-		diceDesignation = modifierSplitStrings[0]
+// rollExpression rolls a single dice expression. A bare number is shorthand for one die of that size.
+func rollExpression(rng dice.RNG, roll string, roller string) rollResult {
+	if _, err := strconv.Atoi(roll); err == nil {
+		roll = "d" + roll
 	}
 
-	splitString := strings.Split(diceDesignation, "d")
-	numRolls, err := strconv.Atoi(splitString[0])
+	result, err := dice.Roll(rng, roll)
 	if err != nil {
-		numRolls = 1
+		return rollResult{text: fmt.Sprintf("Invalid roll: %v. A roll is a number, duality, or dice expression", err)}
 	}
-	rollValue, err := strconv.ParseFloat(splitString[1], 64)
-	if err != nil {
-		return fmt.Sprintf("%s is not a valid roll. A roll is a number, duality, or dice abbreviation", diceDesignationString)
+
+	response := fmt.Sprintf("%s %s result is %d", roller, truncate(roll, maxEmbedTitle), result.Total)
+	if natural, ok := result.Natural(); ok && natural == 1 {
+		response += " :cry:"
+	}
+
+	record := &config.RollRecord{Kind: config.RollDice, Expression: roll, Total: result.Total}
+	for _, g := range result.Groups {
+		for _, d := range g.Dice {
+			record.Dice = append(record.Dice, config.RolledDie{Sides: g.Sides, Value: d.Value, Dropped: d.Dropped})
+		}
+	}
+
+	return rollResult{
+		text:   fmt.Sprintf("%s\n> %s\n", response, breakdown(result, maxBreakdown)),
+		embed:  expressionEmbed(roll, result),
+		record: record,
+	}
+}
+
+// continuesExpression reports whether next belongs to the same expression as current.
+func continuesExpression(current, next string) bool {
+	if current == "" || next == "" {
+		return false
+	}
+	if strings.Count(current, "(") > strings.Count(current, ")") {
+		return true
+	}
+	return strings.ContainsAny(current[len(current)-1:], "+-*/(<>=") || strings.ContainsAny(next[:1], "+-*/)<>=!")
+}
+
+// parseAction consumes the qualifiers following an action keyword, e.g.
+// `+2 adv dc 14` or `+exp Tracking`, adding them to action. It returns the
+// action along with the number of arguments consumed.
+func parseAction(args []string, char *config.Character, action dice.Action) (dice.Action, int, error) {
+	i := 0
+	for ; i < len(args); i++ {
+		arg := strings.ToLower(strings.TrimSpace(args[i]))
+
+		switch arg {
+		case "":
+			continue
+		case "adv", "advantage":
+			action.Advantage++
+			continue
+		case "dis", "disadv", "disadvantage":
+			action.Advantage--
+			continue
+		case "exp", "+exp", "experience", "+experience":
+			if char == nil {
+				return action, i + 1, errors.New("You need an active character to use an Experience, see `!char create`")
+			}
+			exp, consumed := matchExperience(char, args[i+1:])
+			if consumed == 0 {
+				return action, i + 1, fmt.Errorf("`%s` must be followed by one of %s's Experiences", arg, char.Name)
+			}
+			i += consumed
+			action.AddBonus(exp.Name, exp.Bonus)
+			continue
+		case "dc", "vs", "difficulty":
+			if i+1 >= len(args) {
+				return action, i + 1, fmt.Errorf("`%s` must be followed by a Difficulty, e.g. `dc 14`", arg)
+			}
+			i++
+			arg += args[i]
+		}
+
+		if after, ok := cutAnyPrefix(arg, "dc", "vs", "difficulty"); ok {
+			difficulty, err := strconv.Atoi(after)
+			if err != nil || difficulty < 1 {
+				return action, i + 1, fmt.Errorf("%q is not a valid Difficulty", after)
+			}
+			action.Difficulty = difficulty
+			continue
+		}
+
+		if arg[0] != '+' && arg[0] != '-' {
+			break // Not a qualifier, leave it for the next roll
+		}
+		modifier, err := strconv.Atoi(arg)
+		if err != nil {
+			return action, i + 1, fmt.Errorf("%q is not a valid modifier", arg)
+		}
+		action.Modifier += modifier
+	}
+
+	return action, i, nil
+}
+
+// matchExperience finds the character's Experience named by the longest
+// prefix of args, returning it and the number of arguments it used.
+func matchExperience(char *config.Character, args []string) (config.Experience, int) {
+	for n := len(args); n > 0; n-- {
+		if exp, ok := char.Experience(strings.Join(args[:n], " ")); ok {
+			return exp, n
+		}
+	}
+	return config.Experience{}, 0
+}
+
+func cutAnyPrefix(s string, prefixes ...string) (string, bool) {
+	for _, prefix := range prefixes {
+		if after, ok := strings.CutPrefix(s, prefix); ok {
+			return after, true
+		}
+	}
+	return s, false
+}
+
+func rollDuality(rng dice.RNG, roller string, action dice.Action, trackers ...tracker) rollResult {
+	result := dice.RollAction(rng, action)
+
+	var notes []string
+	for _, track := range trackers {
+		if track == nil {
+			continue
+		}
+		if n := track(result); n != "" {
+			notes = append(notes, n)
+		}
+	}
+
+	return rollResult{
+		text:   dualityText(roller, result, notes),
+		embed:  dualityEmbed(result, notes),
+		record: dualityRecord(result),
+	}
+}
+
+// dualityRecord describes an action roll for the roll history.
+func dualityRecord(result *dice.ActionResult) *config.RollRecord {
+	record := &config.RollRecord{
+		Kind:       config.RollDuality,
+		Expression: "duality",
+		Dice: []config.RolledDie{
+			{Sides: dice.DualitySides, Value: result.Hope, Label: "hope"},
+			{Sides: dice.DualitySides, Value: result.Fear, Label: "fear"},
+		},
+		Total:    result.Total,
+		Hope:     result.Hope,
+		Fear:     result.Fear,
+		Outcome:  result.Outcome(),
+		Critical: result.Critical,
+	}
+	if result.Advantage > 0 {
+		record.Dice = append(record.Dice, config.RolledDie{Sides: dice.AdvantageSides, Value: result.Bonus, Label: "advantage"})
+	} else if result.Advantage < 0 {
+		record.Dice = append(record.Dice, config.RolledDie{Sides: dice.AdvantageSides, Value: result.Bonus, Label: "disadvantage"})
+	}
+	return record
+}
+
+// dualityText is the plain-text form of an action roll.
+func dualityText(roller string, result *dice.ActionResult, notes []string) string {
+	var note string
+	for _, n := range notes {
+		note += "\n> " + n
 	}
-	for i := 0; i < numRolls; i++ {
-		dicevalue := rollDice(rollValue)
-		diceTotal += dicevalue
+
+	if result.Critical {
+		return fmt.Sprintf("# %s CRIT!!! :dagger: :heart:\n> with double %d\n> %s%s", strings.ToUpper(roller), result.Hope, result.Breakdown(), note)
 	}
 
-	diceTotal += (positiveModifier - negativeModifier)
-	diceTotalString := strconv.FormatFloat(diceTotal, 'f', -1, 64)
+	dualityResult := fmt.Sprintf("%s rolled %d ", roller, result.Total)
+	if result.WithHope() {
+		dualityResult += "with Hope :heart:"
+	} else {
+		dualityResult += "with Fear :dagger:"
+	}
+	if result.Difficulty > 0 {
+		dualityResult += fmt.Sprintf(" (**%s**)", result.Outcome())
+	}
 
-	return fmt.Sprintf("%s %s result is %s\n", roller, diceDesignationString, diceTotalString)
+	return fmt.Sprintf("%s\n> %s%s", dualityResult, result.Breakdown(), note)
 }
 
 func init() {
-	RegisterCommand(NewCommand("Roll", "Replies with Roll!", roll))
+	cmd := NewCommand("Roll", "Replies with Roll!", roll)
+	cmd.Usage = []string{
+		"roll - Makes a duality roll",
+		"roll duality [+modifier] [adv|dis] [dc <difficulty>] - Makes a Daggerheart action roll",
+		"roll <trait> [+exp <experience>] [+modifier] [adv|dis] [dc <difficulty>] - Makes an action roll with your active character's trait",
+		"roll attack [adv|dis] [dc <difficulty>] - Attacks with your active character's weapon, rolling damage on a hit",
+		"roll <dice> [dice...] - Rolls dice expressions, e.g. 2d6+3 or 4d6kh3",
+		"roll <sides> - Rolls a single die",
+	}
+	cmd.Examples = []string{"roll duality +2 adv dc 14", "roll agility adv", "roll instinct +exp Tracking dc 12", "roll attack dc 13", "roll 2d6+1d4+3", "roll 4d6kh3", "roll 3d6!", "roll 6d10>=7", "roll 20"}
+	cmd.Aliases = []string{"r"}
+	cmd.SetOptions(StringOption("dice", "Dice expression such as 2d6+3, duality +2 adv dc 14, or agility +exp Tracking", false))
+	RegisterCommand(cmd)
 }
@@ -0,0 +1,276 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+func testCharacter() *config.Character {
+	return &config.Character{
+		Name:        "Aria",
+		Traits:      map[string]int{"agility": 2, "strength": 1, "finesse": 0, "instinct": 1, "presence": -1, "knowledge": 0},
+		Proficiency: 2,
+		Experiences: []config.Experience{{Name: "Tracking", Bonus: 2}, {Name: "Silver Tongue", Bonus: 3}},
+		Weapon:      config.Weapon{Name: "Shortbow", Trait: "agility", Damage: "d6+1"},
+	}
+}
+
+func TestParseRoll(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		args  string
+		char  *config.Character
+		faces []int
+		want  []string // Text of each result, in order
+	}{
+		{
+			name:  "bare duality",
+			faces: []int{9, 4},
+			want:  []string{"Tam rolled 13 with Hope :heart:\n> _Hope_ 9 + _Fear_ 4 = 13"},
+		},
+		{
+			name:  "duality qualifiers",
+			args:  "duality +2 adv dc 14",
+			faces: []int{8, 5, 3},
+			want:  []string{"Tam rolled 18 with Hope :heart: (**Success with Hope**)\n> _Hope_ 8 + _Fear_ 5 + 2 + 3 _advantage_ = 18 vs Difficulty 14"},
+		},
+		{
+			name:  "split difficulty and disadvantage",
+			args:  "duality dis dc14",
+			faces: []int{4, 7, 2},
+			want:  []string{"Tam rolled 9 with Fear :dagger: (**Failure with Fear**)\n> _Hope_ 4 + _Fear_ 7 - 2 _disadvantage_ = 9 vs Difficulty 14"},
+		},
+		{
+			name:  "critical",
+			args:  "duality",
+			faces: []int{11, 11},
+			want:  []string{"# TAM CRIT!!! :dagger: :heart:\n> with double 11\n> _Hope_ 11 + _Fear_ 11 = 22"},
+		},
+		{
+			name:  "trait and experience",
+			args:  "instinct +exp Tracking dc 12",
+			char:  testCharacter(),
+			faces: []int{6, 3},
+			want:  []string{"Tam rolled 12 with Hope :heart: (**Success with Hope**)\n> _Hope_ 6 + _Fear_ 3 + 1 _Instinct_ + 2 _Tracking_ = 12 vs Difficulty 12"},
+		},
+		{
+			name:  "multi-word experience",
+			args:  "presence exp silver tongue",
+			char:  testCharacter(),
+			faces: []int{2, 9},
+			want:  []string{"Tam rolled 13 with Fear :dagger:\n> _Hope_ 2 + _Fear_ 9 - 1 _Presence_ + 3 _Silver Tongue_ = 13"},
+		},
+		{
+			name:  "attack hits and rolls damage",
+			args:  "attack dc 10",
+			char:  testCharacter(),
+			faces: []int{7, 5, 4, 6},
+			want:  []string{"Tam rolled 14 with Hope :heart: (**Success with Hope**)\n> _Hope_ 7 + _Fear_ 5 + 2 _Agility_ = 14 vs Difficulty 10\n> Shortbow damage 2d6+1: **11** (2d6 [4, 6] + 1)"},
+		},
+		{
+			name:  "attack without a Difficulty",
+			args:  "attack",
+			char:  testCharacter(),
+			faces: []int{7, 5, 4, 6},
+			want:  []string{"Tam rolled 14 with Hope :heart:\n> _Hope_ 7 + _Fear_ 5 + 2 _Agility_ = 14\n> If it hits, Shortbow damage 2d6+1: **11** (2d6 [4, 6] + 1)"},
+		},
+		{
+			name:  "critical attack without a Difficulty",
+			args:  "attack",
+			char:  testCharacter(),
+			faces: []int{6, 6, 4, 6},
+			want:  []string{"# TAM CRIT!!! :dagger: :heart:\n> with double 6\n> _Hope_ 6 + _Fear_ 6 + 2 _Agility_ = 14\n> Shortbow damage 2d6+1: **11** (2d6 [4, 6] + 1)"},
+		},
+		{
+			name:  "attack misses",
+			args:  "attack dc 20",
+			char:  testCharacter(),
+			faces: []int{2, 5},
+			want:  []string{"Tam rolled 9 with Fear :dagger: (**Failure with Fear**)\n> _Hope_ 2 + _Fear_ 5 + 2 _Agility_ = 9 vs Difficulty 20"},
+		},
+		{
+			name:  "expression",
+			args:  "2d6+3",
+			faces: []int{4, 5},
+			want:  []string{"Tam 2d6+3 result is 12\n> 2d6 [4, 5] + 3\n"},
+		},
+		{
+			name:  "expression split on spaces",
+			args:  "2d6 + 1d4",
+			faces: []int{1, 2, 3},
+			want:  []string{"Tam 2d6+1d4 result is 6\n> 2d6 [1, 2] + 1d4 [3]\n"},
+		},
+		{
+			name:  "bare number and natural one",
+			args:  "20",
+			faces: []int{1},
+			want:  []string{"Tam d20 result is 1 :cry:\n> 1d20 [1]\n"},
+		},
+		{
+			name:  "several rolls",
+			args:  "d4 duality d8",
+			faces: []int{3, 10, 2, 8}, // d4, Hope, Fear, d8
+			want: []string{
+				"Tam d4 result is 3\n> 1d4 [3]\n",
+				"Tam rolled 12 with Hope :heart:\n> _Hope_ 10 + _Fear_ 2 = 12",
+				"Tam d8 result is 8\n> 1d8 [8]\n",
+			},
+		},
+		{
+			name: "invalid expression",
+			args: "foo",
+			want: []string{`Invalid roll: unknown modifier "foo" at position 1 of "foo". A roll is a number, duality, or dice expression`},
+		},
+		{
+			name: "trait without character",
+			args: "agility",
+			want: []string{"You need an active character to roll agility, see `!char create`"},
+		},
+		{
+			name: "attack without weapon",
+			args: "attack",
+			char: &config.Character{Name: "Bram"},
+			want: []string{"Bram has no weapon, set one with `!char set weapon_trait <trait>` and `!char set damage <dice>`"},
+		},
+		{
+			name: "bad difficulty",
+			args: "duality dc x",
+			want: []string{`"x" is not a valid Difficulty`},
+		},
+		{
+			name: "unknown experience",
+			args: "agility exp Cooking",
+			char: testCharacter(),
+			want: []string{
+				"`exp` must be followed by one of Aria's Experiences",
+				`Invalid roll: unknown modifier "Cooking" at position 1 of "Cooking". A roll is a number, duality, or dice expression`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := parseRoll(dice.NewFixedRNG(tt.faces...), strings.Fields(tt.args), "Tam", tt.char, nil)
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d: %q", len(results), len(tt.want), rollText(results))
+			}
+			for i, r := range results {
+				if r.text != tt.want[i] {
+					t.Errorf("result %d:\n got %q\nwant %q", i, r.text, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRollRecords(t *testing.T) {
+	t.Parallel()
+
+	results := parseRoll(dice.NewFixedRNG(8, 5, 3, 1, 2, 3, 4), strings.Fields("duality +2 adv dc 14 4d6kh3"), "Tam", nil, nil)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	duality := results[0].record
+	if duality.Kind != config.RollDuality || duality.Expression != "duality +2 adv dc 14" || duality.Total != 18 ||
+		duality.Hope != 8 || duality.Fear != 5 || duality.Outcome != "Success with Hope" || len(duality.Dice) != 3 {
+		t.Errorf("duality record = %+v", duality)
+	}
+
+	expr := results[1].record
+	if expr.Kind != config.RollDice || expr.Expression != "4d6kh3" || expr.Total != 9 || len(expr.Dice) != 4 || !expr.Dice[0].Dropped {
+		t.Errorf("expression record = %+v", expr)
+	}
+}
+
+func TestFitFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		fields, tail int
+		want         []string // Names of the fields kept, "n" for the nth added
+	}{
+		{3, 1, []string{"0", "1", "2"}},
+		{maxEmbedFields, 1, nil},
+		{maxEmbedFields + 1, 1, append(fieldNames(0, maxEmbedFields-2), "…", fmt.Sprint(maxEmbedFields))},
+		{40, 2, append(fieldNames(0, maxEmbedFields-3), "…", "38", "39")},
+	}
+
+	for _, tt := range tests {
+		embed := &discordgo.MessageEmbed{}
+		for i := range tt.fields {
+			addField(embed, fmt.Sprint(i), "value")
+		}
+		fitFields(embed, tt.tail)
+
+		want := tt.want
+		if want == nil {
+			want = fieldNames(0, tt.fields)
+		}
+		got := make([]string, 0, len(embed.Fields))
+		for _, f := range embed.Fields {
+			got = append(got, f.Name)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%d fields keeping %d:\n got %v\nwant %v", tt.fields, tt.tail, got, want)
+		}
+		if len(embed.Fields) > maxEmbedFields {
+			t.Errorf("%d fields kept, more than Discord allows", len(embed.Fields))
+		}
+		if tt.fields > maxEmbedFields {
+			if more := embed.Fields[maxEmbedFields-1-tt.tail]; more.Value != fmt.Sprintf("%d more", tt.fields-maxEmbedFields+1) {
+				t.Errorf("%d fields: the overflow field says %q", tt.fields, more.Value)
+			}
+		}
+	}
+}
+
+// fieldNames returns the names addField was given for fields from to to-1.
+func fieldNames(from, to int) []string {
+	names := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		names = append(names, fmt.Sprint(i))
+	}
+	return names
+}
+
+func TestEmbedBatches(t *testing.T) {
+	t.Parallel()
+
+	embed := func(size int) *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{Description: strings.Repeat("x", size)}
+	}
+	tests := []struct {
+		name   string
+		sizes  []int
+		counts []int // Embeds in each batch
+	}{
+		{"by count", slices.Repeat([]int{10}, 25), []int{10, 10, 5}},
+		{"by size", []int{4000, 1500, 1000, 3000}, []int{2, 2}},
+		{"exactly full", []int{3000, 3000, 1}, []int{2, 1}},
+	}
+
+	for _, tt := range tests {
+		embeds := make([]*discordgo.MessageEmbed, 0, len(tt.sizes))
+		for _, size := range tt.sizes {
+			embeds = append(embeds, embed(size))
+		}
+		var counts []int
+		for _, batch := range embedBatches(embeds) {
+			counts = append(counts, len(batch))
+		}
+		if !slices.Equal(counts, tt.counts) {
+			t.Errorf("%s: batches of %v, want %v", tt.name, counts, tt.counts)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+/*
+ * Roll results are sent as embeds, colored by how the roll went, with the
+ * plain text kept for channels where the bot may not embed links
+ */
+
+const (
+	ColorHope     = 0xF1C40F // Gold
+	ColorFear     = 0x8E44AD // Purple
+	ColorCritical = 0x1ABC9C // Teal
+	ColorDice     = 0x3498DB // Blue, for plain dice expressions
+
+	// Discord's embed limits
+	maxEmbedTitle       = 256
+	maxEmbedDescription = 4096
+	maxEmbedFields      = 25
+	maxFieldValue       = 1024
+	maxEmbedSize        = maxEmbedsSize - maxEmbedTitle // Room left in a roll embed for the author added by replyRolls
+
+	maxBreakdown = 1000 // Longest dice breakdown shown for one roll
+)
+
+// rollResult is the reply to a single roll, and the record kept of it. Errors have only text.
+type rollResult struct {
+	text   string
+	embed  *discordgo.MessageEmbed
+	record *config.RollRecord
+}
+
+// replyRolls sends roll results as embeds, attributed to the roller's
+// character, or as text where embeds are disabled. Private rolls go to the author's DMs.
+func replyRolls(ctx *Context, results []rollResult, private bool) error {
+	recordRolls(ctx, results, private)
+
+	if !private && !canEmbed(ctx) {
+		return ctx.Reply(rollText(results))
+	}
+
+	var (
+		errs   []string
+		embeds []*discordgo.MessageEmbed
+	)
+	author := rollAuthor(ctx)
+	for _, r := range results {
+		if r.embed == nil {
+			errs = append(errs, r.text)
+			continue
+		}
+		r.embed.Author = author
+		embeds = append(embeds, r.embed)
+	}
+
+	if private {
+		return ctx.ReplyPrivateEmbeds(strings.Join(errs, "\n"), embeds...)
+	}
+	return ctx.ReplyEmbeds(strings.Join(errs, "\n"), embeds...)
+}
+
+// rollText joins the text of each result, summarizing those that would take
+// it past Discord's message limit.
+func rollText(results []rollResult) string {
+	const reserve = 32 // Room for the summary of the rolls left out
+
+	var b strings.Builder
+	for i, r := range results {
+		text := r.text
+		if i == 0 {
+			text = truncate(text, maxMessageLength-reserve)
+		} else if b.Len()+len(text)+1 > maxMessageLength-reserve {
+			fmt.Fprintf(&b, "\n…and %d more rolls", len(results)-i)
+			break
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// breakdown returns the roll's breakdown, or when that is longer than limit,
+// as many of its dice terms as fit followed by a count of the dice left out.
+func breakdown(result *dice.Result, limit int) string {
+	if len(result.Breakdown) <= limit {
+		return result.Breakdown
+	}
+
+	const reserve = 32 // Room for the count of dice left out
+	var (
+		terms  []string
+		size   int
+		hidden int
+	)
+	for _, g := range result.Groups {
+		term := fmt.Sprintf("%dd%d %s", g.Count, g.Sides, g)
+		if hidden > 0 || size+len(term)+2 > limit-reserve {
+			hidden += len(g.Dice)
+			continue
+		}
+		terms = append(terms, term)
+		size += len(term) + 2
+	}
+	return fmt.Sprintf("%s …and %d more dice", strings.Join(terms, ", "), hidden)
+}
+
+// canEmbed reports whether the bot may send embeds where the command was
+// invoked. Interaction replies and direct messages always allow them.
+func canEmbed(ctx *Context) bool {
+	if ctx.Interaction != nil || ctx.Message == nil || ctx.Message.GuildID == "" {
+		return true
+	}
+	perms, err := ctx.Session.Permissions(ctx.Session.Me().ID, ctx.ChannelID)
+	if err != nil {
+		return false
+	}
+	return perms&discordgo.PermissionEmbedLinks != 0
+}
+
+// rollAuthor names the author's active character, or the author when they have none.
+func rollAuthor(ctx *Context) *discordgo.MessageEmbedAuthor {
+	if ctx.Author == nil {
+		return nil
+	}
+	name := ctx.Author.DisplayName()
+	if char := activeCharacter(ctx); char != nil {
+		name = char.Name
+	}
+	return &discordgo.MessageEmbedAuthor{Name: truncate(name, maxEmbedTitle), IconURL: ctx.Author.AvatarURL("64")}
+}
+
+// dualityEmbed shows each die and modifier of an action roll, with any tracker notes.
+func dualityEmbed(result *dice.ActionResult, notes []string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Rolled %d %s", result.Total, result.Outcome()),
+		Description: truncate(strings.Join(notes, "\n"), maxEmbedDescription),
+		Color:       ColorFear,
+	}
+	if result.Difficulty > 0 {
+		embed.Title = fmt.Sprintf("Rolled %d: %s", result.Total, result.Outcome())
+	}
+	switch {
+	case result.Critical:
+		embed.Title = fmt.Sprintf("Critical Success! Rolled %d", result.Total)
+		embed.Color = ColorCritical
+	case result.WithHope():
+		embed.Color = ColorHope
+	}
+
+	addField(embed, "Hope", fmt.Sprintf("%d", result.Hope))
+	addField(embed, "Fear", fmt.Sprintf("%d", result.Fear))
+	if result.Advantage > 0 {
+		addField(embed, "Advantage", fmt.Sprintf("+%d", result.Bonus))
+	} else if result.Advantage < 0 {
+		addField(embed, "Disadvantage", fmt.Sprintf("-%d", result.Bonus))
+	}
+
+	unnamed := result.Modifier
+	for _, b := range result.Bonuses {
+		addField(embed, b.Label, fmt.Sprintf("%+d", b.Value))
+		unnamed -= b.Value
+	}
+	if unnamed != 0 {
+		addField(embed, "Modifier", fmt.Sprintf("%+d", unnamed))
+	}
+
+	tail := 1
+	addField(embed, "Total", fmt.Sprintf("**%d**", result.Total))
+	if result.Difficulty > 0 {
+		addField(embed, "Difficulty", fmt.Sprintf("%d", result.Difficulty))
+		tail++
+	}
+	fitFields(embed, tail)
+	return embed
+}
+
+// expressionEmbed shows every die of each dice term in an expression.
+func expressionEmbed(roll string, result *dice.Result) *discordgo.MessageEmbed {
+	title := fmt.Sprintf("%s: %d", roll, result.Total)
+	if natural, ok := result.Natural(); ok && natural == 1 {
+		title += " :cry:"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       truncate(title, maxEmbedTitle),
+		Description: breakdown(result, maxBreakdown),
+		Color:       ColorDice,
+	}
+	for _, g := range result.Groups {
+		name := fmt.Sprintf("%dd%d", g.Count, g.Sides)
+		if g.Successes {
+			name += fmt.Sprintf(" (%d successes)", g.Total)
+		}
+		addField(embed, name, g.String())
+	}
+	addField(embed, "Total", fmt.Sprintf("**%d**", result.Total))
+	fitFields(embed, 1)
+	return embed
+}
+
+// addField adds an inline field. Call fitFields once they are all added.
+func addField(embed *discordgo.MessageEmbed, name, value string) {
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   truncate(name, maxEmbedTitle),
+		Value:  truncate(value, maxFieldValue),
+		Inline: true,
+	})
+}
+
+// fitFields keeps an embed within Discord's field and size limits, replacing
+// the fields that don't fit with a count of them. The last tail fields, the
+// totals, are always kept.
+func fitFields(embed *discordgo.MessageEmbed, tail int) {
+	if len(embed.Fields) <= maxEmbedFields && embedSize(embed) <= maxEmbedSize {
+		return
+	}
+
+	fields := embed.Fields
+	totals := fields[len(fields)-tail:]
+	for keep := min(maxEmbedFields-1-tail, len(fields)-tail-1); keep >= 0; keep-- {
+		more := &discordgo.MessageEmbedField{Name: "…", Value: fmt.Sprintf("%d more", len(fields)-tail-keep), Inline: true}
+		embed.Fields = append(append(slices.Clip(fields[:keep]), more), totals...)
+		if embedSize(embed) <= maxEmbedSize {
+			return
+		}
+	}
+}
+
+// embedSize counts the characters of an embed that Discord limits per message.
+func embedSize(embed *discordgo.MessageEmbed) int {
+	size := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	if embed.Author != nil {
+		size += utf8.RuneCountInString(embed.Author.Name)
+	}
+	if embed.Footer != nil {
+		size += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	for _, f := range embed.Fields {
+		size += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return size
+}
+
+// truncate shortens s to at most limit characters, marking the cut with an ellipsis.
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit-1]) + "…"
+}
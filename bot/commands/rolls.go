@@ -0,0 +1,347 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+const (
+	defaultRollLines = 10 // Rolls listed when no count is given
+	maxRollLines     = 25 // Most rolls listed in chat, exports have no limit
+)
+
+// rollQuery picks rolls out of the guild's history.
+type rollQuery struct {
+	keep  func(config.RollRecord) bool
+	limit int    // Latest rolls to return, 0 for all
+	about string // Describes the query, e.g. "by Tam"
+}
+
+func Rolls(c *Command, ctx *Context) error {
+	args := ctx.Args
+	if len(args) < 1 {
+		args = []string{"last"}
+	}
+
+	if strings.EqualFold(args[0], "export") {
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !rolls export <json|csv> [last <n>|@player|session <id>]")
+		}
+		query, err := parseRollQuery(ctx, args[2:], 0)
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("Sorry, %v", err))
+		}
+		query.keep = hidePrivate(query.keep, ctx.Author.ID) // Exports go to the author's DMs
+		return exportRolls(ctx, strings.ToLower(args[1]), query)
+	}
+
+	query, err := parseRollQuery(ctx, args, defaultRollLines)
+	if err != nil {
+		return ctx.Reply(fmt.Sprintf("Sorry, %v\n%s", err, CommandHelp(c, ctx.Guild.Prefix())))
+	}
+	if query.limit <= 0 || query.limit > maxRollLines {
+		query.limit = maxRollLines
+	}
+	query.keep = hidePrivate(query.keep, "")
+
+	rolls := ctx.Guild.Rolls().Recent(query.limit, query.keep)
+	if len(rolls) == 0 {
+		return ctx.Reply(fmt.Sprintf("No rolls %s yet", query.about))
+	}
+
+	lines := make([]string, 0, len(rolls))
+	for _, r := range rolls {
+		lines = append(lines, formatRoll(r))
+	}
+	return replyLines(ctx, fmt.Sprintf("Rolls %s:", query.about), lines)
+}
+
+// replyLines sends a heading and lines, splitting them across as many messages as Discord needs.
+func replyLines(ctx *Context, heading string, lines []string) error {
+	response := heading + "\n"
+	for _, line := range lines {
+		line += "\n"
+		if len(response)+len(line) > 2000 {
+			if err := ctx.Reply(response); err != nil {
+				return err
+			}
+			response = ""
+		}
+		response += line
+	}
+	return ctx.Reply(response)
+}
+
+// parseRollQuery reads `last [n]`, `@player [n]` or `session <id>`. Nothing at all means the latest limit rolls.
+func parseRollQuery(ctx *Context, args []string, limit int) (rollQuery, error) {
+	query := rollQuery{limit: limit, about: "in this server"}
+	if len(args) > 0 && strings.EqualFold(args[0], "player") {
+		args = args[1:] // The slash command names the player option
+	}
+	if len(args) < 1 {
+		return query, nil
+	}
+
+	count := func(i int) error {
+		if len(args) <= i {
+			return nil
+		}
+		n, err := strconv.Atoi(args[i])
+		if err != nil || n < 1 {
+			return fmt.Errorf("%q is not a number of rolls", args[i])
+		}
+		query.limit = n
+		return nil
+	}
+
+	switch {
+	case strings.EqualFold(args[0], "last"), strings.EqualFold(args[0], "latest"):
+		return query, count(1)
+
+	case isMention(args[0]):
+		if slices.ContainsFunc(args[1:], isMention) {
+			return query, errOnePlayer
+		}
+		user, err := mentionedUser(ctx, args[0])
+		if err != nil {
+			return query, err
+		}
+		query.keep = func(r config.RollRecord) bool { return r.UserID == user.ID }
+		query.about = "by " + memberName(ctx.Session, ctx.Guild.ID, user.ID)
+		return query, count(1)
+
+	case strings.EqualFold(args[0], "session"):
+		if len(args) < 2 {
+			return query, fmt.Errorf("which session? e.g. `session 3`")
+		}
+		sess, err := ctx.Guild.Sessions().Get(args[1])
+		if err != nil {
+			return query, err
+		}
+		query.keep = sessionRolls(sess)
+		query.limit = 0
+		query.about = fmt.Sprintf("during session #%s, **%s**", sess.ID, sess.Title)
+		return query, nil
+	}
+
+	return query, fmt.Errorf("I don't understand %q", strings.Join(args, " "))
+}
+
+// errOnePlayer is returned when a roll query names more than one player.
+var errOnePlayer = errors.New("I can only look up one player at a time")
+
+// isMention reports whether arg is meant to name a player, as a mention or a plain `@name`.
+func isMention(arg string) bool {
+	return strings.HasPrefix(arg, "<@") || strings.HasPrefix(arg, "@")
+}
+
+// mentionedUser returns the user a mention names. Typed `@name` text and
+// mentions Discord didn't resolve name nobody, so the error says how to fix it.
+func mentionedUser(ctx *Context, arg string) (*discordgo.User, error) {
+	id := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(arg, "<@"), "!"), ">")
+	for _, user := range ctx.Mentions {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, fmt.Errorf("I don't know who %s is, pick the player from Discord's @ suggestions so they are mentioned", arg)
+}
+
+// hidePrivate wraps keep to drop private rolls, except those made by viewerID.
+// An empty viewerID drops them all.
+func hidePrivate(keep func(config.RollRecord) bool, viewerID string) func(config.RollRecord) bool {
+	return func(r config.RollRecord) bool {
+		if r.Private && (viewerID == "" || r.UserID != viewerID) {
+			return false
+		}
+		return keep == nil || keep(r)
+	}
+}
+
+// sessionRolls matches the rolls recorded as made during a session.
+func sessionRolls(sess config.Session) func(config.RollRecord) bool {
+	return func(r config.RollRecord) bool {
+		return r.SessionID == sess.ID
+	}
+}
+
+// exportRolls sends the matching rolls, oldest first, as a JSON or CSV file.
+func exportRolls(ctx *Context, format string, query rollQuery) error {
+	rolls := ctx.Guild.Rolls().Recent(query.limit, query.keep)
+	if len(rolls) == 0 {
+		return ctx.Reply(fmt.Sprintf("No rolls %s yet", query.about))
+	}
+
+	var (
+		data        []byte
+		contentType string
+		err         error
+	)
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(rolls, "", "  ")
+		contentType = "application/json"
+	case "csv":
+		data, err = rollsCSV(rolls)
+		contentType = "text/csv"
+	default:
+		return ctx.Reply(fmt.Sprintf("Sorry, I can export rolls as `json` or `csv`, not %q", format))
+	}
+	if err != nil {
+		log.Printf("[%s] failed to export rolls: %v", ctx.Guild.Name(), err)
+		return ctx.Reply("Sorry, I couldn't export the rolls")
+	}
+
+	message := fmt.Sprintf("Here are the %d rolls %s", len(rolls), query.about)
+	if len(rolls) == 1 {
+		message = "Here is the roll " + query.about
+	}
+	if err := ctx.SendPrivateFile(message, &discordgo.File{
+		Name:        "rolls." + format,
+		ContentType: contentType,
+		Reader:      bytes.NewReader(data),
+	}); err != nil {
+		return ctx.Reply(fmt.Sprintf("I couldn't send you a direct message, %s", ctx.Author))
+	}
+	return ctx.Reply(fmt.Sprintf("I've sent you the rolls, %s", ctx.Author))
+}
+
+func rollsCSV(rolls []config.RollRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"id", "time", "user_id", "user_name", "character", "kind", "expression", "dice", "total", "hope", "fear", "outcome", "critical", "private", "channel_id", "session_id"})
+
+	for _, r := range rolls {
+		dice := make([]string, 0, len(r.Dice))
+		for _, d := range r.Dice {
+			die := fmt.Sprintf("d%d:%d", d.Sides, d.Value)
+			if d.Label != "" {
+				die += " " + d.Label
+			}
+			if d.Dropped {
+				die += " dropped"
+			}
+			dice = append(dice, die)
+		}
+		_ = w.Write([]string{
+			strconv.Itoa(r.ID),
+			r.Time.UTC().Format(time.RFC3339),
+			r.UserID,
+			r.UserName,
+			r.Character,
+			r.Kind,
+			r.Expression,
+			strings.Join(dice, ";"),
+			strconv.Itoa(r.Total),
+			strconv.Itoa(r.Hope),
+			strconv.Itoa(r.Fear),
+			r.Outcome,
+			strconv.FormatBool(r.Critical),
+			strconv.FormatBool(r.Private),
+			r.ChannelID,
+			r.SessionID,
+		})
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// formatRoll is one line of the roll history.
+func formatRoll(r config.RollRecord) string {
+	who := "**" + r.UserName + "**"
+	if r.Character != "" {
+		who += " as " + r.Character
+	}
+	line := fmt.Sprintf("`#%d` <t:%d:f> %s: `%s` **%d**", r.ID, r.Time.Unix(), who, r.Expression, r.Total)
+
+	if r.Kind == config.RollDuality {
+		line += fmt.Sprintf(" %s (Hope %d, Fear %d)", r.Outcome, r.Hope, r.Fear)
+	} else {
+		faces := make([]string, 0, len(r.Dice))
+		for _, d := range r.Dice {
+			face := strconv.Itoa(d.Value)
+			if d.Dropped {
+				face = "~~" + face + "~~"
+			}
+			faces = append(faces, face)
+		}
+		line += " [" + truncate(strings.Join(faces, ", "), 200) + "]"
+	}
+	return line
+}
+
+// recordRolls adds the rolls to the guild's history.
+func recordRolls(ctx *Context, results []rollResult, private bool) {
+	if ctx.Guild == nil || ctx.Author == nil {
+		return
+	}
+
+	var character string
+	if char := activeCharacter(ctx); char != nil {
+		character = char.Name
+	}
+
+	now := time.Now()
+	var sessionID string
+	if sess, ok := ctx.Guild.Sessions().Running(ctx.ChannelID, now); ok && (sess.GMID == ctx.Author.ID || slices.Contains(sess.Players, ctx.Author.ID)) {
+		sessionID = sess.ID
+	}
+
+	var records []config.RollRecord
+	for _, r := range results {
+		if r.record == nil {
+			continue
+		}
+		record := *r.record
+		record.UserID = ctx.Author.ID
+		record.UserName = ctx.Author.DisplayName()
+		record.Character = character
+		record.Private = private
+		record.ChannelID = ctx.ChannelID
+		record.SessionID = sessionID
+		record.Time = now
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	if _, err := ctx.Guild.Rolls().Record(records...); err != nil {
+		log.Printf("[%s] failed to record rolls for %s: %v", ctx.Guild.Name(), ctx.Author, err)
+	}
+}
+
+func init() {
+	cmd := NewCommand("Rolls", "Shows the rolls made in this server", Rolls)
+	cmd.Usage = []string{
+		"rolls [last <n>] - Lists the latest rolls",
+		"rolls @player [n] - Lists a player's latest rolls",
+		"rolls session <id> - Lists the rolls made during a session",
+		"rolls export <json|csv> [last <n>|@player|session <id>] - Sends you the rolls as a file",
+	}
+	cmd.Examples = []string{"rolls last 5", "rolls @Tam", "rolls session 3", "rolls export csv session 3"}
+	cmd.Aliases = []string{"history"}
+	cmd.SetPermission(config.PermGM)
+	cmd.SetOptions(
+		SubcommandOption("last", "Lists the latest rolls", IntegerOption("count", "Number of rolls", false)),
+		SubcommandOption("player", "Lists a player's latest rolls", UserOption("player", "Player", true), IntegerOption("count", "Number of rolls", false)),
+		SubcommandOption("session", "Lists the rolls made during a session", StringOption("id", "Session number", true)),
+		SubcommandOption("export", "Sends you the rolls as a file",
+			ChoiceOption("format", "File format", "json", "csv"),
+			StringOption("query", "last <n>, @player or session <id>", false),
+		),
+	)
+	RegisterCommand(cmd)
+}
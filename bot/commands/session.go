@@ -0,0 +1,368 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/dates"
+	"github.com/nerdwerx/daggerbot/bot/ical"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+const maxDateWords = 6 // Most words a session's date and time may take up
+
+func Session(c *Command, ctx *Context) error {
+	var (
+		args     = ctx.Args
+		guild    = ctx.Guild
+		sessions = guild.Sessions()
+	)
+
+	if len(args) < 1 {
+		args = []string{"list"}
+	}
+
+	subcommand := strings.ToLower(args[0])
+	switch subcommand {
+	case "create", "new", "cancel", "close", "end":
+		if !guild.IsGM(ctx.Member) {
+			log.Printf("[%s] user @%s (%s) is not a GM, denying access to session %s", guild.Name(), ctx.Author.DisplayName(), ctx.Author, subcommand)
+			return ctx.Reply(fmt.Sprintf("you must be a GM to %s sessions, %s", subcommand, ctx.Author))
+		}
+	}
+
+	switch subcommand {
+
+	case "create", "new":
+		title, start, maxPlayers, err := sessionOptions(ctx, args[1:], time.Now(), config.Users.Get(ctx.Author.ID).Location())
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("%v\nUsage: `%ssession create <title> <when> <max players>`", err, guild.Prefix()))
+		}
+		sess, err := sessions.Create(title, ctx.Author.ID, start, maxPlayers)
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("Could not create session: %v", err))
+		}
+
+		msg, err := ctx.Session.ChannelMessageSendComplex(ctx.ChannelID, sessionMessage(sess))
+		if err != nil {
+			log.Printf("[%s] failed to post announcement for session #%s: %v", guild.Name(), sess.ID, err)
+		} else if _, err := sessions.SetAnnouncement(sess.ID, msg.ChannelID, msg.ID); err != nil {
+			log.Printf("Failed to save sessions for guild %q: %v", guild.Name(), err)
+		}
+		scheduleReminders(ctx.Scheduler, guild, sess)
+		return ctx.Reply(fmt.Sprintf("Scheduled session #%s, **%s**", sess.ID, sess.Title))
+
+	case "list":
+		all := len(args) > 1 && strings.EqualFold(args[1], "all")
+		list := sessions.List(all)
+		if len(list) == 0 {
+			return ctx.Reply(fmt.Sprintf("No sessions are scheduled. GMs can post one with `%ssession create`", guild.Prefix()))
+		}
+		response := "Sessions:\n"
+		for _, s := range list {
+			response += fmt.Sprintf("`#%s` **%s** - %s, %d/%d players", s.ID, s.Title, formatSessionTime(s.Start), len(s.Players), s.MaxPlayers)
+			if len(s.Waitlist) > 0 {
+				response += fmt.Sprintf(", %d waiting", len(s.Waitlist))
+			}
+			if !s.Open() {
+				response += fmt.Sprintf(" (%s)", s.Status)
+			}
+			response += "\n"
+		}
+		return ctx.Reply(response)
+
+	case "show":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !session show <id>")
+		}
+		sess, err := sessions.Get(args[1])
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("Sorry, %v", err))
+		}
+		return ctx.Reply(sessionAnnouncement(sess))
+
+	case "join", "signup":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !session join <id>")
+		}
+		return ctx.Reply(joinSession(ctx, args[1]))
+
+	case "leave", "drop":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !session leave <id>")
+		}
+		return ctx.Reply(leaveSession(ctx, args[1]))
+
+	case "ics", "calendar":
+		cal := ical.UserCalendar(guild, ctx.Author.ID)
+		if len(cal.Sessions) == 0 {
+			return ctx.Reply(fmt.Sprintf("You aren't signed up for any sessions, %s", ctx.Author))
+		}
+		if err := ctx.SendPrivateFile("Here are your sessions. Import the file again after changes to update your calendar.", &discordgo.File{
+			Name:        "sessions.ics",
+			ContentType: ical.ContentType,
+			Reader:      bytes.NewReader(cal.Render(time.Now())),
+		}); err != nil {
+			return ctx.Reply(fmt.Sprintf("I couldn't send you a direct message, %s", ctx.Author))
+		}
+		return ctx.Reply(fmt.Sprintf("I've sent you a calendar file, %s", ctx.Author))
+
+	case "cancel", "close":
+		if len(args) < 2 {
+			return ctx.Reply(fmt.Sprintf("Usage: !session %s <id>", subcommand))
+		}
+		status := config.SessionClosed
+		if subcommand == "cancel" {
+			status = config.SessionCancelled
+		}
+		sess, err := sessions.SetStatus(args[1], status)
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("Sorry, %v", err))
+		}
+		if sess.Status == config.SessionCancelled {
+			cancelReminders(ctx.Scheduler, guild, sess)
+		}
+		refreshAnnouncement(ctx, sess)
+		return ctx.Reply(fmt.Sprintf("Session #%s, **%s**, is now %s", sess.ID, sess.Title, sess.Status))
+
+	case "end":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !session end <id>")
+		}
+		sess, err := sessions.End(args[1], time.Now())
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("Sorry, %v", err))
+		}
+		return ctx.Reply(fmt.Sprintf("Session #%s, **%s**, has ended. Its rolls are listed with `%srolls session %s`", sess.ID, sess.Title, guild.Prefix(), sess.ID))
+
+	default:
+		return ctx.Reply(CommandHelp(c, guild.Prefix()))
+	}
+}
+
+// sessionButton handles the join and leave buttons on session announcements.
+func sessionButton(ctx *Context) error {
+	if len(ctx.Args) < 2 {
+		return fmt.Errorf("malformed session button %v", ctx.Args)
+	}
+	if !ctx.Guild.IsPlayer(ctx.Member) {
+		return ctx.ReplyEphemeral("you must be a player to sign up for sessions")
+	}
+	switch ctx.Args[0] {
+	case "join":
+		return ctx.ReplyEphemeral(joinSession(ctx, ctx.Args[1]))
+	case "leave":
+		return ctx.ReplyEphemeral(leaveSession(ctx, ctx.Args[1]))
+	default:
+		return fmt.Errorf("unknown session button %q", ctx.Args[0])
+	}
+}
+
+// joinSession signs the author up and returns a message describing the result.
+func joinSession(ctx *Context, id string) string {
+	sess, result, err := ctx.Guild.Sessions().Join(id, ctx.Author.ID)
+	if err != nil {
+		return fmt.Sprintf("Sorry, %v", err)
+	}
+	refreshAnnouncement(ctx, sess)
+
+	switch result {
+	case config.SignUpWaitlist:
+		return fmt.Sprintf("Session #%s, **%s**, is full. You're number %d on the waitlist", sess.ID, sess.Title, len(sess.Waitlist))
+	case config.SignUpUnchanged:
+		return fmt.Sprintf("You're already signed up for session #%s, **%s**", sess.ID, sess.Title)
+	default:
+		return fmt.Sprintf("You're on the roster for session #%s, **%s**, %s", sess.ID, sess.Title, formatSessionTime(sess.Start))
+	}
+}
+
+// leaveSession drops the author from a session, promoting the next player
+// on the waitlist, and returns a message describing the result.
+func leaveSession(ctx *Context, id string) string {
+	sess, promoted, err := ctx.Guild.Sessions().Leave(id, ctx.Author.ID)
+	if err != nil {
+		return fmt.Sprintf("Sorry, %v", err)
+	}
+	refreshAnnouncement(ctx, sess)
+
+	if promoted != "" {
+		channelID := sess.ChannelID
+		if channelID == "" {
+			channelID = ctx.ChannelID
+		}
+		if _, err := ctx.Session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("<@%s> a place opened up, you're now on the roster for session #%s, **%s**!", promoted, sess.ID, sess.Title),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{promoted}},
+		}); err != nil {
+			log.Printf("[%s] failed to announce waitlist promotion for session #%s: %v", ctx.Guild.Name(), sess.ID, err)
+		}
+	}
+	return fmt.Sprintf("You've left session #%s, **%s**", sess.ID, sess.Title)
+}
+
+// refreshAnnouncement updates a session's announcement message to match its roster and status.
+func refreshAnnouncement(ctx *Context, sess config.Session) {
+	if sess.ChannelID == "" || sess.MessageID == "" {
+		return
+	}
+	msg := sessionMessage(sess)
+	if _, err := ctx.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:              sess.MessageID,
+		Channel:         sess.ChannelID,
+		Content:         &msg.Content,
+		Components:      &msg.Components,
+		AllowedMentions: msg.AllowedMentions,
+	}); err != nil {
+		log.Printf("[%s] failed to update announcement for session #%s: %v", ctx.Guild.Name(), sess.ID, err)
+	}
+}
+
+// sessionMessage builds a session's announcement, with sign-up buttons while it is running.
+func sessionMessage(sess config.Session) *discordgo.MessageSend {
+	msg := &discordgo.MessageSend{
+		Content:         sessionAnnouncement(sess),
+		Components:      []discordgo.MessageComponent{},
+		AllowedMentions: &discordgo.MessageAllowedMentions{}, // Listing players shouldn't ping them
+	}
+	if sess.Status == config.SessionCancelled {
+		return msg
+	}
+	msg.Components = []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Join",
+				Style:    discordgo.SuccessButton,
+				CustomID: ComponentID("session", "join", sess.ID),
+				Disabled: !sess.Open(),
+			},
+			discordgo.Button{
+				Label:    "Leave",
+				Style:    discordgo.SecondaryButton,
+				CustomID: ComponentID("session", "leave", sess.ID),
+			},
+		}},
+	}
+	return msg
+}
+
+func sessionAnnouncement(sess config.Session) string {
+	announcement := fmt.Sprintf("**Session #%s: %s**\n", sess.ID, sess.Title)
+	announcement += fmt.Sprintf("GM: <@%s>\n", sess.GMID)
+	announcement += fmt.Sprintf("When: %s (<t:%d:R>)\n", formatSessionTime(sess.Start), sess.Start.Unix())
+	announcement += fmt.Sprintf("Players (%d/%d): %s\n", len(sess.Players), sess.MaxPlayers, mentions(sess.Players))
+	if len(sess.Waitlist) > 0 {
+		announcement += fmt.Sprintf("Waitlist: %s\n", mentions(sess.Waitlist))
+	}
+	switch sess.Status {
+	case config.SessionClosed:
+		announcement += "_Sign-ups are closed_\n"
+	case config.SessionCancelled:
+		announcement += "_This session has been cancelled_\n"
+	}
+	return announcement
+}
+
+func mentions(userIDs []string) string {
+	if len(userIDs) == 0 {
+		return "-"
+	}
+	tags := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		tags = append(tags, fmt.Sprintf("<@%s>", id))
+	}
+	return strings.Join(tags, ", ")
+}
+
+// formatSessionTime renders t as a Discord timestamp, shown in each reader's own time zone.
+func formatSessionTime(t time.Time) string {
+	return fmt.Sprintf("<t:%d:F>", t.Unix())
+}
+
+// sessionOptions reads a new session's title, start and player limit. Slash
+// commands give each in its own option; prefix commands run them together,
+// so their args are split up by parseSessionArgs.
+func sessionOptions(ctx *Context, args []string, now time.Time, loc *time.Location) (string, time.Time, int, error) {
+	title, when, players := ctx.Option("title"), ctx.Option("when"), ctx.Option("players")
+	if title == nil || when == nil || players == nil {
+		return parseSessionArgs(args, now, loc)
+	}
+
+	start, err := dates.Parse(when.StringValue(), now, loc)
+	if err != nil {
+		return "", time.Time{}, 0, fmt.Errorf("couldn't read %q as a date and time (%v), try something like `friday 7pm` or `2026-11-02 19:00 America/Chicago`", when.StringValue(), err)
+	}
+	if !start.After(now) {
+		return "", time.Time{}, 0, fmt.Errorf("%s has already passed", formatSessionTime(start))
+	}
+	return strings.TrimSpace(title.StringValue()), start, int(players.IntValue()), nil
+}
+
+// parseSessionArgs splits `<title...> <when...> <max players>` into its parts,
+// reading the time in loc. The longest run of trailing words that reads as a
+// date and time is taken as the start.
+func parseSessionArgs(args []string, now time.Time, loc *time.Location) (string, time.Time, int, error) {
+	if len(args) < 3 {
+		return "", time.Time{}, 0, errors.New("a session needs a title, a date and time, and a player limit")
+	}
+
+	maxPlayers, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		return "", time.Time{}, 0, fmt.Errorf("%q is not a valid player limit", args[len(args)-1])
+	}
+	args = args[:len(args)-1]
+
+	var lastErr error
+	for n := min(maxDateWords, len(args)-1); n >= 1; n-- {
+		start, err := dates.Parse(strings.Join(args[len(args)-n:], " "), now, loc)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !start.After(now) {
+			return "", time.Time{}, 0, fmt.Errorf("%s has already passed", formatSessionTime(start))
+		}
+		return strings.Join(args[:len(args)-n], " "), start, maxPlayers, nil
+	}
+	return "", time.Time{}, 0, fmt.Errorf("couldn't find a date and time (%v), try something like `friday 7pm` or `2026-11-02 19:00 America/Chicago`", lastErr)
+}
+
+func init() {
+	cmd := NewCommand("Session", "Schedules West Marches sessions and takes sign-ups", Session)
+	cmd.Usage = []string{
+		"session list [all] - Lists upcoming sessions",
+		"session show <id> - Shows a session's roster",
+		"session join <id> - Signs up for a session, or joins its waitlist",
+		"session leave <id> - Drops out of a session",
+		"session ics - Sends you a calendar file of the sessions you're signed up for",
+		"session create <title> <when> <max players> - Posts a new session, in your `tz` time zone (GM only)",
+		"session close <id> - Closes sign-ups (GM only)",
+		"session cancel <id> - Cancels a session (GM only)",
+		"session end <id> - Ends a running session, so later rolls in its channel aren't counted toward it (GM only)",
+	}
+	cmd.Examples = []string{"session create Into the Mistwood friday 7pm 5", "session create The Sunken Keep 2026-11-02 19:00 America/Chicago 4", "session join 3"}
+	cmd.Aliases = []string{"sessions"}
+	cmd.SetPermission(config.PermPlayer)
+	cmd.SetOptions(
+		SubcommandOption("list", "Lists upcoming sessions", StringOption("scope", "Use `all` to include closed and cancelled sessions", false)),
+		SubcommandOption("show", "Shows a session's roster", StringOption("id", "Session number", true)),
+		SubcommandOption("join", "Signs up for a session", StringOption("id", "Session number", true)),
+		SubcommandOption("leave", "Drops out of a session", StringOption("id", "Session number", true)),
+		SubcommandOption("ics", "Sends you a calendar file of your sessions"),
+		SubcommandOption("create", "Posts a new session",
+			StringOption("title", "Session title", true),
+			StringOption("when", "Date and time, e.g. friday 7pm or 2026-11-02 19:00 America/Chicago", true),
+			IntegerOption("players", "Maximum number of players", true),
+		),
+		SubcommandOption("close", "Closes sign-ups", StringOption("id", "Session number", true)),
+		SubcommandOption("cancel", "Cancels a session", StringOption("id", "Session number", true)),
+		SubcommandOption("end", "Ends a running session", StringOption("id", "Session number", true)),
+	)
+	RegisterCommand(cmd)
+	RegisterComponent("session", sessionButton)
+}
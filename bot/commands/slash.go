@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+/*
+ * Slash command support: every Command can also be registered as a Discord
+ * application command, with its options flattened back into prefix-style args
+ */
+
+// ApplicationCommand describes the command for registration as a Discord slash command.
+func (c *Command) ApplicationCommand() *discordgo.ApplicationCommand {
+	description := c.Description
+	if len(description) > 100 {
+		description = description[:100] // Discord's limit for command descriptions
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        strings.ToLower(c.Name),
+		Description: description,
+		Options:     c.Options(),
+	}
+}
+
+// ApplicationCommands returns every registered command, sorted by name, ready to register with Discord.
+func ApplicationCommands() []*discordgo.ApplicationCommand {
+	names := make([]string, 0, len(Commands))
+	for name := range Commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	appCommands := make([]*discordgo.ApplicationCommand, 0, len(names))
+	for _, name := range names {
+		appCommands = append(appCommands, Commands[name].ApplicationCommand())
+	}
+	return appCommands
+}
+
+// OptionArgs flattens slash command options into the args the prefix command
+// would have received, e.g. `/config set key:prefix value:?` becomes
+// [set prefix ?]. Users picked in options are returned as mentions.
+func OptionArgs(options []*discordgo.ApplicationCommandInteractionDataOption, resolved *discordgo.ApplicationCommandInteractionDataResolved) ([]string, []*discordgo.User) {
+	var (
+		args     = make([]string, 0, len(options))
+		mentions = make([]*discordgo.User, 0)
+	)
+
+	for _, opt := range options {
+		switch opt.Type {
+		case discordgo.ApplicationCommandOptionSubCommand, discordgo.ApplicationCommandOptionSubCommandGroup:
+			subArgs, subMentions := OptionArgs(opt.Options, resolved)
+			args = append(append(args, opt.Name), subArgs...)
+			mentions = append(mentions, subMentions...)
+		case discordgo.ApplicationCommandOptionString:
+			args = append(args, strings.Fields(opt.StringValue())...)
+		case discordgo.ApplicationCommandOptionInteger:
+			args = append(args, strconv.FormatInt(opt.IntValue(), 10))
+		case discordgo.ApplicationCommandOptionBoolean:
+			args = append(args, strconv.FormatBool(opt.BoolValue()))
+		case discordgo.ApplicationCommandOptionUser:
+			id := fmt.Sprint(opt.Value)
+			args = append(args, fmt.Sprintf("<@%s>", id))
+			if resolved != nil && resolved.Users[id] != nil {
+				mentions = append(mentions, resolved.Users[id])
+			}
+		default:
+			args = append(args, fmt.Sprint(opt.Value))
+		}
+	}
+
+	return args, mentions
+}
+
+// Option returns the slash command option called name, looking inside
+// subcommands, or nil for prefix commands and options that weren't given.
+func (ctx *Context) Option(name string) *discordgo.ApplicationCommandInteractionDataOption {
+	if ctx.Interaction == nil || ctx.Interaction.Type != discordgo.InteractionApplicationCommand {
+		return nil
+	}
+	return findOption(ctx.Interaction.ApplicationCommandData().Options, name)
+}
+
+func findOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		switch opt.Type {
+		case discordgo.ApplicationCommandOptionSubCommand, discordgo.ApplicationCommandOptionSubCommandGroup:
+			if found := findOption(opt.Options, name); found != nil {
+				return found
+			}
+		default:
+			if opt.Name == name {
+				return opt
+			}
+		}
+	}
+	return nil
+}
+
+/*
+ * Helpers for declaring command options
+ */
+
+func StringOption(name, description string, required bool) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        name,
+		Description: description,
+		Required:    required,
+	}
+}
+
+func IntegerOption(name, description string, required bool) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        name,
+		Description: description,
+		Required:    required,
+	}
+}
+
+func UserOption(name, description string, required bool) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionUser,
+		Name:        name,
+		Description: description,
+		Required:    required,
+	}
+}
+
+// ChoiceOption is a required string option limited to the given choices.
+func ChoiceOption(name, description string, choices ...string) *discordgo.ApplicationCommandOption {
+	opt := StringOption(name, description, true)
+	for _, choice := range choices {
+		opt.Choices = append(opt.Choices, &discordgo.ApplicationCommandOptionChoice{Name: choice, Value: choice})
+	}
+	return opt
+}
+
+func SubcommandOption(name, description string, options ...*discordgo.ApplicationCommandOption) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        name,
+		Description: description,
+		Options:     options,
+	}
+}
+
+// configKeyOption lets slash commands pick one of the guild config keys.
+func configKeyOption() *discordgo.ApplicationCommandOption {
+	return ChoiceOption("key", "Config key", config.ConfigKeys...)
+}
@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/bot/stats"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+const (
+	rngSampleRolls = 1000 // Rolls per face when sampling the RNG
+	maxShownFaces  = 20   // Largest die whose every face is listed
+	maxSampleSides = 100  // Largest die the RNG may be sampled with
+)
+
+var windowPattern = regexp.MustCompile(`^(\d+)([hdw])$`)
+
+func Stats(c *Command, ctx *Context) error {
+	if len(ctx.Args) > 0 && strings.EqualFold(ctx.Args[0], "rng") {
+		return sampleRNG(ctx, ctx.Args[1:])
+	}
+
+	query, err := parseStatsQuery(ctx, ctx.Args)
+	if err != nil {
+		return ctx.Reply(fmt.Sprintf("Sorry, %v\n%s", err, CommandHelp(c, ctx.Guild.Prefix())))
+	}
+
+	rolls := ctx.Guild.Rolls().Recent(0, hidePrivate(query.keep, "")) // Stats are posted for everyone to see
+	if len(rolls) == 0 {
+		return ctx.Reply(fmt.Sprintf("No rolls %s yet", query.about))
+	}
+	summary := stats.Summarize(rolls)
+
+	lines := []string{"__Players__"}
+	for _, p := range summary.Players {
+		lines = append(lines, formatPlayerStats(p))
+	}
+	lines = append(lines, "__Dice__")
+	for _, d := range summary.Dice {
+		lines = append(lines, formatDieStats(d)...)
+	}
+	return replyLines(ctx, fmt.Sprintf("**Roll stats** %s, %d rolls", query.about, summary.Rolls), lines)
+}
+
+// parseStatsQuery reads any mix of `@player`, a window such as `7d`, `24h`
+// or `2w`, and `session <id>`. With none of them every recorded roll counts.
+func parseStatsQuery(ctx *Context, args []string) (rollQuery, error) {
+	var (
+		filters []func(config.RollRecord) bool
+		about   []string
+		player  *discordgo.User
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := strings.ToLower(args[i])
+		switch {
+		case arg == "" || arg == "all" || arg == "player":
+			continue
+
+		case isMention(arg):
+			if player != nil {
+				return rollQuery{}, errOnePlayer
+			}
+			user, err := mentionedUser(ctx, args[i])
+			if err != nil {
+				return rollQuery{}, err
+			}
+			player = user
+			filters = append(filters, func(r config.RollRecord) bool { return r.UserID == user.ID })
+			about = append(about, "for "+memberName(ctx.Session, ctx.Guild.ID, user.ID))
+
+		case arg == "session":
+			if i+1 >= len(args) {
+				return rollQuery{}, fmt.Errorf("which session? e.g. `session 3`")
+			}
+			i++
+			sess, err := ctx.Guild.Sessions().Get(args[i])
+			if err != nil {
+				return rollQuery{}, err
+			}
+			filters = append(filters, sessionRolls(sess))
+			about = append(about, fmt.Sprintf("during session #%s", sess.ID))
+
+		case windowPattern.MatchString(arg):
+			window, err := parseWindow(arg)
+			if err != nil {
+				return rollQuery{}, err
+			}
+			since := time.Now().Add(-window)
+			filters = append(filters, func(r config.RollRecord) bool { return !r.Time.Before(since) })
+			about = append(about, "over the last "+arg)
+
+		default:
+			return rollQuery{}, fmt.Errorf("I don't understand %q", args[i])
+		}
+	}
+
+	query := rollQuery{about: "in this server"}
+	if len(about) > 0 {
+		query.about = strings.Join(about, " ")
+	}
+	if len(filters) > 0 {
+		query.keep = func(r config.RollRecord) bool {
+			for _, keep := range filters {
+				if !keep(r) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return query, nil
+}
+
+// parseWindow reads a window such as 24h, 7d or 2w.
+func parseWindow(window string) (time.Duration, error) {
+	match := windowPattern.FindStringSubmatch(window)
+	if match == nil {
+		return 0, fmt.Errorf("%q is not a time window, use hours, days or weeks like `7d`", window)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a time window", window)
+	}
+
+	unit := time.Hour
+	switch match[2] {
+	case "d":
+		unit = 24 * time.Hour
+	case "w":
+		unit = 7 * 24 * time.Hour
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func formatPlayerStats(p stats.Player) string {
+	line := "**" + p.Name + "**:"
+	if p.Duality > 0 {
+		line += fmt.Sprintf(" %d action rolls, %d with Hope and %d with Fear (%.0f%% Hope), %d %s, averaging %.1f",
+			p.Duality, p.Hope, p.Fear, 100*p.HopeRatio(), p.Critical, pluralize(p.Critical, "crit", "crits"), p.DualityAverage())
+	}
+	if p.Dice > 0 {
+		if p.Duality > 0 {
+			line += ";"
+		}
+		line += fmt.Sprintf(" %d dice rolls averaging %.1f", p.Dice, p.DiceAverage())
+	}
+	return line
+}
+
+func formatDieStats(d stats.Die) []string {
+	lines := []string{fmt.Sprintf("**d%d**: %d rolled, averaging %.2f (fair is %.1f)", d.Sides, d.Rolled(), d.Average(), float64(d.Sides+1)/2)}
+
+	if d.Sides <= maxShownFaces {
+		faces := make([]string, 0, len(d.Counts))
+		for i, c := range d.Counts {
+			faces = append(faces, fmt.Sprintf("%d: %d", i+1, c))
+		}
+		lines = append(lines, "> "+strings.Join(faces, ", "))
+	}
+
+	if !d.Testable() {
+		lines = append(lines, fmt.Sprintf("> Too few rolls to test for fairness, it takes at least %d", stats.MinExpected*d.Sides))
+		return lines
+	}
+	return append(lines, "> "+formatFairness(d.Fairness()))
+}
+
+func formatFairness(t stats.Test) string {
+	verdict := "consistent with fair dice"
+	if !t.Fair() {
+		verdict = "**unusually uneven**, worth keeping an eye on"
+	}
+	return fmt.Sprintf("χ² %.2f with %d degrees of freedom, p = %.3f: %s", t.ChiSquare, t.Freedom, t.P, verdict)
+}
+
+// sampleRNG rolls a fresh batch of dice through the roll commands' RNG and tests them.
+func sampleRNG(ctx *Context, args []string) error {
+	sides := dice.DualitySides
+	if len(args) > 0 {
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(args[0]), "d"))
+		if err != nil || n < 2 || n > maxSampleSides {
+			return ctx.Reply(fmt.Sprintf("Sorry, %q is not a die I can test, try `d12` or `d20`", args[0]))
+		}
+		sides = n
+	}
+
+	rolls := rngSampleRolls * sides
+	test := stats.ChiSquare(dice.Tally(ctx.RNG, sides, rolls))
+	return ctx.Reply(fmt.Sprintf("Rolled %d d%d with the bot's dice\n> %s", rolls, sides, formatFairness(test)))
+}
+
+func pluralize(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+func init() {
+	cmd := NewCommand("Stats", "Shows roll statistics and checks the dice are fair", Stats)
+	cmd.Usage = []string{
+		"stats [@player] [<n>h|<n>d|<n>w] [session <id>] - Shows Hope and Fear, crits, averages and how evenly each die has rolled",
+		"stats rng [d<sides>] - Rolls a fresh batch of dice and checks they're fair",
+	}
+	cmd.Examples = []string{"stats", "stats 7d", "stats @Tam session 3", "stats rng d20"}
+	cmd.SetPermission(config.PermPlayer)
+	cmd.SetOptions(
+		SubcommandOption("all", "Shows statistics for the rolls made in this server",
+			UserOption("player", "Only this player's rolls", false),
+			StringOption("window", "Only recent rolls, e.g. 24h, 7d or 2w", false),
+		),
+		SubcommandOption("session", "Shows statistics for the rolls made during a session", StringOption("id", "Session number", true)),
+		SubcommandOption("rng", "Rolls a fresh batch of dice and checks they're fair", StringOption("die", "Die to test, e.g. d20", false)),
+	)
+	RegisterCommand(cmd)
+}
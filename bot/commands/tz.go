@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+func TimeZone(c *Command, ctx *Context) error {
+	args := ctx.Args
+	if len(args) < 1 {
+		args = []string{"show"}
+	}
+
+	switch strings.ToLower(args[0]) {
+
+	case "show":
+		user := config.Users.Get(ctx.Author.ID)
+		if user.TimeZone == "" {
+			return ctx.Reply(fmt.Sprintf("You haven't set a time zone, so I read your times as UTC. Set one with `%stz set Europe/Berlin`", ctx.Guild.Prefix()))
+		}
+		return ctx.Reply(fmt.Sprintf("Your time zone is **%s**, where it's %s", user.TimeZone, time.Now().In(user.Location()).Format("Mon 15:04 MST")))
+
+	case "set":
+		if len(args) < 2 {
+			return ctx.Reply("Usage: !tz set <zone>, e.g. Europe/Berlin or America/Chicago")
+		}
+		user, err := config.Users.SetTimeZone(ctx.Author.ID, args[1])
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("Sorry, %v", err))
+		}
+		return ctx.Reply(fmt.Sprintf("Time zone set to **%s**, where it's %s", user.TimeZone, time.Now().In(user.Location()).Format("Mon 15:04 MST")))
+
+	case "clear", "reset":
+		if _, err := config.Users.SetTimeZone(ctx.Author.ID, ""); err != nil {
+			return ctx.Reply(fmt.Sprintf("Sorry, %v", err))
+		}
+		return ctx.Reply("Time zone cleared, I'll read your times as UTC")
+
+	default:
+		return ctx.Reply(CommandHelp(c, ctx.Guild.Prefix()))
+	}
+}
+
+func init() {
+	cmd := NewCommand("TZ", "Sets the time zone your scheduling commands are read in", TimeZone)
+	cmd.Usage = []string{
+		"tz [show] - Shows your time zone",
+		"tz set <zone> - Sets your time zone, using a name like Europe/Berlin",
+		"tz clear - Goes back to UTC",
+	}
+	cmd.Examples = []string{"tz set America/Chicago"}
+	cmd.Aliases = []string{"timezone"}
+	cmd.SetOptions(
+		SubcommandOption("show", "Shows your time zone"),
+		SubcommandOption("set", "Sets your time zone", StringOption("zone", "Zone name, e.g. Europe/Berlin", true)),
+		SubcommandOption("clear", "Goes back to UTC"),
+	)
+	RegisterCommand(cmd)
+}
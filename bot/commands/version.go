@@ -3,12 +3,11 @@ package commands
 import (
 	"fmt"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/nerdwerx/daggerbot/config"
 )
 
-func Version(c *Command, s *discordgo.Session, m *discordgo.MessageCreate) error {
-	if _, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Daggerbot Version: %s", config.Version)); err != nil {
+func Version(c *Command, ctx *Context) error {
+	if err := ctx.Reply(fmt.Sprintf("Daggerbot Version: %s", config.Version)); err != nil {
 		return fmt.Errorf("failed to send Version response: %w", err)
 	}
 	return nil
@@ -0,0 +1,303 @@
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Zone names must resolve even where the host has no zoneinfo
+)
+
+/*
+ * This package reads the dates and times players type into scheduling
+ * commands: `friday 7pm`, `tomorrow 19:30`, `2026-11-02 19:00 America/Chicago`,
+ * `nov 2 7pm` or `in 3 days`. Times are read in the caller's time zone unless
+ * the input ends with a zone name of its own.
+ */
+
+var ErrNoTime = errors.New("add a time of day, like 7pm or 19:30")
+
+var (
+	weekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+	units = map[string]time.Duration{
+		"minute": time.Minute, "min": time.Minute,
+		"hour": time.Hour, "hr": time.Hour,
+		"day":  24 * time.Hour,
+		"week": 7 * 24 * time.Hour,
+	}
+	isoDateTime = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})t(\d{1,2}:\d{2})$`)
+	dayOfMonth  = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+)
+
+// Parse reads input as a moment in time. Relative dates are counted from now,
+// and wall-clock times are read in loc unless input names a zone at the end.
+func Parse(input string, now time.Time, loc *time.Location) (time.Time, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return time.Time{}, errors.New("no date or time given")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// A trailing zone name overrides the caller's zone
+	if zone, ok := zoneName(fields[len(fields)-1]); ok {
+		loc = zone
+		fields = fields[:len(fields)-1]
+	}
+	now = now.In(loc)
+
+	words := make([]string, 0, len(fields)+1)
+	for _, f := range fields {
+		f = strings.TrimSuffix(strings.ToLower(f), ",")
+		switch {
+		case f == "at" || f == "on":
+			continue // Filler, as in "friday at 7pm"
+		case isoDateTime.MatchString(f):
+			m := isoDateTime.FindStringSubmatch(f)
+			words = append(words, m[1], m[2])
+		default:
+			words = append(words, f)
+		}
+	}
+	if len(words) == 0 {
+		return time.Time{}, errors.New("no date or time given")
+	}
+
+	if words[0] == "in" {
+		return relative(words[1:], now)
+	}
+
+	hour, minute, n, ok := timeOfDay(words)
+	if !ok {
+		return time.Time{}, ErrNoTime
+	}
+	words = words[:len(words)-n]
+
+	return onDate(words, now, hour, minute)
+}
+
+// zoneName reports whether word names a time zone, like Europe/Berlin or UTC.
+func zoneName(word string) (*time.Location, bool) {
+	if !strings.Contains(word, "/") && !strings.EqualFold(word, "utc") && !strings.EqualFold(word, "gmt") {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(word)
+	if err != nil {
+		if strings.EqualFold(word, "utc") || strings.EqualFold(word, "gmt") {
+			return time.UTC, true
+		}
+		return nil, false
+	}
+	return loc, true
+}
+
+// relative reads "3 days", "an hour" or "2 hours 30 minutes" as an offset from now.
+func relative(words []string, now time.Time) (time.Time, error) {
+	if len(words) == 0 || len(words)%2 != 0 {
+		return time.Time{}, errors.New("say how long from now, like `in 3 days` or `in 2 hours`")
+	}
+
+	t := now
+	for i := 0; i < len(words); i += 2 {
+		amount, err := strconv.Atoi(words[i])
+		if words[i] == "a" || words[i] == "an" {
+			amount, err = 1, nil
+		}
+		if err != nil || amount < 0 {
+			return time.Time{}, fmt.Errorf("%q is not a number", words[i])
+		}
+
+		unit, ok := units[strings.TrimSuffix(words[i+1], "s")]
+		if !ok {
+			return time.Time{}, fmt.Errorf("%q is not minutes, hours, days or weeks", words[i+1])
+		}
+		switch unit {
+		case units["day"]:
+			t = t.AddDate(0, 0, amount) // Keeps the wall-clock time across DST changes
+		case units["week"]:
+			t = t.AddDate(0, 0, 7*amount)
+		default:
+			t = t.Add(time.Duration(amount) * unit)
+		}
+	}
+	return t.Truncate(time.Minute), nil
+}
+
+// timeOfDay reads a time from the end of words, returning how many words it used.
+func timeOfDay(words []string) (int, int, int, bool) {
+	if len(words) >= 2 {
+		last := words[len(words)-1]
+		if last == "am" || last == "pm" {
+			if h, m, ok := clock(words[len(words)-2] + last); ok {
+				return h, m, 2, true
+			}
+		}
+	}
+	if h, m, ok := clock(words[len(words)-1]); ok {
+		return h, m, 1, true
+	}
+	return 0, 0, 0, false
+}
+
+// clock reads one of 7pm, 7:30pm, 19:30, noon or midnight.
+func clock(word string) (int, int, bool) {
+	switch word {
+	case "noon", "midday":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	meridiem := ""
+	if strings.HasSuffix(word, "am") || strings.HasSuffix(word, "pm") {
+		meridiem = word[len(word)-2:]
+		word = word[:len(word)-2]
+	}
+
+	hours, minutes, hasMinutes := strings.Cut(word, ":")
+	hour, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, 0, false
+	}
+	minute := 0
+	if hasMinutes {
+		if len(minutes) != 2 {
+			return 0, 0, false
+		}
+		if minute, err = strconv.Atoi(minutes); err != nil || minute > 59 {
+			return 0, 0, false
+		}
+	}
+
+	switch {
+	case meridiem == "" && !hasMinutes:
+		return 0, 0, false // A bare number could be anything
+	case meridiem == "":
+		if hour > 23 {
+			return 0, 0, false
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, true
+}
+
+// onDate reads the date words in front of a time. With no date, the next time
+// the clock reads hour:minute is used.
+func onDate(words []string, now time.Time, hour, minute int) (time.Time, error) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+	}
+	today := at(now.Date())
+
+	next := false
+	if len(words) > 0 && words[0] == "next" {
+		next = true
+		words = words[1:]
+	}
+
+	switch {
+	case len(words) == 0 && !next:
+		if !today.After(now) {
+			return today.AddDate(0, 0, 1), nil
+		}
+		return today, nil
+
+	case len(words) == 1 && !next && (words[0] == "today" || words[0] == "tonight"):
+		return today, nil
+
+	case len(words) == 1 && !next && words[0] == "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+
+	case len(words) == 1:
+		if wd, ok := weekdays[words[0]]; ok {
+			days := (int(wd) - int(now.Weekday()) + 7) % 7
+			if days == 0 && (next || !today.After(now)) {
+				days = 7
+			}
+			return today.AddDate(0, 0, days), nil
+		}
+	}
+	if next {
+		return time.Time{}, fmt.Errorf("%q is not a day of the week", strings.Join(words, " "))
+	}
+
+	if len(words) == 1 {
+		if d, err := time.ParseInLocation("2006-01-02", words[0], now.Location()); err == nil {
+			return at(d.Date()), nil
+		}
+	}
+
+	if t, ok := monthDay(words, now, at); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date I understand", strings.Join(words, " "))
+}
+
+// monthDay reads "nov 2", "2 november" or "nov 2nd 2026". Without a year, the
+// next such date is used.
+func monthDay(words []string, now time.Time, at func(int, time.Month, int) time.Time) (time.Time, bool) {
+	if len(words) < 2 || len(words) > 3 {
+		return time.Time{}, false
+	}
+
+	month, ok := monthName(words[0])
+	dayWord := words[1]
+	if !ok {
+		if month, ok = monthName(words[1]); !ok {
+			return time.Time{}, false
+		}
+		dayWord = words[0]
+	}
+
+	m := dayOfMonth.FindStringSubmatch(dayWord)
+	if m == nil {
+		return time.Time{}, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	if day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	if len(words) == 3 {
+		year, err := strconv.Atoi(words[2])
+		if err != nil || year < 1000 {
+			return time.Time{}, false
+		}
+		t := at(year, month, day)
+		return t, t.Day() == day
+	}
+
+	t := at(now.Year(), month, day)
+	if !t.After(now) {
+		t = at(now.Year()+1, month, day)
+	}
+	return t, t.Day() == day
+}
+
+func monthName(word string) (time.Month, bool) {
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if word == name || (len(word) >= 3 && strings.HasPrefix(name, word)) {
+			return m, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,109 @@
+package dates
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		wednesday = time.Date(2026, time.March, 4, 15, 0, 0, 0, chicago)    // A plain afternoon
+		springEve = time.Date(2026, time.March, 7, 15, 0, 0, 0, chicago)    // Clocks go forward overnight
+		fallEve   = time.Date(2026, time.October, 31, 15, 0, 0, 0, chicago) // Clocks go back overnight
+	)
+
+	tests := []struct {
+		input string
+		now   time.Time
+		loc   *time.Location
+		want  string // RFC 3339, so the offset is checked too
+	}{
+		{"friday 7pm", wednesday, chicago, "2026-03-06T19:00:00-06:00"},
+		{"Friday at 7 PM", wednesday, chicago, "2026-03-06T19:00:00-06:00"},
+		{"on fri, 7:30pm", wednesday, chicago, "2026-03-06T19:30:00-06:00"},
+		{"tomorrow 19:30", wednesday, chicago, "2026-03-05T19:30:00-06:00"},
+		{"tonight 9pm", wednesday, chicago, "2026-03-04T21:00:00-06:00"},
+		{"7pm", wednesday, chicago, "2026-03-04T19:00:00-06:00"},
+		{"noon", wednesday, chicago, "2026-03-05T12:00:00-06:00"},
+		{"wednesday 8pm", wednesday, chicago, "2026-03-04T20:00:00-06:00"},
+		{"wednesday 10am", wednesday, chicago, "2026-03-11T10:00:00-05:00"},
+		{"next wednesday 8pm", wednesday, chicago, "2026-03-11T20:00:00-05:00"},
+		{"in 3 days", wednesday, chicago, "2026-03-07T15:00:00-06:00"},
+		{"in 2 hours 30 minutes", wednesday, chicago, "2026-03-04T17:30:00-06:00"},
+		{"in a week", wednesday, chicago, "2026-03-11T15:00:00-05:00"},
+		{"nov 2 7pm", wednesday, chicago, "2026-11-02T19:00:00-06:00"},
+		{"2nd november 2027 7pm", wednesday, chicago, "2027-11-02T19:00:00-05:00"},
+		{"2026-11-02 19:00", wednesday, chicago, "2026-11-02T19:00:00-06:00"},
+
+		// A trailing zone overrides the caller's
+		{"friday 7pm Europe/Berlin", wednesday, chicago, "2026-03-06T19:00:00+01:00"},
+		{"2026-11-02 19:00 America/New_York", wednesday, chicago, "2026-11-02T19:00:00-05:00"},
+		{"2026-11-02T19:00 UTC", wednesday, chicago, "2026-11-02T19:00:00Z"},
+		{"friday 7pm", wednesday, nil, "2026-03-06T19:00:00Z"},
+
+		// Clocks go forward at 2am on March 8
+		{"tomorrow 7pm", springEve, chicago, "2026-03-08T19:00:00-05:00"},
+		{"in 1 day", springEve, chicago, "2026-03-08T15:00:00-05:00"},
+		{"in 24 hours", springEve, chicago, "2026-03-08T16:00:00-05:00"},
+
+		// Clocks go back at 2am on November 1
+		{"sunday 7pm", fallEve, chicago, "2026-11-01T19:00:00-06:00"},
+		{"in 1 day", fallEve, chicago, "2026-11-01T15:00:00-06:00"},
+		{"in 24 hours", fallEve, chicago, "2026-11-01T14:00:00-06:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(tt.input, tt.now, tt.loc)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+			}
+			if s := got.Format(time.RFC3339); s != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.input, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.March, 4, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		is    error // The error wrapped, if any
+	}{
+		{"", nil},
+		{"friday", ErrNoTime},
+		{"next tomorrow 7pm", nil},
+		{"feb 30 7pm", nil},
+		{"in 3 fortnights", nil},
+		{"in three days", nil},
+		{"in 3", nil},
+		{"someday 25:00", ErrNoTime},
+		{"13pm", ErrNoTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(tt.input, now, time.UTC)
+			if err == nil {
+				t.Fatalf("Parse(%q) = %s, want an error", tt.input, got)
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.input, err, tt.is)
+			}
+		})
+	}
+}
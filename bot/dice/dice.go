@@ -0,0 +1,230 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+ * This package parses and evaluates dice expressions
+ */
+
+// Die is a single die that was rolled.
+type Die struct {
+	Value    int  // Face rolled
+	Dropped  bool // Not counted toward the total, by a keep/drop rule or because it was rerolled
+	Rerolled bool // Replaced by the next die in the group
+	Exploded bool // Met the explode condition and added the next die in the group
+	Success  bool // Met the success target
+}
+
+func (d Die) String() string {
+	str := fmt.Sprintf("%d", d.Value)
+	if d.Exploded {
+		str += "!"
+	}
+	if d.Success {
+		str = "**" + str + "**"
+	}
+	if d.Dropped {
+		str = "~~" + str + "~~"
+	}
+	return str
+}
+
+// Group is the outcome of a single dice term such as 2d6 or 4d6kh3.
+type Group struct {
+	Count     int   // Number of dice asked for
+	Sides     int   // Number of sides on each die
+	Dice      []Die // Every die rolled, including rerolled, exploded and dropped dice
+	Total     int   // Sum of the kept dice, or the number of successes
+	Successes bool  // Whether Total counts successes rather than summing faces
+}
+
+// MaxListedDice is how many dice Group.String lists before summarizing the
+// rest, since exploding dice can add thousands to a single group.
+const MaxListedDice = 50
+
+// String lists the dice rolled, e.g. [3, ~~1~~, 6!, 2], summarizing any beyond MaxListedDice.
+func (g Group) String() string {
+	shown := g.Dice[:min(len(g.Dice), MaxListedDice)]
+	values := make([]string, 0, len(shown)+1)
+	for _, d := range shown {
+		values = append(values, d.String())
+	}
+	if hidden := len(g.Dice) - len(shown); hidden > 0 {
+		values = append(values, fmt.Sprintf("…and %d more", hidden))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(values, ", "))
+}
+
+// Rolls returns the faces of the dice that count toward the total.
+func (g Group) Rolls() []int {
+	rolls := make([]int, 0, len(g.Dice))
+	for _, d := range g.Dice {
+		if !d.Dropped {
+			rolls = append(rolls, d.Value)
+		}
+	}
+	return rolls
+}
+
+// Result is the outcome of rolling an expression.
+type Result struct {
+	Expression string  // Normalized expression that was rolled
+	Total      int     // Final value of the expression
+	Groups     []Group // Every dice term, in expression order
+	Breakdown  string  // Expression with each dice term replaced by its rolls
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%s = %d", r.Breakdown, r.Total)
+}
+
+// Natural reports the face of the only die in the expression, if exactly one was rolled.
+func (r *Result) Natural() (int, bool) {
+	if len(r.Groups) != 1 || len(r.Groups[0].Dice) != 1 {
+		return 0, false
+	}
+	return r.Groups[0].Dice[0].Value, true
+}
+
+// Roll parses and rolls an expression in one step.
+func Roll(rng RNG, expr string) (*Result, error) {
+	e, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return e.Roll(rng)
+}
+
+// Roll evaluates the expression, rolling every die it contains with rng.
+func (e *Expr) Roll(rng RNG) (*Result, error) {
+	ev := &evaluator{rng: rng}
+
+	total, breakdown, err := e.root.eval(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Expression: e.String(),
+		Total:      total,
+		Groups:     ev.groups,
+		Breakdown:  breakdown,
+	}, nil
+}
+
+type evaluator struct {
+	rng    RNG
+	groups []Group
+}
+
+func (ev *evaluator) roll(sides int) int {
+	return rollDie(ev.rng, sides)
+}
+
+func (n *numberNode) eval(ev *evaluator) (int, string, error) {
+	return n.value, n.String(), nil
+}
+
+func (n *diceNode) eval(ev *evaluator) (int, string, error) {
+	group := Group{Count: n.count, Sides: n.sides, Dice: make([]Die, 0, n.count), Successes: n.success != nil}
+
+	for range n.count {
+		group.Dice = append(group.Dice, n.rollOne(ev)...)
+		for explosions := 0; n.explode != nil && explosions < MaxExplosions; explosions++ {
+			last := &group.Dice[len(group.Dice)-1]
+			if !n.explode.matches(last.Value) {
+				break
+			}
+			last.Exploded = true
+			group.Dice = append(group.Dice, n.rollOne(ev)...)
+		}
+	}
+
+	if n.keep != nil {
+		n.keep.apply(group.Dice)
+	}
+
+	for i := range group.Dice {
+		d := &group.Dice[i]
+		if d.Dropped {
+			continue
+		}
+		if n.success == nil {
+			group.Total += d.Value
+		} else if n.success.matches(d.Value) {
+			d.Success = true
+			group.Total++
+		}
+	}
+
+	ev.groups = append(ev.groups, group)
+	return group.Total, n.String() + " " + group.String(), nil
+}
+
+// rollOne rolls a single die, rerolling it as the reroll modifier requires.
+// Every reroll is kept in the result, marked as dropped.
+func (n *diceNode) rollOne(ev *evaluator) []Die {
+	var rolled []Die
+
+	v := ev.roll(n.sides)
+	for rerolls := 0; n.reroll != nil && n.reroll.matches(v) && rerolls < MaxRerolls; rerolls++ {
+		rolled = append(rolled, Die{Value: v, Dropped: true, Rerolled: true})
+		v = ev.roll(n.sides)
+		if n.rerollOnce {
+			break
+		}
+	}
+
+	return append(rolled, Die{Value: v})
+}
+
+func (n *groupNode) eval(ev *evaluator) (int, string, error) {
+	v, s, err := n.inner.eval(ev)
+	if err != nil {
+		return 0, "", err
+	}
+	return v, "(" + s + ")", nil
+}
+
+func (n *negateNode) eval(ev *evaluator) (int, string, error) {
+	v, s, err := n.operand.eval(ev)
+	if err != nil {
+		return 0, "", err
+	}
+	return -v, "-" + s, nil
+}
+
+func (n *binaryNode) eval(ev *evaluator) (int, string, error) {
+	left, ls, err := n.left.eval(ev)
+	if err != nil {
+		return 0, "", err
+	}
+	right, rs, err := n.right.eval(ev)
+	if err != nil {
+		return 0, "", err
+	}
+
+	breakdown := fmt.Sprintf("%s %c %s", ls, n.op, rs)
+	switch n.op {
+	case '+':
+		return left + right, breakdown, nil
+	case '-':
+		return left - right, breakdown, nil
+	case '*':
+		if product := left * right; left != 0 && (product/left != right || product > MaxResult || product < -MaxResult) {
+			return 0, "", errors.New("result is too large")
+		}
+		return left * right, breakdown, nil
+	case '/':
+		if right == 0 {
+			return 0, "", errors.New("division by zero")
+		}
+		return left / right, breakdown, nil // Rounds toward zero
+	default:
+		return 0, "", fmt.Errorf("unknown operator %q", n.op)
+	}
+}
@@ -0,0 +1,149 @@
+package dice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRoll(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr      string
+		faces     []int
+		total     int
+		breakdown string
+	}{
+		{"d20", []int{17}, 17, "1d20 [17]"},
+		{"2d6+3", []int{4, 5}, 12, "2d6 [4, 5] + 3"},
+		{"2d6 - 1d4", []int{6, 6, 3}, 9, "2d6 [6, 6] - 1d4 [3]"},
+		{"(1d4+1)*2", []int{3}, 8, "(1d4 [3] + 1) * 2"},
+		{"7/2", nil, 3, "7 / 2"},
+		{"-1d6+10", []int{4}, 6, "-1d6 [4] + 10"},
+		{"4d6kh3", []int{1, 2, 3, 4}, 9, "4d6kh3 [~~1~~, 2, 3, 4]"},
+		{"4d6k3", []int{5, 1, 6, 2}, 13, "4d6kh3 [5, ~~1~~, 6, 2]"},
+		{"2d20kl1", []int{15, 7}, 7, "2d20kl1 [~~15~~, 7]"},
+		{"4d6dl1", []int{3, 3, 4, 2}, 10, "4d6dl1 [3, 3, 4, ~~2~~]"},
+		{"3d6!", []int{6, 2, 3, 4}, 15, "3d6! [6!, 2, 3, 4]"},
+		{"1d6!>=5", []int{5, 6, 1}, 12, "1d6!>=5 [5!, 6!, 1]"},
+		{"2d6r1", []int{1, 1, 4, 5}, 9, "2d6r1 [~~1~~, ~~1~~, 4, 5]"},
+		{"2d6ro1", []int{1, 1, 5}, 6, "2d6ro1 [~~1~~, 1, 5]"},
+		{"6d10>=7", []int{7, 2, 10, 6, 8, 1}, 3, "6d10>=7 [**7**, 2, **10**, 6, **8**, 1]"},
+		{"d%", []int{42}, 42, "1d% [42]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := Roll(NewFixedRNG(tt.faces...), tt.expr)
+			if err != nil {
+				t.Fatalf("Roll(%q) failed: %v", tt.expr, err)
+			}
+			if result.Total != tt.total {
+				t.Errorf("Roll(%q) total = %d, want %d", tt.expr, result.Total, tt.total)
+			}
+			if result.Breakdown != tt.breakdown {
+				t.Errorf("Roll(%q) breakdown = %q, want %q", tt.expr, result.Breakdown, tt.breakdown)
+			}
+		})
+	}
+}
+
+func TestRollErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		want string // Part of the error message
+	}{
+		{"", "empty expression"},
+		{"foo", ""},
+		{"0d6", "number of dice must be between 1 and 100"},
+		{"101d6", "number of dice must be between 1 and 100"},
+		{"1d0", "number of sides must be between 1 and 1000"},
+		{"1d1001", "number of sides must be between 1 and 1000"},
+		{strings.Repeat("100d6+", 5) + "1d6", "more than 500 dice"},
+		{"1/0", "division by zero"},
+		{"2d6kh3", "must keep or drop between 1 and 2 dice"},
+		{"1d6r<=6", "would reroll every face"},
+		{"1d6!>=1", "would explode every face"},
+		{"2d6r1r2", "rerolled once per term"},
+		{"2d6kh1kl1", "only one keep or drop"},
+		{"(1d6", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Roll(NewFixedRNG(1), tt.expr)
+			if err == nil {
+				t.Fatalf("Roll(%q) succeeded, want an error", tt.expr)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Roll(%q) error = %q, want it to mention %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeededRNGRepeats(t *testing.T) {
+	t.Parallel()
+
+	first, err := Roll(NewSeededRNG(42), "10d20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Roll(NewSeededRNG(42), "10d20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Breakdown != second.Breakdown {
+		t.Errorf("seeded rolls differ: %q and %q", first.Breakdown, second.Breakdown)
+	}
+}
+
+func TestNatural(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr  string
+		faces []int
+		want  int
+		ok    bool
+	}{
+		{"d20", []int{1}, 1, true},
+		{"1d20+5", []int{20}, 20, true},
+		{"2d20", []int{1, 1}, 0, false},
+		{"5", nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		result, err := Roll(NewFixedRNG(tt.faces...), tt.expr)
+		if err != nil {
+			t.Fatalf("Roll(%q) failed: %v", tt.expr, err)
+		}
+		if got, ok := result.Natural(); got != tt.want || ok != tt.ok {
+			t.Errorf("Roll(%q).Natural() = %d, %v, want %d, %v", tt.expr, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGroupStringSummarizesExplodingDice(t *testing.T) {
+	t.Parallel()
+
+	result, err := Roll(NewFixedRNG(6), "3d6!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := result.Groups[0]
+	if len(g.Dice) != 3*(MaxExplosions+1) {
+		t.Fatalf("rolled %d dice, want %d", len(g.Dice), 3*(MaxExplosions+1))
+	}
+	want := fmt.Sprintf("…and %d more]", len(g.Dice)-MaxListedDice)
+	if s := g.String(); !strings.HasSuffix(s, want) || strings.Count(s, "6!") != MaxListedDice {
+		t.Errorf("String() = %q, want %d dice listed then %q", s, MaxListedDice, want)
+	}
+}
@@ -0,0 +1,126 @@
+package dice
+
+import (
+	"fmt"
+)
+
+/*
+ * Daggerheart action rolls: a Hope d12 and a Fear d12, plus modifiers
+ */
+
+const (
+	DualitySides   = 12 // Sides on the Hope and Fear dice
+	AdvantageSides = 6  // Sides on the advantage/disadvantage die
+)
+
+// Action describes a Daggerheart action roll before it is rolled.
+type Action struct {
+	Modifier   int     // Sum of trait, Experience and any other flat bonuses
+	Bonuses    []Bonus // Named parts of Modifier, e.g. the trait and Experience used
+	Advantage  int     // Net advantage: positive for advantage, negative for disadvantage
+	Difficulty int     // Target number to meet or beat, 0 when none was given
+}
+
+// Bonus is a named modifier, shown by name in the breakdown.
+type Bonus struct {
+	Label string
+	Value int
+}
+
+// AddBonus adds a named modifier to the action.
+func (a *Action) AddBonus(label string, value int) {
+	a.Bonuses = append(a.Bonuses, Bonus{Label: label, Value: value})
+	a.Modifier += value
+}
+
+// ActionResult is the outcome of a Daggerheart action roll.
+type ActionResult struct {
+	Action
+	Hope      int // Value of the Hope die
+	Fear      int // Value of the Fear die
+	Bonus     int // Value of the advantage or disadvantage d6, 0 when neither applied
+	Total     int // Hope + Fear + Modifier ± Bonus
+	Critical  bool
+	Succeeded bool // Only meaningful when a Difficulty was given
+}
+
+// RollAction rolls the Hope and Fear dice for an action. Advantage and
+// disadvantage cancel each other out, and only one d6 is ever rolled.
+func RollAction(rng RNG, action Action) *ActionResult {
+	r := &ActionResult{
+		Action: action,
+		Hope:   rollDie(rng, DualitySides),
+		Fear:   rollDie(rng, DualitySides),
+	}
+
+	if action.Advantage != 0 {
+		r.Bonus = rollDie(rng, AdvantageSides)
+	}
+
+	r.Total = r.Hope + r.Fear + action.Modifier
+	if action.Advantage > 0 {
+		r.Total += r.Bonus
+	} else {
+		r.Total -= r.Bonus
+	}
+
+	// Matching dice are a critical success regardless of the Difficulty
+	r.Critical = r.Hope == r.Fear
+	r.Succeeded = r.Critical || r.Total >= action.Difficulty
+
+	return r
+}
+
+// WithHope reports whether the roll was made with Hope. Critical successes always are.
+func (r *ActionResult) WithHope() bool {
+	return r.Hope >= r.Fear
+}
+
+// Outcome names the result, e.g. "Success with Hope" or "Critical Success".
+func (r *ActionResult) Outcome() string {
+	if r.Critical {
+		return "Critical Success"
+	}
+
+	side := "Fear"
+	if r.WithHope() {
+		side = "Hope"
+	}
+	if r.Difficulty == 0 {
+		return "with " + side
+	}
+	if r.Succeeded {
+		return "Success with " + side
+	}
+	return "Failure with " + side
+}
+
+// Breakdown lists every die and modifier that went into the total.
+func (r *ActionResult) Breakdown() string {
+	breakdown := fmt.Sprintf("_Hope_ %d + _Fear_ %d", r.Hope, r.Fear)
+	unnamed := r.Modifier
+	for _, b := range r.Bonuses {
+		breakdown += fmt.Sprintf(" %s _%s_", signed(b.Value), b.Label)
+		unnamed -= b.Value
+	}
+	if unnamed != 0 {
+		breakdown += " " + signed(unnamed)
+	}
+	if r.Advantage > 0 {
+		breakdown += fmt.Sprintf(" + %d _advantage_", r.Bonus)
+	} else if r.Advantage < 0 {
+		breakdown += fmt.Sprintf(" - %d _disadvantage_", r.Bonus)
+	}
+	breakdown += fmt.Sprintf(" = %d", r.Total)
+	if r.Difficulty > 0 {
+		breakdown += fmt.Sprintf(" vs Difficulty %d", r.Difficulty)
+	}
+	return breakdown
+}
+
+func signed(n int) string {
+	if n < 0 {
+		return fmt.Sprintf("- %d", -n)
+	}
+	return fmt.Sprintf("+ %d", n)
+}
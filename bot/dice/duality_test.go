@@ -0,0 +1,110 @@
+package dice
+
+import "testing"
+
+func TestRollAction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		action    Action
+		faces     []int // Hope, Fear, then the advantage die
+		total     int
+		critical  bool
+		succeeded bool
+		outcome   string
+		breakdown string
+	}{
+		{
+			name:      "hope",
+			faces:     []int{9, 4},
+			total:     13,
+			succeeded: true,
+			outcome:   "with Hope",
+			breakdown: "_Hope_ 9 + _Fear_ 4 = 13",
+		},
+		{
+			name:      "fear",
+			faces:     []int{3, 10},
+			total:     13,
+			succeeded: true,
+			outcome:   "with Fear",
+			breakdown: "_Hope_ 3 + _Fear_ 10 = 13",
+		},
+		{
+			name:      "modifier and advantage",
+			action:    Action{Modifier: 2, Advantage: 1, Difficulty: 14},
+			faces:     []int{8, 5, 3},
+			total:     18,
+			succeeded: true,
+			outcome:   "Success with Hope",
+			breakdown: "_Hope_ 8 + _Fear_ 5 + 2 + 3 _advantage_ = 18 vs Difficulty 14",
+		},
+		{
+			name:      "disadvantage fails",
+			action:    Action{Advantage: -1, Difficulty: 12},
+			faces:     []int{4, 7, 2},
+			total:     9,
+			outcome:   "Failure with Fear",
+			breakdown: "_Hope_ 4 + _Fear_ 7 - 2 _disadvantage_ = 9 vs Difficulty 12",
+		},
+		{
+			name:      "advantage and disadvantage cancel",
+			action:    Action{Advantage: 0},
+			faces:     []int{6, 6},
+			total:     12,
+			critical:  true,
+			succeeded: true,
+			outcome:   "Critical Success",
+			breakdown: "_Hope_ 6 + _Fear_ 6 = 12",
+		},
+		{
+			name:      "critical beats any difficulty",
+			action:    Action{Difficulty: 30},
+			faces:     []int{1, 1},
+			total:     2,
+			critical:  true,
+			succeeded: true,
+			outcome:   "Critical Success",
+			breakdown: "_Hope_ 1 + _Fear_ 1 = 2 vs Difficulty 30",
+		},
+		{
+			name: "named bonuses",
+			action: func() Action {
+				var a Action
+				a.AddBonus("Agility", 2)
+				a.AddBonus("Tracking", 2)
+				a.Modifier--
+				return a
+			}(),
+			faces:     []int{5, 2},
+			total:     10,
+			succeeded: true,
+			outcome:   "with Hope",
+			breakdown: "_Hope_ 5 + _Fear_ 2 + 2 _Agility_ + 2 _Tracking_ - 1 = 10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := RollAction(NewFixedRNG(tt.faces...), tt.action)
+			if r.Total != tt.total {
+				t.Errorf("total = %d, want %d", r.Total, tt.total)
+			}
+			if r.Critical != tt.critical {
+				t.Errorf("critical = %v, want %v", r.Critical, tt.critical)
+			}
+			if r.Succeeded != tt.succeeded {
+				t.Errorf("succeeded = %v, want %v", r.Succeeded, tt.succeeded)
+			}
+			if got := r.Outcome(); got != tt.outcome {
+				t.Errorf("outcome = %q, want %q", got, tt.outcome)
+			}
+			if got := r.Breakdown(); got != tt.breakdown {
+				t.Errorf("breakdown = %q, want %q", got, tt.breakdown)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package dice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Tokenizer for dice expressions such as `2d6+1d4+3`, `(2d6+3)*2` or `4d6kh3`
+ */
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokDice    // d or D
+	tokPercent // % as in d%
+	tokPlus
+	tokMinus
+	tokStar
+	tokSlash
+	tokLParen
+	tokRParen
+	tokModifier // kh, kl, k, dh, dl, r or ro
+	tokBang     // ! as in 3d6!
+	tokCompare  // =, <, >, <= or >=
+)
+
+// modifiers are the letter sequences allowed after a dice term
+var modifiers = map[string]bool{"k": true, "kh": true, "kl": true, "dh": true, "dl": true, "r": true, "ro": true}
+
+type token struct {
+	kind  tokenKind
+	pos   int    // Byte offset of the token in the expression
+	text  string // Raw text of the token
+	value int    // Numeric value for tokNumber
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// SyntaxError describes a problem found while tokenizing or parsing a dice expression.
+type SyntaxError struct {
+	Expr string // The expression being parsed
+	Pos  int    // Byte offset of the problem
+	Msg  string // What went wrong
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d of %q", e.Msg, e.Pos+1, e.Expr)
+}
+
+func tokenize(expr string) ([]token, error) {
+	tokens := make([]token, 0, len(expr))
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(expr) && expr[i] >= '0' && expr[i] <= '9' {
+				i++
+			}
+			n, err := strconv.Atoi(expr[start:i])
+			if err != nil || n > MaxNumber {
+				return nil, &SyntaxError{Expr: expr, Pos: start, Msg: fmt.Sprintf("number %s is too large", expr[start:i])}
+			}
+			tokens = append(tokens, token{kind: tokNumber, pos: start, text: expr[start:i], value: n})
+			continue
+		case isLetter(c):
+			start := i
+			for i < len(expr) && isLetter(expr[i]) {
+				i++
+			}
+			word := strings.ToLower(expr[start:i])
+			switch {
+			case word == "d":
+				tokens = append(tokens, token{kind: tokDice, pos: start, text: expr[start:i]})
+			case modifiers[word]:
+				tokens = append(tokens, token{kind: tokModifier, pos: start, text: word})
+			default:
+				return nil, &SyntaxError{Expr: expr, Pos: start, Msg: fmt.Sprintf("unknown modifier %q", expr[start:i])}
+			}
+			continue
+		case c == '<' || c == '>' || c == '=':
+			start := i
+			i++
+			if c != '=' && i < len(expr) && expr[i] == '=' {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokCompare, pos: start, text: expr[start:i]})
+			continue
+		}
+
+		var kind tokenKind
+		switch c {
+		case '!':
+			kind = tokBang
+		case '%':
+			kind = tokPercent
+		case '+':
+			kind = tokPlus
+		case '-':
+			kind = tokMinus
+		case '*':
+			kind = tokStar
+		case '/':
+			kind = tokSlash
+		case '(':
+			kind = tokLParen
+		case ')':
+			kind = tokRParen
+		default:
+			return nil, &SyntaxError{Expr: expr, Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+		tokens = append(tokens, token{kind: kind, pos: i, text: expr[i : i+1]})
+		i++
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
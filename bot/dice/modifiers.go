@@ -0,0 +1,92 @@
+package dice
+
+import (
+	"fmt"
+	"slices"
+)
+
+/*
+ * Dice modifiers: keep/drop, exploding, rerolling and success counting
+ */
+
+const (
+	MaxExplosions = 100 // Maximum extra dice a single die may explode into
+	MaxRerolls    = 100 // Maximum times a single die may be rerolled
+)
+
+// compare is a condition such as `>=7` or `=1` tested against a die face.
+type compare struct {
+	op    string
+	value int
+}
+
+func (c *compare) matches(face int) bool {
+	switch c.op {
+	case ">=":
+		return face >= c.value
+	case "<=":
+		return face <= c.value
+	case ">":
+		return face > c.value
+	case "<":
+		return face < c.value
+	default:
+		return face == c.value
+	}
+}
+
+// always reports whether the condition holds for every face of the die.
+func (c *compare) always(sides int) bool {
+	for face := 1; face <= sides; face++ {
+		if !c.matches(face) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *compare) String() string {
+	if c.op == "=" {
+		return fmt.Sprintf("%d", c.value)
+	}
+	return fmt.Sprintf("%s%d", c.op, c.value)
+}
+
+// keep selects which dice count toward the total.
+type keep struct {
+	mode  string // kh, kl, dh or dl
+	count int
+}
+
+func (k *keep) String() string {
+	return fmt.Sprintf("%s%d", k.mode, k.count)
+}
+
+// apply marks dice as dropped according to the keep or drop rule.
+func (k *keep) apply(dice []Die) {
+	active := make([]int, 0, len(dice))
+	for i, d := range dice {
+		if !d.Dropped {
+			active = append(active, i)
+		}
+	}
+	// Sort indices from lowest to highest face, keeping roll order for ties
+	slices.SortStableFunc(active, func(a, b int) int {
+		return dice[a].Value - dice[b].Value
+	})
+
+	var drop []int
+	switch k.mode {
+	case "kh":
+		drop = active[:max(0, len(active)-k.count)]
+	case "kl":
+		drop = active[min(k.count, len(active)):]
+	case "dh":
+		drop = active[max(0, len(active)-k.count):]
+	case "dl":
+		drop = active[:min(k.count, len(active))]
+	}
+	for _, i := range drop {
+		dice[i].Dropped = true
+	}
+}
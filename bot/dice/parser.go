@@ -0,0 +1,366 @@
+package dice
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+ * Recursive descent parser producing an AST for dice expressions.
+ *
+ * Grammar:
+ *   expr    := term (('+' | '-') term)*
+ *   term    := unary (('*' | '/') unary)*
+ *   unary   := '-' unary | primary
+ *   primary := NUMBER | dice | '(' expr ')'
+ *   dice    := [NUMBER] 'd' (NUMBER | '%') modifier*
+ *   modifier:= ('kh' | 'kl' | 'k' | 'dh' | 'dl') [NUMBER]   keep or drop
+ *            | ('r' | 'ro') [cond]                       reroll, default ones
+ *            | '!' [cond]                                explode, default max
+ *            | COMPARE NUMBER                            count successes
+ *   cond    := [COMPARE] NUMBER
+ */
+
+const (
+	MaxDice   = 100       // Maximum number of dice in a single group (e.g. 100d6)
+	MaxSides  = 1000      // Maximum number of sides on a die
+	MaxNumber = 1_000_000 // Maximum value of a numeric literal
+	MaxTotal  = 500       // Maximum number of dice rolled by a whole expression
+	MaxResult = 1 << 40   // Maximum magnitude of a multiplication result
+)
+
+// Expr is a parsed dice expression, ready to be rolled any number of times.
+type Expr struct {
+	source string
+	root   node
+}
+
+func (e *Expr) String() string {
+	return e.root.String()
+}
+
+// Source returns the expression as it was originally written.
+func (e *Expr) Source() string {
+	return e.source
+}
+
+type node interface {
+	eval(ev *evaluator) (int, string, error) // value and a breakdown of how it was reached
+	String() string
+}
+
+type numberNode struct {
+	value int
+}
+
+func (n *numberNode) String() string {
+	return fmt.Sprintf("%d", n.value)
+}
+
+type diceNode struct {
+	count      int
+	sides      int
+	keep       *keep    // Keep or drop rule
+	explode    *compare // Faces that explode
+	reroll     *compare // Faces that are rerolled
+	rerollOnce bool     // Reroll at most once per die
+	success    *compare // Faces that count as a success
+}
+
+func (n *diceNode) String() string {
+	str := fmt.Sprintf("%dd%d", n.count, n.sides)
+	if n.sides == 100 {
+		str = fmt.Sprintf("%dd%%", n.count)
+	}
+	if n.reroll != nil {
+		mode := "r"
+		if n.rerollOnce {
+			mode = "ro"
+		}
+		str += mode + n.reroll.String()
+	}
+	if n.explode != nil {
+		str += "!"
+		if n.explode.op != "=" || n.explode.value != n.sides {
+			str += n.explode.String()
+		}
+	}
+	if n.keep != nil {
+		str += n.keep.String()
+	}
+	if n.success != nil {
+		str += n.success.op + fmt.Sprintf("%d", n.success.value)
+	}
+	return str
+}
+
+type binaryNode struct {
+	op    byte
+	left  node
+	right node
+}
+
+func (n *binaryNode) String() string {
+	return fmt.Sprintf("%s%c%s", n.left, n.op, n.right)
+}
+
+type groupNode struct {
+	inner node
+}
+
+func (n *groupNode) String() string {
+	return fmt.Sprintf("(%s)", n.inner)
+}
+
+type negateNode struct {
+	operand node
+}
+
+func (n *negateNode) String() string {
+	return fmt.Sprintf("-%s", n.operand)
+}
+
+type parser struct {
+	expr   string
+	tokens []token
+	pos    int
+	dice   int // Running count of dice in the expression, checked against MaxTotal
+}
+
+// Parse tokenizes and parses a dice expression.
+func Parse(expr string) (*Expr, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, &SyntaxError{Expr: expr, Pos: 0, Msg: "empty expression"}
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{expr: expr, tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+
+	return &Expr{source: expr, root: root}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	return &SyntaxError{Expr: p.expr, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokPlus && tok.kind != tokMinus {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text[0], left: left, right: right}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokStar && tok.kind != tokSlash {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text[0], left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokMinus {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		if p.peek().kind == tokDice {
+			return p.parseDice(tok)
+		}
+		return &numberNode{value: tok.value}, nil
+	case tokDice:
+		p.pos-- // Let parseDice consume the 'd'
+		return p.parseDice(token{kind: tokNumber, pos: tok.pos, value: 1})
+	case tokLParen:
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected %q but found %s", ")", closing)
+		}
+		return &groupNode{inner: inner}, nil
+	case tokEOF:
+		return nil, p.errorf(tok, "unexpected end of expression")
+	default:
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+}
+
+func (p *parser) parseDice(count token) (node, error) {
+	p.next() // the 'd'
+
+	var sides int
+	switch tok := p.next(); tok.kind {
+	case tokNumber:
+		sides = tok.value
+	case tokPercent:
+		sides = 100
+	default:
+		return nil, p.errorf(tok, "expected number of sides after %q but found %s", "d", tok)
+	}
+
+	if count.value < 1 || count.value > MaxDice {
+		return nil, p.errorf(count, "number of dice must be between 1 and %d", MaxDice)
+	}
+	if sides < 1 || sides > MaxSides {
+		return nil, p.errorf(count, "number of sides must be between 1 and %d", MaxSides)
+	}
+	if p.dice += count.value; p.dice > MaxTotal {
+		return nil, p.errorf(count, "expression rolls more than %d dice", MaxTotal)
+	}
+
+	n := &diceNode{count: count.value, sides: sides}
+	if err := p.parseModifiers(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseModifiers(n *diceNode) error {
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case tokModifier:
+			p.next()
+			switch tok.text {
+			case "r", "ro":
+				if n.reroll != nil {
+					return p.errorf(tok, "dice can only be rerolled once per term")
+				}
+				cond, err := p.parseCondition(&compare{op: "=", value: 1})
+				if err != nil {
+					return err
+				}
+				if cond.always(n.sides) {
+					return p.errorf(tok, "reroll %s would reroll every face of a d%d", cond, n.sides)
+				}
+				n.reroll, n.rerollOnce = cond, tok.text == "ro"
+			default:
+				if n.keep != nil {
+					return p.errorf(tok, "only one keep or drop modifier is allowed per term")
+				}
+				k := &keep{mode: tok.text, count: 1}
+				if k.mode == "k" {
+					k.mode = "kh"
+				}
+				if num := p.peek(); num.kind == tokNumber {
+					p.next()
+					k.count = num.value
+				}
+				if k.count < 1 || k.count > n.count {
+					return p.errorf(tok, "%s must keep or drop between 1 and %d dice", tok.text, n.count)
+				}
+				n.keep = k
+			}
+
+		case tokBang:
+			p.next()
+			if n.explode != nil {
+				return p.errorf(tok, "dice can only explode once per term")
+			}
+			cond, err := p.parseCondition(&compare{op: "=", value: n.sides})
+			if err != nil {
+				return err
+			}
+			if cond.always(n.sides) {
+				return p.errorf(tok, "explode %s would explode every face of a d%d", cond, n.sides)
+			}
+			n.explode = cond
+
+		case tokCompare:
+			if n.success != nil {
+				return p.errorf(tok, "only one success target is allowed per term")
+			}
+			cond, err := p.parseCondition(nil)
+			if err != nil {
+				return err
+			}
+			n.success = cond
+
+		default:
+			return nil
+		}
+	}
+}
+
+// parseCondition reads an optional comparison such as `>=7` or `1`. A bare
+// number means equality. When def is nil the condition is required.
+func (p *parser) parseCondition(def *compare) (*compare, error) {
+	cond := &compare{op: "="}
+	tok := p.peek()
+	if tok.kind == tokCompare {
+		p.next()
+		cond.op = tok.text
+		tok = p.peek()
+		if tok.kind != tokNumber {
+			return nil, p.errorf(tok, "expected a number after %q but found %s", cond.op, tok)
+		}
+	}
+	if tok.kind != tokNumber {
+		if def == nil {
+			return nil, p.errorf(tok, "expected a comparison but found %s", tok)
+		}
+		return def, nil
+	}
+	p.next()
+	cond.value = tok.value
+	return cond, nil
+}
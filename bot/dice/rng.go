@@ -0,0 +1,89 @@
+package dice
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+)
+
+/*
+ * Random sources for rolling dice
+ */
+
+// RNG is a source of uniformly distributed integers used to roll dice.
+type RNG interface {
+	// IntN returns a value in [0, n). It panics if n <= 0.
+	IntN(n int) int
+}
+
+// NewCryptoRNG returns an RNG backed by crypto/rand. It is safe for concurrent use.
+func NewCryptoRNG() RNG {
+	return rand.New(cryptoSource{})
+}
+
+// NewSeededRNG returns a deterministic RNG for reproducible rolls. It is safe for concurrent use.
+func NewSeededRNG(seed uint64) RNG {
+	return &lockedRNG{rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// NewFixedRNG returns an RNG that rolls the given die faces in order, cycling
+// when it runs out. A face larger than the die being rolled wraps around.
+func NewFixedRNG(faces ...int) RNG {
+	if len(faces) == 0 {
+		faces = []int{1}
+	}
+	return &fixedRNG{faces: faces}
+}
+
+type cryptoSource struct{}
+
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("dice: crypto/rand failed: " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+type lockedRNG struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (l *lockedRNG) IntN(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.IntN(n)
+}
+
+type fixedRNG struct {
+	mu    sync.Mutex
+	faces []int
+	next  int
+}
+
+func (f *fixedRNG) IntN(n int) int {
+	if n <= 0 {
+		panic("dice: invalid argument to IntN")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	face := f.faces[f.next%len(f.faces)]
+	f.next++
+	return ((face-1)%n + n) % n
+}
+
+func rollDie(rng RNG, sides int) int {
+	return rng.IntN(sides) + 1
+}
+
+// Tally rolls n dice of the given size and counts each face, so the RNG can
+// be checked for fairness. Counts[f-1] is how often face f came up.
+func Tally(rng RNG, sides, n int) []int {
+	counts := make([]int, sides)
+	for range n {
+		counts[rollDie(rng, sides)-1]++
+	}
+	return counts
+}
@@ -0,0 +1,57 @@
+package discordapi
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+/*
+ * The Discord operations the bot relies on. Handlers and commands talk to
+ * Discord through this interface rather than a concrete *discordgo.Session,
+ * so a recording fake can stand in for the real gateway in tests.
+ */
+
+type Session interface {
+	// Me returns the bot's own user.
+	Me() *discordgo.User
+	// Member returns a guild member from the cached state.
+	Member(guildID, userID string) (*discordgo.Member, error)
+	// Permissions returns a user's permissions in a channel from the cached state.
+	Permissions(userID, channelID string) (int64, error)
+
+	Guild(guildID string, options ...discordgo.RequestOption) (*discordgo.Guild, error)
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+
+	ChannelMessageSend(channelID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	InteractionResponseDelete(interaction *discordgo.Interaction, options ...discordgo.RequestOption) error
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+}
+
+// live adapts a connected discordgo session to Session.
+type live struct {
+	*discordgo.Session
+}
+
+// Wrap returns s as a Session.
+func Wrap(s *discordgo.Session) Session {
+	return live{s}
+}
+
+func (s live) Me() *discordgo.User {
+	return s.State.User
+}
+
+func (s live) Member(guildID, userID string) (*discordgo.Member, error) {
+	return s.State.Member(guildID, userID)
+}
+
+func (s live) Permissions(userID, channelID string) (int64, error) {
+	return s.State.UserChannelPermissions(userID, channelID)
+}
@@ -0,0 +1,61 @@
+package discordtest
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// redirect points discordgo's REST and gateway endpoints at base, which must
+// end in a slash, and returns a function that restores the originals. The
+// endpoint helpers build their URLs from these at call time.
+func redirect(base string) func() {
+	vars := []*string{
+		&discordgo.EndpointDiscord,
+		&discordgo.EndpointAPI,
+		&discordgo.EndpointGuilds,
+		&discordgo.EndpointChannels,
+		&discordgo.EndpointUsers,
+		&discordgo.EndpointGateway,
+		&discordgo.EndpointGatewayBot,
+		&discordgo.EndpointWebhooks,
+		&discordgo.EndpointStickers,
+		&discordgo.EndpointStageInstances,
+		&discordgo.EndpointSKUs,
+		&discordgo.EndpointVoice,
+		&discordgo.EndpointVoiceRegions,
+		&discordgo.EndpointNitroStickersPacks,
+		&discordgo.EndpointGuildCreate,
+		&discordgo.EndpointApplications,
+		&discordgo.EndpointOAuth2,
+		&discordgo.EndpointOAuth2Applications,
+	}
+	saved := make([]string, len(vars))
+	for i, v := range vars {
+		saved[i] = *v
+	}
+
+	api := base + "api/v" + discordgo.APIVersion + "/"
+	discordgo.EndpointDiscord = base
+	discordgo.EndpointAPI = api
+	discordgo.EndpointGuilds = api + "guilds/"
+	discordgo.EndpointChannels = api + "channels/"
+	discordgo.EndpointUsers = api + "users/"
+	discordgo.EndpointGateway = api + "gateway"
+	discordgo.EndpointGatewayBot = discordgo.EndpointGateway + "/bot"
+	discordgo.EndpointWebhooks = api + "webhooks/"
+	discordgo.EndpointStickers = api + "stickers/"
+	discordgo.EndpointStageInstances = api + "stage-instances"
+	discordgo.EndpointSKUs = api + "skus"
+	discordgo.EndpointVoice = api + "/voice/"
+	discordgo.EndpointVoiceRegions = discordgo.EndpointVoice + "regions"
+	discordgo.EndpointNitroStickersPacks = api + "/sticker-packs"
+	discordgo.EndpointGuildCreate = api + "guilds"
+	discordgo.EndpointApplications = api + "applications"
+	discordgo.EndpointOAuth2 = api + "oauth2/"
+	discordgo.EndpointOAuth2Applications = discordgo.EndpointOAuth2 + "applications"
+
+	return func() {
+		for i, v := range vars {
+			*v = saved[i]
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package discordtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/discordapi"
+)
+
+/*
+ * A recording stand-in for the Discord API. Everything the bot sends is kept
+ * in order so tests can assert on replies, and guilds, channels and members
+ * are served from whatever the test put in place.
+ */
+
+// Sent is one message the bot sent, edited or used to answer an interaction.
+type Sent struct {
+	Action      string // "send", "edit", "respond", "response-edit", "response-delete" or "followup"
+	ChannelID   string // Channel the message went to, empty for interaction responses
+	MessageID   string
+	Interaction string // ID of the interaction answered, if any
+	Content     string
+	Embeds      []*discordgo.MessageEmbed
+	Components  []discordgo.MessageComponent
+	Files       map[string]string // Attachment contents by file name
+	Ephemeral   bool
+	DM          bool // Sent to a direct message channel
+}
+
+type Fake struct {
+	User *discordgo.User // The bot's own user
+
+	mu       sync.Mutex
+	guilds   map[string]*discordgo.Guild
+	channels map[string]*discordgo.Channel
+	members  map[string]*discordgo.Member // Keyed by guild and user ID
+	perms    map[string]int64             // Bot permissions by channel, all when unset
+	failures map[string]int               // Sends still to fail, by channel
+	sent     []Sent
+	commands []*discordgo.ApplicationCommand
+	nextID   int
+}
+
+var _ discordapi.Session = (*Fake)(nil)
+
+var (
+	ErrUnknown = errors.New("unknown discord object")
+	ErrOutage  = errors.New("discord is unavailable") // Returned for sends set up to fail with FailSends
+)
+
+func NewFake() *Fake {
+	return &Fake{
+		User:     &discordgo.User{ID: "100", Username: "daggerbot", Bot: true},
+		guilds:   make(map[string]*discordgo.Guild),
+		channels: make(map[string]*discordgo.Channel),
+		members:  make(map[string]*discordgo.Member),
+		perms:    make(map[string]int64),
+		failures: make(map[string]int),
+		nextID:   1000,
+	}
+}
+
+// AddGuild makes a guild, and its channels and members, known to the fake.
+func (f *Fake) AddGuild(g *discordgo.Guild) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.guilds[g.ID] = g
+	for _, c := range g.Channels {
+		f.channels[c.ID] = c
+	}
+	for _, m := range g.Members {
+		f.members[memberKey(g.ID, m.User.ID)] = m
+	}
+}
+
+// AddChannel makes a channel known to the fake.
+func (f *Fake) AddChannel(c *discordgo.Channel) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels[c.ID] = c
+}
+
+// AddMember makes a guild member known to the fake.
+func (f *Fake) AddMember(guildID string, m *discordgo.Member) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m.GuildID = guildID
+	f.members[memberKey(guildID, m.User.ID)] = m
+}
+
+// SetPermissions limits the bot's permissions in a channel. Channels without
+// a limit grant everything.
+func (f *Fake) SetPermissions(channelID string, perms int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.perms[channelID] = perms
+}
+
+// FailSends makes the next n messages sent to a channel fail with ErrOutage.
+func (f *Fake) FailSends(channelID string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[channelID] = n
+}
+
+// Sent returns everything sent so far, oldest first.
+func (f *Fake) Sent() []Sent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.sent)
+}
+
+// Since returns everything sent after the first n messages.
+func (f *Fake) Since(n int) []Sent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if n >= len(f.sent) {
+		return nil
+	}
+	return slices.Clone(f.sent[n:])
+}
+
+// Reset forgets everything sent so far.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+}
+
+// Commands returns the slash commands last registered.
+func (f *Fake) Commands() []*discordgo.ApplicationCommand {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.commands)
+}
+
+/*
+ * discordapi.Session
+ */
+
+func (f *Fake) Me() *discordgo.User {
+	return f.User
+}
+
+func (f *Fake) Member(guildID, userID string) (*discordgo.Member, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m, ok := f.members[memberKey(guildID, userID)]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("%w member %s in guild %s", ErrUnknown, userID, guildID)
+}
+
+// Permissions returns what SetPermissions allowed in the channel. Every user
+// shares the bot's permissions.
+func (f *Fake) Permissions(_, channelID string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if perms, ok := f.perms[channelID]; ok {
+		return perms, nil
+	}
+	return discordgo.PermissionAll, nil
+}
+
+func (f *Fake) Guild(guildID string, _ ...discordgo.RequestOption) (*discordgo.Guild, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if g, ok := f.guilds[guildID]; ok {
+		return g, nil
+	}
+	return nil, fmt.Errorf("%w guild %s", ErrUnknown, guildID)
+}
+
+func (f *Fake) Channel(channelID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.channels[channelID]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w channel %s", ErrUnknown, channelID)
+}
+
+// UserChannelCreate opens a direct message channel, with the ID "dm-<user>".
+func (f *Fake) UserChannelCreate(recipientID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := "dm-" + recipientID
+	c, ok := f.channels[id]
+	if !ok {
+		c = &discordgo.Channel{
+			ID:         id,
+			Type:       discordgo.ChannelTypeDM,
+			Recipients: []*discordgo.User{{ID: recipientID}},
+		}
+		f.channels[id] = c
+	}
+	return c, nil
+}
+
+func (f *Fake) ChannelMessageSend(channelID, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return f.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content})
+}
+
+func (f *Fake) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.channels[channelID]; !ok {
+		return nil, fmt.Errorf("%w channel %s", ErrUnknown, channelID)
+	}
+	if f.failures[channelID] > 0 {
+		f.failures[channelID]--
+		return nil, fmt.Errorf("sending to channel %s: %w", channelID, ErrOutage)
+	}
+	files, err := readFiles(data.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	embeds := data.Embeds
+	if data.Embed != nil {
+		embeds = append([]*discordgo.MessageEmbed{data.Embed}, embeds...)
+	}
+	sent := f.record(Sent{
+		Action:     "send",
+		ChannelID:  channelID,
+		Content:    data.Content,
+		Embeds:     embeds,
+		Components: data.Components,
+		Files:      files,
+	})
+	return f.message(sent), nil
+}
+
+func (f *Fake) ChannelMessageEditComplex(m *discordgo.MessageEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := Sent{Action: "edit", ChannelID: m.Channel, MessageID: m.ID}
+	if m.Content != nil {
+		sent.Content = *m.Content
+	}
+	if m.Embeds != nil {
+		sent.Embeds = *m.Embeds
+	}
+	if m.Components != nil {
+		sent.Components = *m.Components
+	}
+	sent = f.record(sent)
+	return f.message(sent), nil
+}
+
+func (f *Fake) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := Sent{Action: "respond", Interaction: interaction.ID}
+	if resp.Data != nil {
+		sent.Content = resp.Data.Content
+		sent.Embeds = resp.Data.Embeds
+		sent.Components = resp.Data.Components
+		sent.Ephemeral = resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+	}
+	f.record(sent)
+	return nil
+}
+
+func (f *Fake) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := Sent{Action: "response-edit", Interaction: interaction.ID, ChannelID: interaction.ChannelID}
+	if newresp.Content != nil {
+		sent.Content = *newresp.Content
+	}
+	if newresp.Embeds != nil {
+		sent.Embeds = *newresp.Embeds
+	}
+	if newresp.Components != nil {
+		sent.Components = *newresp.Components
+	}
+	files, err := readFiles(newresp.Files)
+	if err != nil {
+		return nil, err
+	}
+	sent.Files = files
+	sent = f.record(sent)
+	return f.message(sent), nil
+}
+
+func (f *Fake) InteractionResponseDelete(interaction *discordgo.Interaction, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(Sent{Action: "response-delete", Interaction: interaction.ID, ChannelID: interaction.ChannelID})
+	return nil
+}
+
+func (f *Fake) FollowupMessageCreate(interaction *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	files, err := readFiles(data.Files)
+	if err != nil {
+		return nil, err
+	}
+	sent := f.record(Sent{
+		Action:      "followup",
+		Interaction: interaction.ID,
+		ChannelID:   interaction.ChannelID,
+		Content:     data.Content,
+		Embeds:      data.Embeds,
+		Components:  data.Components,
+		Files:       files,
+		Ephemeral:   data.Flags&discordgo.MessageFlagsEphemeral != 0,
+	})
+	return f.message(sent), nil
+}
+
+func (f *Fake) ApplicationCommandBulkOverwrite(_ string, _ string, commands []*discordgo.ApplicationCommand, _ ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = slices.Clone(commands)
+	return commands, nil
+}
+
+/*
+ * Private helpers, callers must hold the lock
+ */
+
+// record assigns the message an ID if it needs one and appends it to the log.
+func (f *Fake) record(s Sent) Sent {
+	if s.MessageID == "" && s.Action != "respond" && s.Action != "response-delete" {
+		f.nextID++
+		s.MessageID = fmt.Sprint(f.nextID)
+	}
+	if c, ok := f.channels[s.ChannelID]; ok && c.Type == discordgo.ChannelTypeDM {
+		s.DM = true
+	}
+	f.sent = append(f.sent, s)
+	return s
+}
+
+func (f *Fake) message(s Sent) *discordgo.Message {
+	return &discordgo.Message{
+		ID:         s.MessageID,
+		ChannelID:  s.ChannelID,
+		Content:    s.Content,
+		Embeds:     s.Embeds,
+		Components: s.Components,
+		Author:     f.User,
+	}
+}
+
+func memberKey(guildID, userID string) string {
+	return guildID + ":" + userID
+}
+
+func readFiles(files []*discordgo.File) (map[string]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	contents := make(map[string]string, len(files))
+	for _, file := range files {
+		var b strings.Builder
+		if _, err := io.Copy(&b, file.Reader); err != nil {
+			return nil, fmt.Errorf("reading attachment %s: %w", file.Name, err)
+		}
+		contents[file.Name] = b.String()
+	}
+	return contents, nil
+}
@@ -0,0 +1,365 @@
+package discordtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+/*
+ * The gateway half of the stand-in. It says hello, answers identify with
+ * READY and a GUILD_CREATE per guild, acknowledges heartbeats and replays
+ * missed events on resume. Every dispatched event is numbered and kept, so a
+ * bot that reconnects picks up where it left off.
+ */
+
+const maxEvents = 1000 // Dispatched events kept for resuming
+
+type frame struct {
+	Op int    `json:"op"`
+	D  any    `json:"d"`
+	S  int64  `json:"s,omitempty"`
+	T  string `json:"t,omitempty"`
+}
+
+type gatewayState struct {
+	sessions   map[*gatewayConn]string // Open connections, by the session they identified or resumed
+	live       map[string]bool         // Sessions that can be resumed
+	seq        int64
+	events     []event
+	identifies int
+	resumes    int
+}
+
+// event is a dispatched frame, kept with the sessions it was sent on.
+type event struct {
+	frame
+	to map[string]bool
+}
+
+type gatewayConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (gc *gatewayConn) write(f frame) error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.conn.WriteJSON(f)
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+// Dispatch sends an event to every connected session and keeps it for resumes.
+func (s *Server) Dispatch(event string, data any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch(event, data, nil)
+}
+
+// GuildCreate adds a guild and tells the bot it has joined it.
+func (s *Server) GuildCreate(g *discordgo.Guild) {
+	s.AddGuild(g)
+	s.Dispatch("GUILD_CREATE", g)
+}
+
+// SendMessage posts content in a channel as the user, the way a MESSAGE_CREATE arrives from Discord.
+func (s *Server) SendMessage(channelID string, author *discordgo.User, content string) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, ok := s.channels[channelID]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %s", channelID)
+	}
+
+	s.nextID++
+	m := &discordgo.Message{
+		ID:        fmt.Sprint(s.nextID),
+		ChannelID: channelID,
+		GuildID:   channel.GuildID,
+		Content:   content,
+		Author:    author,
+		Timestamp: time.Now(),
+		Mentions:  s.mentions(channel.GuildID, content),
+	}
+	if g, ok := s.guilds[channel.GuildID]; ok {
+		m.Member = &discordgo.Member{}
+		for _, member := range g.Members {
+			if member.User != nil && member.User.ID == author.ID {
+				m.Member.Roles = member.Roles
+				m.Member.Nick = member.Nick
+			}
+		}
+	}
+
+	s.dispatch("MESSAGE_CREATE", m, nil)
+	return m, nil
+}
+
+// Interact sends an interaction, such as a button press, to the bot. Its ID,
+// token and application are filled in if unset, and replies to it are recorded
+// against it.
+func (s *Server) Interact(i *discordgo.Interaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i.ID == "" {
+		s.nextID++
+		i.ID = fmt.Sprint(s.nextID)
+	}
+	if i.Token == "" {
+		i.Token = "token-" + i.ID
+	}
+	if i.AppID == "" {
+		i.AppID = s.User.ID
+	}
+	i.Version = 1
+	s.interactions[i.Token] = i
+	s.dispatch("INTERACTION_CREATE", i, nil)
+}
+
+// Disconnect drops every gateway connection without a close frame, as a network failure would.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for gc := range s.gateway.sessions {
+		_ = gc.conn.Close()
+		delete(s.gateway.sessions, gc)
+	}
+	s.notify()
+}
+
+// RequestReconnect asks every connected session to reconnect and resume, as Discord does before maintenance.
+func (s *Server) RequestReconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for gc := range s.gateway.sessions {
+		if err := gc.write(frame{Op: 7}); err != nil {
+			log.Printf("[ERROR] Stand-in gateway failed to request a reconnect: %v", err)
+		}
+	}
+}
+
+// Connections returns how many gateway connections are open.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.gateway.sessions)
+}
+
+// Identifies returns how many times the bot has identified.
+func (s *Server) Identifies() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gateway.identifies
+}
+
+// Resumes returns how many times the bot has resumed its session.
+func (s *Server) Resumes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gateway.resumes
+}
+
+// WaitReady blocks until the bot has identified or resumed n times in all.
+func (s *Server) WaitReady(ctx context.Context, n int) error {
+	for {
+		s.mu.Lock()
+		ready := s.gateway.identifies + s.gateway.resumes
+		changed := s.changed
+		s.mu.Unlock()
+
+		if ready >= n {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the bot to connect: %w", ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+func (s *Server) serveGateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader has already answered
+	}
+	gc := &gatewayConn{conn: conn}
+
+	s.mu.Lock()
+	s.gateway.sessions[gc] = ""
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.gateway.sessions, gc)
+		s.notify()
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	if err := gc.write(frame{Op: 10, D: map[string]any{"heartbeat_interval": s.Heartbeat.Milliseconds()}}); err != nil {
+		return
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var f struct {
+			Op int             `json:"op"`
+			D  json.RawMessage `json:"d"`
+		}
+		if err := json.Unmarshal(data, &f); err != nil {
+			return
+		}
+
+		switch f.Op {
+		case 1: // Heartbeat
+			if err := gc.write(frame{Op: 11}); err != nil {
+				return
+			}
+		case 2: // Identify
+			if !s.identify(gc, f.D) {
+				return
+			}
+		case 6: // Resume
+			s.resume(gc, f.D)
+		}
+	}
+}
+
+// identify starts a new session on gc, returning false if the token was refused.
+func (s *Server) identify(gc *gatewayConn, data json.RawMessage) bool {
+	var identify struct {
+		Token string `json:"token"`
+	}
+	_ = json.Unmarshal(data, &identify)
+
+	if s.Token != "" && strings.TrimPrefix(identify.Token, "Bot ") != strings.TrimPrefix(s.Token, "Bot ") {
+		gc.mu.Lock()
+		_ = gc.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4004, "Authentication failed."))
+		gc.mu.Unlock()
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gateway.identifies++
+	sessionID := fmt.Sprintf("session-%d", s.gateway.identifies)
+	s.gateway.sessions[gc] = sessionID
+	s.gateway.live[sessionID] = true
+
+	unavailable := make([]*discordgo.Guild, 0, len(s.guilds))
+	for id := range s.guilds {
+		unavailable = append(unavailable, &discordgo.Guild{ID: id, Unavailable: true})
+	}
+	s.dispatch("READY", &discordgo.Ready{
+		Version:     9,
+		SessionID:   sessionID,
+		User:        s.User,
+		Application: &discordgo.Application{ID: s.User.ID},
+		Guilds:      unavailable,
+	}, gc)
+	for _, g := range s.guilds {
+		s.dispatch("GUILD_CREATE", g, gc)
+	}
+	s.notify()
+	return true
+}
+
+// resume replays the events the session missed, or invalidates a session the server doesn't know.
+func (s *Server) resume(gc *gatewayConn, data json.RawMessage) {
+	var resume struct {
+		SessionID string `json:"session_id"`
+		Seq       int64  `json:"seq"`
+	}
+	_ = json.Unmarshal(data, &resume)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.gateway.live[resume.SessionID] {
+		_ = gc.write(frame{Op: 9, D: false}) // The bot identifies again
+		return
+	}
+
+	s.gateway.resumes++
+	s.gateway.sessions[gc] = resume.SessionID
+	for _, e := range s.gateway.events {
+		if e.S > resume.Seq && e.to[resume.SessionID] {
+			if err := gc.write(e.frame); err != nil {
+				return
+			}
+		}
+	}
+	s.dispatch("RESUMED", map[string]any{}, gc)
+	s.notify()
+}
+
+/*
+ * Private helpers, callers must hold the lock
+ */
+
+// dispatch numbers and keeps an event, then sends it on only's session, or
+// every live session if only is nil. Sessions with no connection get it when they resume.
+func (s *Server) dispatch(name string, data any, only *gatewayConn) {
+	s.gateway.seq++
+	e := event{frame: frame{Op: 0, T: name, S: s.gateway.seq, D: data}, to: make(map[string]bool)}
+	if only != nil {
+		e.to[s.gateway.sessions[only]] = true
+	} else {
+		for sessionID := range s.gateway.live {
+			e.to[sessionID] = true
+		}
+	}
+	s.gateway.events = append(s.gateway.events, e)
+	if len(s.gateway.events) > maxEvents {
+		s.gateway.events = s.gateway.events[len(s.gateway.events)-maxEvents:]
+	}
+
+	for gc, sessionID := range s.gateway.sessions {
+		if sessionID == "" || !e.to[sessionID] {
+			continue
+		}
+		if err := gc.write(e.frame); err != nil {
+			log.Printf("[ERROR] Stand-in gateway failed to send %s: %v", name, err)
+		}
+	}
+}
+
+var serverMentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+
+// mentions resolves the <@id> tags in content to the bot or members of the guild.
+func (s *Server) mentions(guildID, content string) []*discordgo.User {
+	users := make([]*discordgo.User, 0)
+	for _, match := range serverMentionPattern.FindAllStringSubmatch(content, -1) {
+		if match[1] == s.User.ID {
+			users = append(users, s.User)
+			continue
+		}
+		if g, ok := s.guilds[guildID]; ok {
+			for _, member := range g.Members {
+				if member.User != nil && member.User.ID == match[1] {
+					users = append(users, member.User)
+				}
+			}
+		}
+	}
+	return users
+}
@@ -0,0 +1,108 @@
+package discordtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// dial opens a gateway connection and reads the hello.
+func dial(t *testing.T, srv *Server) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.http.URL, "http")+"/gateway", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	if f := read(t, conn); f.Op != 10 {
+		t.Fatalf("first frame is op %d, want hello", f.Op)
+	}
+	return conn
+}
+
+type received struct {
+	Op int    `json:"op"`
+	S  int64  `json:"s"`
+	T  string `json:"t"`
+}
+
+func read(t *testing.T, conn *websocket.Conn) received {
+	t.Helper()
+
+	var f received
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readUntil reads dispatches up to and including the event named last.
+func readUntil(t *testing.T, conn *websocket.Conn, last string) []received {
+	t.Helper()
+
+	var frames []received
+	for {
+		f := read(t, conn)
+		frames = append(frames, f)
+		if f.T == last {
+			return frames
+		}
+	}
+}
+
+func TestResumeReplaysOnlyItsSession(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddGuild(&discordgo.Guild{ID: "200", Name: "Harness"})
+
+	first := dial(t, srv)
+	if err := first.WriteJSON(frame{Op: 2, D: map[string]any{"token": "Bot token"}}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, first, "GUILD_CREATE")
+
+	// A second session, whose READY and GUILD_CREATE are its own
+	second := dial(t, srv)
+	if err := second.WriteJSON(frame{Op: 2, D: map[string]any{"token": "Bot token"}}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, second, "GUILD_CREATE")
+
+	_ = first.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for srv.Connections() != 1 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("the server kept the closed connection")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	srv.Dispatch("TYPING_START", map[string]any{"channel_id": "300"}) // Missed by the first session
+
+	resumed := dial(t, srv)
+	if err := resumed.WriteJSON(frame{Op: 6, D: map[string]any{"token": "Bot token", "session_id": "session-1", "seq": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	frames := readUntil(t, resumed, "RESUMED")
+	if len(frames) != 2 || frames[0].T != "TYPING_START" || frames[0].S != 5 {
+		t.Errorf("resuming replayed %+v, want only the TYPING_START it missed", frames)
+	}
+	if srv.Resumes() != 1 {
+		t.Errorf("Resumes() = %d, want 1", srv.Resumes())
+	}
+
+	unknown := dial(t, srv)
+	if err := unknown.WriteJSON(frame{Op: 6, D: map[string]any{"token": "Bot token", "session_id": "session-9", "seq": 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if f := read(t, unknown); f.Op != 9 {
+		t.Errorf("resuming an unknown session got op %d, want an invalid session", f.Op)
+	}
+}
@@ -0,0 +1,245 @@
+package discordtest
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/bot/handlers"
+	"github.com/nerdwerx/daggerbot/bot/scheduler"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+/*
+ * An end-to-end harness. It registers a guild backed by in-memory storage and
+ * feeds synthetic messages and button presses through the real handlers, so
+ * a test reads like a conversation:
+ *
+ *	h, err := discordtest.NewHarness()
+ *	defer h.Close()
+ *	h.AddMember("1", "Mira")
+ *	replies := h.Say("1", "!roll 2d6")
+ *
+ * The harness swaps out config.Storage and config.Guilds while it is open, so
+ * harnesses must not run in parallel. A single harness may be driven from
+ * many goroutines once its members and channels are added.
+ */
+
+const (
+	GuildID   = "200" // ID of the harness guild
+	ChannelID = "300" // ID of the harness guild's text channel
+	OwnerID   = "400" // ID of the harness guild's owner
+)
+
+var mentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+
+type Harness struct {
+	Fake      *Fake
+	Guild     *config.Guild
+	Store     *config.MemoryStore
+	RNG       dice.RNG             // Rolls the dice for every command, seeded so runs repeat
+	Scheduler *scheduler.Scheduler // Runs session reminders, nil to skip them
+
+	guild         *discordgo.Guild
+	savedStorage  config.Store
+	savedRegistry *config.Registry
+	mu            sync.Mutex
+	nextID        int
+}
+
+// NewHarness registers the harness guild, with its owner as the only member.
+func NewHarness() (*Harness, error) {
+	h := &Harness{
+		Fake:          NewFake(),
+		Store:         config.NewMemoryStore(),
+		RNG:           dice.NewSeededRNG(1),
+		savedStorage:  config.Storage,
+		savedRegistry: config.Guilds,
+		nextID:        5000,
+	}
+	config.Storage = h.Store
+	config.Guilds = config.NewRegistry()
+
+	h.guild = &discordgo.Guild{
+		ID:      GuildID,
+		Name:    "Harness",
+		OwnerID: OwnerID,
+		Roles:   []*discordgo.Role{{ID: GuildID, Name: "@everyone"}},
+		Channels: []*discordgo.Channel{
+			{ID: ChannelID, GuildID: GuildID, Name: "general", Type: discordgo.ChannelTypeGuildText},
+		},
+	}
+	h.Fake.AddGuild(h.guild)
+	h.AddMember(OwnerID, "Owner")
+
+	guild, err := config.Guilds.Register(h.guild)
+	if err != nil {
+		h.Close()
+		return nil, fmt.Errorf("registering harness guild: %w", err)
+	}
+	h.Guild = guild
+	return h, nil
+}
+
+// Close puts back the storage and guild registry the harness replaced.
+func (h *Harness) Close() {
+	config.Storage = h.savedStorage
+	config.Guilds = h.savedRegistry
+}
+
+// AddRole creates a guild role, for use with AddMember and `!config set`.
+func (h *Harness) AddRole(name string) *discordgo.Role {
+	role := &discordgo.Role{ID: h.id(), Name: name}
+	h.guild.Roles = append(h.guild.Roles, role)
+	h.Guild.SetRole(role)
+	return role
+}
+
+// AddChannel creates another text channel in the guild and returns its ID.
+func (h *Harness) AddChannel(name string) string {
+	channel := &discordgo.Channel{ID: h.id(), GuildID: GuildID, Name: name, Type: discordgo.ChannelTypeGuildText}
+	h.guild.Channels = append(h.guild.Channels, channel)
+	h.Fake.AddChannel(channel)
+	return channel.ID
+}
+
+// AddMember adds a member to the guild with the given role IDs.
+func (h *Harness) AddMember(userID, name string, roles ...string) *discordgo.Member {
+	member := &discordgo.Member{
+		GuildID: GuildID,
+		User:    &discordgo.User{ID: userID, Username: name, GlobalName: name, Discriminator: "0"},
+		Roles:   roles,
+	}
+	h.Fake.AddMember(GuildID, member)
+	return member
+}
+
+// Say posts content in the harness channel as the member and returns what the bot sent in response.
+func (h *Harness) Say(userID, content string) []Sent {
+	before := len(h.Fake.Sent())
+	h.SayIn(ChannelID, userID, content)
+	return h.Fake.Since(before)
+}
+
+// SayIn posts content in a channel as the member. It returns nothing, since
+// other goroutines may be talking at the same time; read Fake.Sent instead.
+func (h *Harness) SayIn(channelID, userID, content string) {
+	member, err := h.Fake.Member(GuildID, userID)
+	if err != nil {
+		member = h.AddMember(userID, "user"+userID)
+	}
+
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        h.id(),
+		GuildID:   GuildID,
+		ChannelID: channelID,
+		Content:   content,
+		Author:    member.User,
+		Member:    &discordgo.Member{GuildID: GuildID, User: member.User, Roles: member.Roles},
+		Mentions:  h.mentions(content),
+	}}
+
+	handlers.HandleMessage(h.Fake, h.RNG, h.Scheduler, m)
+}
+
+// Message feeds a raw message event through the handlers and returns what the bot sent in response.
+func (h *Harness) Message(m *discordgo.MessageCreate) []Sent {
+	before := len(h.Fake.Sent())
+	handlers.HandleMessage(h.Fake, h.RNG, h.Scheduler, m)
+	return h.Fake.Since(before)
+}
+
+// Interaction feeds a raw interaction event through the handlers and returns what the bot sent in response.
+func (h *Harness) Interaction(i *discordgo.InteractionCreate) []Sent {
+	before := len(h.Fake.Sent())
+	handlers.HandleInteraction(h.Fake, h.RNG, h.Scheduler, i)
+	return h.Fake.Since(before)
+}
+
+// Slash runs the slash command name with options as the member and returns what
+// the bot sent in response. User options are resolved to known members, as Discord does.
+func (h *Harness) Slash(userID, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) []Sent {
+	member, err := h.Fake.Member(GuildID, userID)
+	if err != nil {
+		member = h.AddMember(userID, "user"+userID)
+	}
+
+	return h.Interaction(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        h.id(),
+		Type:      discordgo.InteractionApplicationCommand,
+		GuildID:   GuildID,
+		ChannelID: ChannelID,
+		Member:    &discordgo.Member{GuildID: GuildID, User: member.User, Roles: member.Roles},
+		Data: discordgo.ApplicationCommandInteractionData{
+			ID:       h.id(),
+			Name:     name,
+			Options:  options,
+			Resolved: h.resolve(options),
+		},
+	}})
+}
+
+// Press clicks the button with customID as the member and returns what the bot sent in response.
+func (h *Harness) Press(userID, messageID, customID string) []Sent {
+	member, err := h.Fake.Member(GuildID, userID)
+	if err != nil {
+		member = h.AddMember(userID, "user"+userID)
+	}
+
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        h.id(),
+		Type:      discordgo.InteractionMessageComponent,
+		GuildID:   GuildID,
+		ChannelID: ChannelID,
+		Member:    &discordgo.Member{GuildID: GuildID, User: member.User, Roles: member.Roles},
+		Message:   &discordgo.Message{ID: messageID, ChannelID: ChannelID},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      customID,
+			ComponentType: discordgo.ButtonComponent,
+		},
+	}}
+
+	return h.Interaction(i)
+}
+
+// resolve looks up the users named in user options, however deeply nested.
+func (h *Harness) resolve(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataResolved {
+	resolved := &discordgo.ApplicationCommandInteractionDataResolved{Users: make(map[string]*discordgo.User)}
+	var walk func([]*discordgo.ApplicationCommandInteractionDataOption)
+	walk = func(options []*discordgo.ApplicationCommandInteractionDataOption) {
+		for _, opt := range options {
+			if opt.Type == discordgo.ApplicationCommandOptionUser {
+				if member, err := h.Fake.Member(GuildID, fmt.Sprint(opt.Value)); err == nil {
+					resolved.Users[member.User.ID] = member.User
+				}
+			}
+			walk(opt.Options)
+		}
+	}
+	walk(options)
+	return resolved
+}
+
+// mentions resolves the <@id> tags in content to known users.
+func (h *Harness) mentions(content string) []*discordgo.User {
+	users := make([]*discordgo.User, 0)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if match[1] == h.Fake.User.ID {
+			users = append(users, h.Fake.User)
+			continue
+		}
+		if member, err := h.Fake.Member(GuildID, match[1]); err == nil {
+			users = append(users, member.User)
+		}
+	}
+	return users
+}
+
+func (h *Harness) id() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	return fmt.Sprint(h.nextID)
+}
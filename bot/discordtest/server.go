@@ -0,0 +1,447 @@
+package discordtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+/*
+ * A local stand-in for Discord. It serves enough of the REST API and the
+ * gateway for discordgo to connect, receive READY, GUILD_CREATE and
+ * MESSAGE_CREATE, and post messages, so the whole bot can run offline:
+ *
+ *	srv := discordtest.NewServer()
+ *	defer srv.Close()
+ *	srv.AddGuild(guild)
+ *	restore := srv.Redirect() // Points discordgo at the server
+ *	defer restore()
+ *
+ * Everything the bot sends is recorded as Sent, the same as the fake.
+ */
+
+const DefaultHeartbeat = 41250 * time.Millisecond // Interval Discord usually asks for
+
+type Server struct {
+	URL       string          // Base URL, in the form of discordgo.EndpointDiscord
+	Token     string          // Token to accept at identify, empty to accept any
+	User      *discordgo.User // The bot's own user
+	Heartbeat time.Duration   // Interval sent in the gateway hello
+
+	http *httptest.Server
+
+	mu           sync.Mutex
+	guilds       map[string]*discordgo.Guild
+	channels     map[string]*discordgo.Channel
+	interactions map[string]*discordgo.Interaction // By token, for recording replies
+	sent         []Sent
+	commands     []*discordgo.ApplicationCommand
+	changed      chan struct{} // Closed and replaced whenever something is recorded
+	nextID       int
+
+	gateway gatewayState
+}
+
+func NewServer() *Server {
+	s := &Server{
+		User:         &discordgo.User{ID: "100", Username: "daggerbot", Discriminator: "0", Bot: true},
+		Heartbeat:    DefaultHeartbeat,
+		guilds:       make(map[string]*discordgo.Guild),
+		channels:     make(map[string]*discordgo.Channel),
+		interactions: make(map[string]*discordgo.Interaction),
+		changed:      make(chan struct{}),
+		nextID:       9000,
+	}
+	s.gateway.sessions = make(map[*gatewayConn]string)
+	s.gateway.live = make(map[string]bool)
+
+	api := "/api/v" + discordgo.APIVersion
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+api+"/gateway", s.serveGatewayURL)
+	mux.HandleFunc("GET "+api+"/gateway/bot", s.serveGatewayURL)
+	mux.HandleFunc("GET /gateway", s.serveGateway)
+	mux.HandleFunc("GET /gateway/{$}", s.serveGateway) // discordgo adds a trailing slash
+	mux.HandleFunc("GET "+api+"/guilds/{guild}", s.serveGuild)
+	mux.HandleFunc("GET "+api+"/channels/{channel}", s.serveChannel)
+	mux.HandleFunc("POST "+api+"/channels/{channel}/messages", s.serveMessageCreate)
+	mux.HandleFunc("PATCH "+api+"/channels/{channel}/messages/{message}", s.serveMessageEdit)
+	mux.HandleFunc("POST "+api+"/users/@me/channels", s.serveUserChannel)
+	mux.HandleFunc("PUT "+api+"/applications/{app}/commands", s.serveCommands)
+	mux.HandleFunc("POST "+api+"/interactions/{interaction}/{token}/callback", s.serveInteractionResponse)
+	mux.HandleFunc("PATCH "+api+"/webhooks/{app}/{token}/messages/@original", s.serveResponseEdit)
+	mux.HandleFunc("DELETE "+api+"/webhooks/{app}/{token}/messages/@original", s.serveResponseDelete)
+	mux.HandleFunc("POST "+api+"/webhooks/{app}/{token}", s.serveFollowup)
+	mux.HandleFunc("/", s.serveUnknown)
+
+	s.http = httptest.NewServer(mux)
+	s.URL = s.http.URL + "/"
+	return s
+}
+
+// Close disconnects every gateway session and shuts the server down.
+func (s *Server) Close() {
+	s.Disconnect()
+	s.http.Close()
+}
+
+// Redirect points discordgo's endpoints at the server and returns a function
+// that puts them back. Only restore them once every session using the server
+// has been closed, as discordgo reads them from its own goroutines.
+func (s *Server) Redirect() (restore func()) {
+	return redirect(s.URL)
+}
+
+// AddGuild makes a guild, and its channels, known to the server. It is sent
+// to the bot at its next identify, or straight away with GuildCreate.
+func (s *Server) AddGuild(g *discordgo.Guild) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.guilds[g.ID] = g
+	for _, c := range g.Channels {
+		c.GuildID = g.ID
+		s.channels[c.ID] = c
+	}
+}
+
+// Sent returns everything the bot has sent, oldest first.
+func (s *Server) Sent() []Sent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.sent)
+}
+
+// Commands returns the slash commands the bot last registered.
+func (s *Server) Commands() []*discordgo.ApplicationCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+// WaitSent blocks until the bot sends something that match accepts, returning it.
+// Only messages sent after the first skip are considered.
+func (s *Server) WaitSent(ctx context.Context, skip int, match func(Sent) bool) (Sent, error) {
+	for {
+		s.mu.Lock()
+		for _, sent := range s.sent[min(skip, len(s.sent)):] {
+			if match == nil || match(sent) {
+				s.mu.Unlock()
+				return sent, nil
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Sent{}, fmt.Errorf("waiting for the bot to send a message: %w", ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+/*
+ * REST handlers
+ */
+
+func (s *Server) serveGatewayURL(w http.ResponseWriter, r *http.Request) {
+	url := "ws" + strings.TrimPrefix(s.http.URL, "http") + "/gateway"
+	writeJSON(w, http.StatusOK, map[string]any{"url": url, "shards": 1})
+}
+
+func (s *Server) serveGuild(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	g, ok := s.guilds[r.PathValue("guild")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, 10004, "Unknown Guild")
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (s *Server) serveChannel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c, ok := s.channels[r.PathValue("channel")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, 10003, "Unknown Channel")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) serveMessageCreate(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.MessageSend
+	files, err := readBody(r, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 50035, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channelID := r.PathValue("channel")
+	if _, ok := s.channels[channelID]; !ok {
+		writeError(w, http.StatusNotFound, 10003, "Unknown Channel")
+		return
+	}
+	sent := s.record(Sent{
+		Action:     "send",
+		ChannelID:  channelID,
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+		Files:      files,
+	})
+	writeJSON(w, http.StatusOK, s.message(sent))
+}
+
+func (s *Server) serveMessageEdit(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.MessageEdit
+	if _, err := readBody(r, &data); err != nil {
+		writeError(w, http.StatusBadRequest, 50035, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := Sent{Action: "edit", ChannelID: r.PathValue("channel"), MessageID: r.PathValue("message")}
+	if data.Content != nil {
+		sent.Content = *data.Content
+	}
+	if data.Embeds != nil {
+		sent.Embeds = *data.Embeds
+	}
+	if data.Components != nil {
+		sent.Components = *data.Components
+	}
+	writeJSON(w, http.StatusOK, s.message(s.record(sent)))
+}
+
+func (s *Server) serveUserChannel(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		RecipientID string `json:"recipient_id"`
+	}
+	if _, err := readBody(r, &data); err != nil || data.RecipientID == "" {
+		writeError(w, http.StatusBadRequest, 50035, "recipient_id is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := "dm-" + data.RecipientID
+	c, ok := s.channels[id]
+	if !ok {
+		c = &discordgo.Channel{
+			ID:         id,
+			Type:       discordgo.ChannelTypeDM,
+			Recipients: []*discordgo.User{{ID: data.RecipientID}},
+		}
+		s.channels[id] = c
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) serveCommands(w http.ResponseWriter, r *http.Request) {
+	var commands []*discordgo.ApplicationCommand
+	if _, err := readBody(r, &commands); err != nil {
+		writeError(w, http.StatusBadRequest, 50035, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cmd := range commands {
+		s.nextID++
+		cmd.ID = fmt.Sprint(s.nextID)
+		cmd.ApplicationID = r.PathValue("app")
+	}
+	s.commands = commands
+	s.notify()
+	writeJSON(w, http.StatusOK, commands)
+}
+
+func (s *Server) serveInteractionResponse(w http.ResponseWriter, r *http.Request) {
+	var resp discordgo.InteractionResponse
+	if _, err := readBody(r, &resp); err != nil {
+		writeError(w, http.StatusBadRequest, 50035, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := Sent{Action: "respond", Interaction: r.PathValue("interaction")}
+	if i, ok := s.interactions[r.PathValue("token")]; ok {
+		sent.ChannelID = i.ChannelID
+	}
+	if resp.Data != nil {
+		sent.Content = resp.Data.Content
+		sent.Embeds = resp.Data.Embeds
+		sent.Components = resp.Data.Components
+		sent.Ephemeral = resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+	}
+	s.record(sent)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveResponseEdit(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.WebhookEdit
+	files, err := readBody(r, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 50035, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := s.interactionSent("response-edit", r.PathValue("token"))
+	if data.Content != nil {
+		sent.Content = *data.Content
+	}
+	if data.Embeds != nil {
+		sent.Embeds = *data.Embeds
+	}
+	if data.Components != nil {
+		sent.Components = *data.Components
+	}
+	sent.Files = files
+	writeJSON(w, http.StatusOK, s.message(s.record(sent)))
+}
+
+func (s *Server) serveResponseDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.record(s.interactionSent("response-delete", r.PathValue("token")))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveFollowup(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.WebhookParams
+	files, err := readBody(r, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 50035, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := s.interactionSent("followup", r.PathValue("token"))
+	sent.Content = data.Content
+	sent.Embeds = data.Embeds
+	sent.Components = data.Components
+	sent.Files = files
+	sent.Ephemeral = data.Flags&discordgo.MessageFlagsEphemeral != 0
+	writeJSON(w, http.StatusOK, s.message(s.record(sent)))
+}
+
+func (s *Server) serveUnknown(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, 0, fmt.Sprintf("%s %s is not served by the stand-in", r.Method, r.URL.Path))
+}
+
+/*
+ * Private helpers, callers must hold the lock
+ */
+
+func (s *Server) interactionSent(action, token string) Sent {
+	sent := Sent{Action: action}
+	if i, ok := s.interactions[token]; ok {
+		sent.Interaction = i.ID
+		sent.ChannelID = i.ChannelID
+	}
+	return sent
+}
+
+// record assigns the message an ID if it needs one, appends it to the log and wakes any waiters.
+func (s *Server) record(sent Sent) Sent {
+	if sent.MessageID == "" && sent.Action != "respond" && sent.Action != "response-delete" {
+		s.nextID++
+		sent.MessageID = fmt.Sprint(s.nextID)
+	}
+	if c, ok := s.channels[sent.ChannelID]; ok && c.Type == discordgo.ChannelTypeDM {
+		sent.DM = true
+	}
+	s.sent = append(s.sent, sent)
+	s.notify()
+	return sent
+}
+
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) message(sent Sent) *discordgo.Message {
+	return &discordgo.Message{
+		ID:         sent.MessageID,
+		ChannelID:  sent.ChannelID,
+		Content:    sent.Content,
+		Embeds:     sent.Embeds,
+		Components: sent.Components,
+		Author:     s.User,
+		Timestamp:  time.Now(),
+	}
+}
+
+// readBody decodes a JSON body, or the payload_json part and attachments of a multipart one.
+func readBody(r *http.Request, v any) (map[string]string, error) {
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("decoding body: %w", err)
+		}
+		return nil, nil
+	}
+
+	files := make(map[string]string)
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading multipart body: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("reading multipart body: %w", err)
+		}
+		if part.FormName() == "payload_json" {
+			if err := json.Unmarshal(data, v); err != nil {
+				return nil, fmt.Errorf("decoding payload_json: %w", err)
+			}
+			continue
+		}
+		files[part.FileName()] = string(data)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	writeJSON(w, status, map[string]any{"code": code, "message": message})
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/nerdwerx/daggerbot/bot/discordtest"
+)
+
+var modifierPattern = regexp.MustCompile(`^1d6\+(\d+):`)
+
+// Run with -race: every goroutine rolls in its own channel with a unique
+// modifier, so each reply shows which message it answers.
+func TestConcurrentCommands(t *testing.T) {
+	const (
+		channels = 4
+		users    = 5
+		rolls    = 10
+	)
+
+	h, err := discordtest.NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	type origin struct{ channelID, name string }
+	var (
+		channelIDs = make([]string, channels)
+		userIDs    = make([]string, users)
+		origins    = make(map[int]origin)
+	)
+	for c := range channels {
+		channelIDs[c] = h.AddChannel(fmt.Sprintf("table-%d", c))
+	}
+	for u := range users {
+		userIDs[u] = strconv.Itoa(10 + u)
+		h.AddMember(userIDs[u], fmt.Sprintf("Player%d", u))
+	}
+
+	var wg sync.WaitGroup
+	for c := range channels {
+		for u := range users {
+			for r := range rolls {
+				modifier := (c*users+u)*rolls + r + 1
+				origins[modifier] = origin{channelIDs[c], fmt.Sprintf("Player%d", u)}
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					h.SayIn(channelIDs[c], userIDs[u], fmt.Sprintf("!roll 1d6+%d", modifier))
+				}()
+			}
+		}
+	}
+	wg.Wait()
+
+	sent := h.Fake.Sent()
+	if len(sent) != len(origins) {
+		t.Fatalf("got %d replies to %d rolls", len(sent), len(origins))
+	}
+
+	seen := make(map[int]bool)
+	for _, s := range sent {
+		if len(s.Embeds) != 1 {
+			t.Errorf("reply in %s has %d embeds: %q", s.ChannelID, len(s.Embeds), s.Content)
+			continue
+		}
+		embed := s.Embeds[0]
+		match := modifierPattern.FindStringSubmatch(embed.Title)
+		if match == nil {
+			t.Errorf("unexpected reply %q", embed.Title)
+			continue
+		}
+		modifier, _ := strconv.Atoi(match[1])
+		want, ok := origins[modifier]
+		if !ok || seen[modifier] {
+			t.Errorf("reply %q answers no roll, or one already answered", embed.Title)
+			continue
+		}
+		seen[modifier] = true
+
+		if s.ChannelID != want.channelID {
+			t.Errorf("%q went to channel %s, want %s", embed.Title, s.ChannelID, want.channelID)
+		}
+		if embed.Author == nil || embed.Author.Name != want.name {
+			t.Errorf("%q is attributed to %+v, want %s", embed.Title, embed.Author, want.name)
+		}
+	}
+
+	if got := len(h.Guild.Rolls().Recent(0, nil)); got != len(origins) {
+		t.Errorf("recorded %d rolls, want %d", got, len(origins))
+	}
+}
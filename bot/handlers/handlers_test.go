@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/discordtest"
+)
+
+func newHarness(t *testing.T) *discordtest.Harness {
+	t.Helper()
+
+	h, err := discordtest.NewHarness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.Close)
+	return h
+}
+
+// message builds a message event in the harness channel, as Discord sends it to the gateway.
+func message(author *discordgo.User, member *discordgo.Member, content string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        "900",
+		GuildID:   discordtest.GuildID,
+		ChannelID: discordtest.ChannelID,
+		Author:    author,
+		Member:    member,
+		Content:   content,
+	}}
+}
+
+func TestMessageEvents(t *testing.T) {
+	tests := []struct {
+		name  string
+		event func(h *discordtest.Harness) *discordgo.MessageCreate
+		want  string // Empty when the bot should stay silent
+	}{
+		{"member without user", func(h *discordtest.Harness) *discordgo.MessageCreate {
+			tam := h.AddMember("1", "Tam")
+			return message(tam.User, &discordgo.Member{GuildID: discordtest.GuildID}, "!roll 1d6")
+		}, "1d6"},
+		{"no member", func(h *discordtest.Harness) *discordgo.MessageCreate {
+			tam := h.AddMember("1", "Tam")
+			return message(tam.User, nil, "!roll 1d6")
+		}, "1d6"},
+		{"no author", func(*discordtest.Harness) *discordgo.MessageCreate {
+			return message(nil, nil, "!roll 1d6")
+		}, ""},
+		{"direct message", func(h *discordtest.Harness) *discordgo.MessageCreate {
+			m := message(h.AddMember("1", "Tam").User, nil, "!roll 1d6")
+			m.GuildID, m.ChannelID = "", "dm-1"
+			return m
+		}, ""},
+		{"my own message", func(h *discordtest.Harness) *discordgo.MessageCreate {
+			return message(h.Fake.Me(), nil, "!roll 1d6")
+		}, ""},
+		{"no prefix", func(h *discordtest.Harness) *discordgo.MessageCreate {
+			return message(h.AddMember("1", "Tam").User, nil, "roll 1d6")
+		}, ""},
+		{"unknown command", func(h *discordtest.Harness) *discordgo.MessageCreate {
+			return message(h.AddMember("1", "Tam").User, nil, "!juggle")
+		}, `Sorry, I don't understand "juggle".`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			sent := h.Message(tt.event(h))
+
+			if tt.want == "" {
+				if len(sent) != 0 {
+					t.Errorf("replied %+v, want nothing", sent)
+				}
+				return
+			}
+			if len(sent) != 1 {
+				t.Fatalf("got %d replies, want 1: %+v", len(sent), sent)
+			}
+			if text := replyText(sent[0]); !strings.Contains(text, tt.want) {
+				t.Errorf("replied %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	h := newHarness(t)
+	h.AddRole("GM")
+	h.AddMember("1", "Tam")
+	h.Say(discordtest.OwnerID, "!config set gms GM")
+
+	sent := h.Say("1", "!session create Into the Mire friday 7pm 4")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "you must be") {
+		t.Errorf("a player creating a session got %+v, want a refusal", sent)
+	}
+}
+
+func TestInteractionEvents(t *testing.T) {
+	t.Run("slash roll", func(t *testing.T) {
+		h := newHarness(t)
+		sent := h.Slash("1", "roll", &discordgo.ApplicationCommandInteractionDataOption{
+			Name: "dice", Type: discordgo.ApplicationCommandOptionString, Value: "2d6+3",
+		})
+
+		if len(sent) != 2 || sent[0].Action != "respond" || sent[1].Action != "response-edit" {
+			t.Fatalf("got %+v, want a deferred response then its edit", sent)
+		}
+		if len(sent[1].Embeds) != 1 || !strings.Contains(replyText(sent[1]), "2d6+3") {
+			t.Errorf("roll reply %+v, want an embed for 2d6+3", sent[1])
+		}
+	})
+
+	t.Run("unknown slash command", func(t *testing.T) {
+		h := newHarness(t)
+		sent := h.Slash("1", "juggle")
+		if len(sent) != 1 || !sent[0].Ephemeral || sent[0].Content != `Sorry, I don't understand "juggle".` {
+			t.Errorf("got %+v, want an ephemeral refusal", sent)
+		}
+	})
+
+	t.Run("direct message", func(t *testing.T) {
+		h := newHarness(t)
+		sent := h.Interaction(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+			ID:        "900",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "dm-1",
+			User:      &discordgo.User{ID: "1", Username: "Tam"},
+			Data:      discordgo.ApplicationCommandInteractionData{Name: "roll"},
+		}})
+		if len(sent) != 1 || sent[0].Content != "Sorry, I only take commands in servers." {
+			t.Errorf("got %+v, want the servers-only reply", sent)
+		}
+	})
+
+	t.Run("unknown button", func(t *testing.T) {
+		h := newHarness(t)
+		h.AddMember("1", "Tam")
+		sent := h.Press("1", "901", "juggle:throw")
+		if len(sent) != 1 || sent[0].Content != "Sorry, that button no longer works." {
+			t.Errorf("got %+v, want the stale button reply", sent)
+		}
+	})
+}
+
+// replyText joins the content and embed text of a reply.
+func replyText(s discordtest.Sent) string {
+	parts := []string{s.Content}
+	for _, e := range s.Embeds {
+		parts = append(parts, e.Title, e.Description)
+		for _, f := range e.Fields {
+			parts = append(parts, f.Name, f.Value)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
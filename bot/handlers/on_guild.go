@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+/*
+ * Gateway events that keep the guild registry in sync with Discord
+ */
+
+// OnGuildCreate registers guilds as they become available, including ones
+// that invite the bot after startup.
+func OnGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if g.Guild == nil || g.Unavailable {
+		return
+	}
+	if err := config.RegisterGuild(g.Guild); err != nil {
+		log.Printf("error adding guild %s: %v", g.ID, err)
+	}
+}
+
+func OnGuildUpdate(s *discordgo.Session, g *discordgo.GuildUpdate) {
+	if g.Guild == nil {
+		return
+	}
+	guild, ok := config.Guilds.Get(g.ID)
+	if !ok {
+		if err := config.RegisterGuild(g.Guild); err != nil {
+			log.Printf("error adding guild %s: %v", g.ID, err)
+		}
+		return
+	}
+	if config.Verbose && guild.Name() != g.Name {
+		log.Printf("[VERBOSE] guild %q renamed to %q", guild.Name(), g.Name)
+	}
+	guild.Update(g.Guild)
+}
+
+// OnGuildDelete archives a guild's data when the bot is removed from it. An
+// unavailable guild is only in an outage, so it stays registered.
+func OnGuildDelete(s *discordgo.Session, g *discordgo.GuildDelete) {
+	if g.Guild == nil {
+		return
+	}
+	if g.Unavailable {
+		log.Printf("guild %s is unavailable", g.ID)
+		return
+	}
+
+	guild, ok := config.Guilds.Remove(g.ID)
+	if !ok {
+		return
+	}
+	log.Printf("removed from guild %q (%s)", guild.Name(), g.ID)
+
+	if err := guild.Save(); err != nil {
+		log.Printf("error saving guild %q before archiving: %v", guild.Name(), err)
+	}
+	if err := config.ArchiveGuild(g.ID); err != nil {
+		log.Printf("error archiving guild %q: %v", guild.Name(), err)
+	}
+}
+
+func OnGuildRoleCreate(s *discordgo.Session, r *discordgo.GuildRoleCreate) {
+	if guild, ok := roleGuild(r.GuildRole); ok {
+		guild.SetRole(r.Role)
+	}
+}
+
+func OnGuildRoleUpdate(s *discordgo.Session, r *discordgo.GuildRoleUpdate) {
+	if guild, ok := roleGuild(r.GuildRole); ok {
+		guild.SetRole(r.Role)
+	}
+}
+
+func OnGuildRoleDelete(s *discordgo.Session, r *discordgo.GuildRoleDelete) {
+	guild, ok := config.Guilds.Get(r.GuildID)
+	if !ok {
+		return
+	}
+	if err := guild.RemoveRole(r.RoleID); err != nil {
+		log.Printf("error saving guild %q after role deletion: %v", guild.Name(), err)
+	}
+}
+
+func roleGuild(r *discordgo.GuildRole) (*config.Guild, bool) {
+	if r == nil || r.Role == nil {
+		return nil, false
+	}
+	guild, ok := config.Guilds.Get(r.GuildID)
+	if !ok {
+		log.Printf("role event received from invalid Guild: %s", r.GuildID)
+	}
+	return guild, ok
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/commands"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/bot/discordapi"
+	"github.com/nerdwerx/daggerbot/bot/scheduler"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+// OnInteraction returns the handler for interactions from Discord, with
+// session reminders scheduled on sched.
+func OnInteraction(sched *scheduler.Scheduler) func(*discordgo.Session, *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		HandleInteraction(discordapi.Wrap(s), liveRNG, sched, i)
+	}
+}
+
+// HandleInteraction runs a slash command or routes a button press, rolling
+// any dice with rng and scheduling any reminders on sched.
+func HandleInteraction(s discordapi.Session, rng dice.RNG, sched *scheduler.Scheduler, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand && i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
+		interactionReply(s, i, "Sorry, I only take commands in servers.")
+		return
+	}
+
+	guild, ok := config.Guilds.Get(i.GuildID)
+	if !ok {
+		log.Printf("Interaction received from invalid Guild: %s", i.GuildID)
+		interactionReply(s, i, "Sorry, this server has not finished registering yet.")
+		return
+	}
+
+	if i.Type == discordgo.InteractionMessageComponent {
+		onComponent(s, rng, sched, i, guild)
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	cmd, ok := commands.Lookup(data.Name)
+	if !ok {
+		interactionReply(s, i, fmt.Sprintf("Sorry, I don't understand %q.", data.Name))
+		if config.Verbose {
+			log.Printf("[VERBOSE] Slash command %q not found in commands map", data.Name)
+		}
+		return
+	}
+
+	// Acknowledge the interaction now; the command's first reply completes it
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		log.Printf("Failed acknowledging interaction for /%s: %v", data.Name, err)
+		return
+	}
+
+	args, mentions := commands.OptionArgs(data.Options, data.Resolved)
+	ctx := commands.NewInteractionContext(s, rng, sched, guild, i, args, mentions)
+	defer ctx.Finish()
+
+	if err := commands.Execute(cmd, ctx); err != nil {
+		log.Printf("Error executing command %q: %v", cmd.Name, err)
+		if err := ctx.Reply("Sorry, something went wrong running that command."); err != nil {
+			log.Printf("Failed sending error response: %v", err)
+		}
+	}
+}
+
+// onComponent routes a button press to the handler registered for its custom ID.
+func onComponent(s discordapi.Session, rng dice.RNG, sched *scheduler.Scheduler, i *discordgo.InteractionCreate, guild *config.Guild) {
+	customID := i.MessageComponentData().CustomID
+	handler, args, ok := commands.LookupComponent(customID)
+	if !ok {
+		log.Printf("No handler for component %q", customID)
+		interactionReply(s, i, "Sorry, that button no longer works.")
+		return
+	}
+
+	// Acknowledge without changing the message; the handler updates it if needed
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		log.Printf("Failed acknowledging component %q: %v", customID, err)
+		return
+	}
+
+	ctx := commands.NewComponentContext(s, rng, sched, guild, i, args)
+	if config.Verbose {
+		log.Printf("[VERBOSE] [%s] @%s (%s) pressed component %q", guild.Name(), ctx.Author.DisplayName(), ctx.Author, customID)
+	}
+
+	if err := handler(ctx); err != nil {
+		log.Printf("Error handling component %q: %v", customID, err)
+		if err := ctx.ReplyEphemeral("Sorry, something went wrong."); err != nil {
+			log.Printf("Failed sending error response: %v", err)
+		}
+	}
+}
+
+// interactionReply responds to an interaction with a message only the caller can see.
+func interactionReply(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("Failed responding to interaction: %v", err)
+	}
+}
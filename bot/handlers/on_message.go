@@ -7,26 +7,45 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/nerdwerx/daggerbot/bot/commands"
+	"github.com/nerdwerx/daggerbot/bot/dice"
+	"github.com/nerdwerx/daggerbot/bot/discordapi"
+	"github.com/nerdwerx/daggerbot/bot/scheduler"
 	"github.com/nerdwerx/daggerbot/config"
 )
 
-func OnMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
+// liveRNG rolls the dice for commands arriving from Discord. It is safe for concurrent use.
+var liveRNG = dice.NewCryptoRNG()
+
+// OnMessage returns the handler for messages from Discord, with session
+// reminders scheduled on sched.
+func OnMessage(sched *scheduler.Scheduler) func(*discordgo.Session, *discordgo.MessageCreate) {
+	return func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		HandleMessage(discordapi.Wrap(s), liveRNG, sched, m)
+	}
+}
+
+// HandleMessage runs the command in a message, if it holds one, rolling any
+// dice with rng and scheduling any reminders on sched.
+func HandleMessage(s discordapi.Session, rng dice.RNG, sched *scheduler.Scheduler, m *discordgo.MessageCreate) {
 	var (
 		fullcmd = make([]string, 0)
 		message = m.Content
-		my      = s.State.User
+		my      = s.Me()
 	)
 
-	// Ignore my own messages
-	if m.Author.ID == my.ID {
+	// Ignore my own messages, and system messages with no author
+	if m.Author == nil || m.Author.ID == my.ID {
 		return
 	}
 
+	if m.Member == nil {
+		m.Member = &discordgo.Member{GuildID: m.GuildID} // Direct messages and webhooks carry no member
+	}
 	if m.Member.User == nil {
 		m.Member.User = m.Author // Ensure m.Member.User is set to the message author
 	}
 
-	guild, ok := config.Guilds[m.GuildID]
+	guild, ok := config.Guilds.Get(m.GuildID)
 	if !ok {
 		log.Printf("Message received from invalid Guild: %s", m.GuildID)
 		return
@@ -69,7 +88,7 @@ func OnMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
-	cmd, ok := commands.Commands[strings.ToLower(command)]
+	cmd, ok := commands.Lookup(command)
 	if !ok {
 		if _, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, I don't understand %q.", command)); err != nil {
 			log.Printf("Failed sending Unknown Command response: %v", err)
@@ -80,23 +99,13 @@ func OnMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
-	// Add a reference to the current guild for this command
-	cmd.SetGuild(guild)
-
-	// Inject any args into the command
-	cmd.SetArgs(fullcmd[1:])
-
-	if cmd.Admin() && !guild.IsAdmin(m.Member) {
-		log.Printf("[%s] user @%s (%s) is not an admin, denying access to %s command", guild.Name, m.Author.DisplayName(), m.Author, cmd.Name)
-		if _, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("you must be an admin to use this command, %s", m.Author)); err != nil {
-			log.Printf("Failed sending Admin Command response: %v", err)
-		}
-		return
+	if config.Verbose {
+		log.Printf("[VERBOSE] [%s] command %q received in channel %q", guild.Name(), cmd.Name, channel.Name)
 	}
 
-	log.Printf("[%s] @%s (%s) executing command %q with args %v in channel %q", guild.Name, m.Author.DisplayName(), m.Author, cmd.Name, cmd.Args(), channel.Name)
-
-	if err := cmd.Run(s, m); err != nil {
+	// Each invocation gets its own context, the registered command is never mutated
+	ctx := commands.NewMessageContext(s, rng, sched, guild, m, fullcmd[1:])
+	if err := commands.Execute(cmd, ctx); err != nil {
 		log.Printf("Error executing command %q: %v", cmd.Name, err)
 	}
 }
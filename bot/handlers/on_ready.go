@@ -4,12 +4,20 @@ import (
 	"log"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/bot/commands"
 	"github.com/nerdwerx/daggerbot/config"
 )
 
 func OnReady(s *discordgo.Session, r *discordgo.Ready) {
+	// The state fills these guilds in place as GUILD_CREATE arrives, so read them under its lock
+	s.State.RLock()
+	guildIDs := make([]string, 0, len(r.Guilds))
 	for _, g := range r.Guilds {
-		gid := g.ID
+		guildIDs = append(guildIDs, g.ID)
+	}
+	s.State.RUnlock()
+
+	for _, gid := range guildIDs {
 		gdata, err := s.Guild(gid)
 		if err != nil {
 			log.Printf("error fetching guild data for %s: %v", gid, err)
@@ -23,5 +31,12 @@ func OnReady(s *discordgo.Session, r *discordgo.Ready) {
 		}
 	}
 
-	log.Printf("Bot is ready! Connected to %d servers", len(config.Guilds))
+	appCommands, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", commands.ApplicationCommands())
+	if err != nil {
+		log.Printf("error registering slash commands: %v", err)
+	} else if config.Verbose {
+		log.Printf("[VERBOSE] registered %d slash commands", len(appCommands))
+	}
+
+	log.Printf("Bot is ready! Connected to %d servers", config.Guilds.Len())
 }
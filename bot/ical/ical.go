@@ -0,0 +1,153 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+/*
+ * This package renders scheduled sessions as RFC 5545 iCalendar documents.
+ * Each session keeps the same UID for its whole life and carries its revision
+ * as the SEQUENCE, so calendar apps that import a newer copy of the feed pick
+ * up changed times and cancellations instead of adding duplicates.
+ */
+
+const (
+	ContentType = "text/calendar; charset=utf-8" // For serving a feed over HTTP
+	productID   = "-//nerdwerx//Daggerbot//EN"
+	maxLine     = 75 // Longest content line in octets before it is folded
+	stampLayout = "20060102T150405Z"
+)
+
+// Calendar is a set of a guild's sessions to render.
+type Calendar struct {
+	GuildID  string
+	Name     string // Shown as the calendar's name in most apps
+	Sessions []config.Session
+	Left     []string // IDs of sessions the calendar's user has left, shown as cancelled
+}
+
+// GuildFeed returns every session in the guild, including closed and cancelled ones.
+func GuildFeed(guild *config.Guild) Calendar {
+	return Calendar{
+		GuildID:  guild.ID,
+		Name:     guild.Name() + " sessions",
+		Sessions: guild.Sessions().List(true),
+	}
+}
+
+// UserCalendar returns the guild's sessions that the user is on the roster
+// for. Sessions they have left stay in, cancelled, so a calendar that
+// imported them earlier drops the event instead of keeping a stale copy.
+func UserCalendar(guild *config.Guild, userID string) Calendar {
+	cal := GuildFeed(guild)
+	cal.Sessions = slices.DeleteFunc(cal.Sessions, func(s config.Session) bool {
+		if slices.Contains(s.Left, userID) {
+			cal.Left = append(cal.Left, s.ID)
+			return false
+		}
+		return !slices.Contains(s.Players, userID)
+	})
+	return cal
+}
+
+// UID returns the stable identifier for a session's event.
+func UID(guildID, sessionID string) string {
+	return fmt.Sprintf("session-%s-%s@daggerbot", sessionID, guildID)
+}
+
+// Render writes the calendar as an iCalendar document, stamped with now.
+func (c Calendar) Render(now time.Time) []byte {
+	var w writer
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + productID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+
+	for _, s := range c.Sessions {
+		left := slices.Contains(c.Left, s.ID)
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + UID(c.GuildID, s.ID))
+		w.line("DTSTAMP:" + stamp(now))
+		w.line("DTSTART:" + stamp(s.Start))
+		w.line("DTEND:" + stamp(s.End()))
+		w.line(fmt.Sprintf("SEQUENCE:%d", s.Sequence))
+		w.line("SUMMARY:" + escape(s.Title))
+		w.line("DESCRIPTION:" + escape(description(s, left)))
+		if !s.Created.IsZero() {
+			w.line("CREATED:" + stamp(s.Created))
+		}
+		if !s.Updated.IsZero() {
+			w.line("LAST-MODIFIED:" + stamp(s.Updated))
+		}
+		if s.ChannelID != "" && s.MessageID != "" {
+			w.line(fmt.Sprintf("URL:https://discord.com/channels/%s/%s/%s", c.GuildID, s.ChannelID, s.MessageID))
+		}
+		if s.Status == config.SessionCancelled || left {
+			w.line("STATUS:CANCELLED")
+		} else {
+			w.line("STATUS:CONFIRMED")
+		}
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+func description(s config.Session, left bool) string {
+	desc := fmt.Sprintf("Session #%s\nPlayers: %d/%d", s.ID, len(s.Players), s.MaxPlayers)
+	if len(s.Waitlist) > 0 {
+		desc += fmt.Sprintf(", %d waiting", len(s.Waitlist))
+	}
+	switch s.Status {
+	case config.SessionClosed:
+		desc += "\nSign-ups are closed"
+	case config.SessionCancelled:
+		desc += "\nThis session has been cancelled"
+	}
+	if left && s.Status != config.SessionCancelled {
+		desc += "\nYou left this session"
+	}
+	return desc
+}
+
+func stamp(t time.Time) string {
+	return t.UTC().Format(stampLayout)
+}
+
+// escape quotes a TEXT value as RFC 5545 section 3.3.11 requires.
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(text)
+}
+
+// writer emits CRLF-terminated content lines, folding any longer than 75 octets.
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) line(content string) {
+	limit := maxLine
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut-- // Never split a multi-byte character
+		}
+		w.buf.WriteString(content[:cut])
+		w.buf.WriteString("\r\n ")
+		content = content[cut:]
+		limit = maxLine - 1 // Continuation lines start with a space
+	}
+	w.buf.WriteString(content)
+	w.buf.WriteString("\r\n")
+}
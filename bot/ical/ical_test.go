@@ -0,0 +1,190 @@
+package ical
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var (
+	now     = time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	created = time.Date(2026, time.February, 20, 18, 30, 0, 0, time.UTC)
+)
+
+// golden compares got with testdata/name, rewriting the file instead with -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the rendered calendar:\n got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func testSessions() []config.Session {
+	return []config.Session{
+		{
+			ID:         "1",
+			Title:      "Into the Mire; bring rope, torches & a spare \\ pole",
+			GMID:       "10",
+			Start:      time.Date(2026, time.March, 6, 19, 0, 0, 0, time.FixedZone("CST", -6*3600)),
+			MaxPlayers: 2,
+			Players:    []string{"11", "12"},
+			Waitlist:   []string{"13"},
+			Status:     config.SessionClosed,
+			ChannelID:  "300",
+			MessageID:  "301",
+			Sequence:   1,
+			Created:    created,
+			Updated:    created.Add(time.Hour),
+		},
+		{
+			ID:         "2",
+			Title:      "Le Château des Ombres Éternelles — une très longue aventure à travers les marais",
+			GMID:       "10",
+			Start:      time.Date(2026, time.March, 13, 18, 0, 0, 0, time.UTC),
+			MaxPlayers: 4,
+			Players:    []string{"11"},
+			Status:     config.SessionCancelled,
+			Sequence:   2,
+			Created:    created,
+		},
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	cal := Calendar{GuildID: "200", Name: "Harness, West Marches", Sessions: testSessions()}
+	golden(t, "feed.ics", cal.Render(now))
+}
+
+func TestUserCalendarGolden(t *testing.T) {
+	saved := config.Storage
+	config.Storage = config.NewMemoryStore()
+	t.Cleanup(func() { config.Storage = saved })
+
+	guild := config.NewGuild(&discordgo.Guild{ID: "200", Name: "Harness"})
+	sessions := guild.Sessions()
+	for _, title := range []string{"Into the Mire", "The Sunken Keep", "Ashfall"} {
+		if _, err := sessions.Create(title, "10", time.Date(2026, time.March, 6, 19, 0, 0, 0, time.UTC), 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"1", "2"} {
+		if _, _, err := sessions.Join(id, "11"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := sessions.Leave("2", "11"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sessions.Join("3", "12"); err != nil {
+		t.Fatal(err)
+	}
+
+	cal := UserCalendar(guild, "11")
+	if len(cal.Sessions) != 2 {
+		t.Fatalf("calendar has %d sessions, want the one joined and the one left", len(cal.Sessions))
+	}
+	for i := range cal.Sessions {
+		cal.Sessions[i].Created, cal.Sessions[i].Updated = created, created // Stamped with the wall clock when created
+	}
+	golden(t, "user.ics", cal.Render(now))
+
+	// Joining again puts the session back, with a newer revision
+	left, _ := sessions.Get("2")
+	if _, _, err := sessions.Join("2", "11"); err != nil {
+		t.Fatal(err)
+	}
+	rejoined, _ := sessions.Get("2")
+	if rejoined.Sequence <= left.Sequence {
+		t.Errorf("rejoining left the sequence at %d, calendars would keep the event cancelled", rejoined.Sequence)
+	}
+	if cal := UserCalendar(guild, "11"); len(cal.Left) != 0 || len(cal.Sessions) != 2 {
+		t.Errorf("after rejoining, sessions %d and left %v", len(cal.Sessions), cal.Left)
+	}
+}
+
+func TestUIDIsStable(t *testing.T) {
+	sessions := testSessions()
+	before := Calendar{GuildID: "200", Sessions: sessions[:1]}.Render(now)
+
+	moved := sessions[0]
+	moved.Title = "Out of the Mire"
+	moved.Start = moved.Start.Add(24 * time.Hour)
+	moved.Sequence++
+	after := Calendar{GuildID: "200", Sessions: []config.Session{moved}}.Render(now.Add(time.Hour))
+
+	uid := "UID:session-1-200@daggerbot\r\n"
+	if !bytes.Contains(before, []byte(uid)) || !bytes.Contains(after, []byte(uid)) {
+		t.Errorf("UID changed when the session did:\n%s\n%s", before, after)
+	}
+	if !bytes.Contains(after, []byte("SEQUENCE:2\r\n")) {
+		t.Errorf("moved session has no newer SEQUENCE:\n%s", after)
+	}
+	if UID("200", "1") == UID("200", "10") || UID("200", "1") == UID("201", "1") {
+		t.Error("UIDs collide across sessions or guilds")
+	}
+}
+
+func TestFolding(t *testing.T) {
+	doc := Calendar{GuildID: "200", Sessions: testSessions()}.Render(now)
+
+	if !bytes.HasSuffix(doc, []byte("\r\n")) {
+		t.Error("document does not end with CRLF")
+	}
+	lines := strings.Split(strings.TrimSuffix(string(doc), "\r\n"), "\r\n")
+	folded := false
+	for _, line := range lines {
+		if len(line) > maxLine {
+			t.Errorf("line is %d octets, longer than %d: %q", len(line), maxLine, line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("folding split a character: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded = true
+		}
+	}
+	if !folded {
+		t.Fatal("the long title was not folded")
+	}
+
+	unfolded := strings.ReplaceAll(string(doc), "\r\n ", "")
+	want := "SUMMARY:" + escape(testSessions()[1].Title) + "\r\n"
+	if !strings.Contains(unfolded, want) {
+		t.Errorf("unfolding did not restore %q", want)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "plain"},
+		{"a;b,c", `a\;b\,c`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines\r\nand\rmore", `two\nlines\nand\nmore`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+/*
+ * Clocks drive the scheduler. The real clock follows wall time; the fake
+ * clock only moves when told to, so schedules can be exercised offline.
+ */
+
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer fires once on C after its duration, like time.Timer. Stop and Reset
+// leave no stale time on C, so one timer can be reused for every wait.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type realClock struct{}
+
+// RealClock returns a clock that follows wall time.
+func RealClock() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// FakeClock is a clock that only moves when Advance or Set is called.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer // Timers waiting to fire
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer that fires with the fake time once the clock reaches now+d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward, firing any timers that have come due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to t, firing any timers that have come due.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = t
+	c.timers = slices.DeleteFunc(c.timers, func(timer *fakeTimer) bool {
+		if timer.at.After(t) {
+			return false
+		}
+		timer.ch <- t
+		return true
+	})
+}
+
+// Waiters returns how many timers have yet to fire.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop keeps the timer from firing and reports whether it was still waiting.
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return t.stop()
+}
+
+// Reset stops the timer and has it fire once the clock reaches now+d,
+// reporting whether it was still waiting.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waiting := t.stop()
+	t.at = c.now.Add(d)
+	if !t.at.After(c.now) {
+		t.ch <- c.now
+		return waiting
+	}
+	c.timers = append(c.timers, t)
+	return waiting
+}
+
+// stop removes the timer from the clock and drains C. The clock must be locked.
+func (t *fakeTimer) stop() bool {
+	select {
+	case <-t.ch:
+	default:
+	}
+	n := len(t.clock.timers)
+	t.clock.timers = slices.DeleteFunc(t.clock.timers, func(timer *fakeTimer) bool { return timer == t })
+	return len(t.clock.timers) < n
+}
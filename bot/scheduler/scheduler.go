@@ -0,0 +1,283 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+/*
+ * An in-process job scheduler. Jobs are persisted through the config storage,
+ * so anything scheduled survives a restart, and each job is run by the
+ * handler registered for its kind once it comes due. A job stays pending
+ * until its handler succeeds, so a crash mid-run delivers it again.
+ */
+
+const (
+	MaxWait     = time.Hour       // Longest the scheduler sleeps before checking again
+	RetryDelay  = 5 * time.Minute // Wait before running a failed job again
+	MaxAttempts = 5               // Failed runs before a job is dropped
+)
+
+// Job is a unit of scheduled work. Data carries whatever the handler needs,
+// such as a guild and session ID.
+type Job struct {
+	ID       string            `json:"id"`                 // Unique ID, scheduling the same ID again replaces the job
+	Kind     string            `json:"kind"`               // Selects the handler that runs the job
+	Due      time.Time         `json:"due"`                // When the job should run
+	Expires  time.Time         `json:"expires"`            // Jobs still pending after this are dropped, zero for never
+	Attempts int               `json:"attempts,omitempty"` // Failed runs so far
+	Data     map[string]string `json:"data"`
+}
+
+// Handler runs a job that has come due.
+type Handler func(job Job) error
+
+type Scheduler struct {
+	clock    Clock
+	mu       sync.Mutex
+	jobs     map[string]Job
+	running  map[string]bool // IDs of jobs whose handlers are running
+	handlers map[string]Handler
+	wake     chan struct{}
+}
+
+type jobsJSON struct {
+	Jobs []Job `json:"jobs"`
+}
+
+func New(clock Clock) *Scheduler {
+	if clock == nil {
+		clock = RealClock()
+	}
+	return &Scheduler{
+		clock:    clock,
+		jobs:     make(map[string]Job),
+		running:  make(map[string]bool),
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (s *Scheduler) Clock() Clock {
+	return s.clock
+}
+
+// Handle registers the handler for jobs of the given kind.
+func (s *Scheduler) Handle(kind string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = handler
+}
+
+// Schedule adds a job, replacing any pending job with the same ID.
+func (s *Scheduler) Schedule(job Job) error {
+	if job.ID == "" || job.Kind == "" {
+		return errors.New("a job needs an ID and a kind")
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	err := s.save()
+	s.mu.Unlock()
+
+	s.poke()
+	return err
+}
+
+// Cancel removes every pending job whose ID starts with prefix, returning how many were removed.
+func (s *Scheduler) Cancel(prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for id := range s.jobs {
+		if strings.HasPrefix(id, prefix) {
+			delete(s.jobs, id)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.save()
+}
+
+// Jobs returns the pending jobs ordered by due time.
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted()
+}
+
+// Run executes jobs as they come due until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	timer := s.clock.NewTimer(MaxWait)
+	defer timer.Stop()
+
+	for {
+		s.RunDue()
+		timer.Reset(s.nextWait())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C():
+		}
+	}
+}
+
+// RunDue runs every job that is due now and returns how many succeeded.
+// Jobs are removed once their handler succeeds; failed jobs are retried
+// after RetryDelay, up to MaxAttempts times.
+func (s *Scheduler) RunDue() int {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	due := make([]Job, 0)
+	for id, job := range s.jobs {
+		if !job.Due.After(now) && !s.running[id] {
+			due = append(due, job)
+			s.running[id] = true
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(due, func(a, b Job) int { return a.Due.Compare(b.Due) })
+
+	ran := 0
+	for _, job := range due {
+		if !job.Expires.IsZero() && now.After(job.Expires) {
+			log.Printf("Dropping expired job %q, it was due %s", job.ID, job.Due.Format(time.RFC3339))
+			s.finish(job, nil)
+			continue
+		}
+
+		var err error
+		if handler, ok := s.handler(job.Kind); ok {
+			if config.Verbose {
+				log.Printf("[VERBOSE] Running job %q", job.ID)
+			}
+			err = handler(job)
+		} else {
+			err = fmt.Errorf("no handler for jobs of kind %q", job.Kind)
+		}
+
+		if err != nil {
+			log.Printf("[ERROR] Job %q failed: %v", job.ID, err)
+		} else {
+			ran++
+		}
+		s.finish(job, err)
+	}
+	return ran
+}
+
+func (s *Scheduler) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jjdata jobsJSON
+	if err := config.Storage.Load("scheduler", "jobs", &jjdata); err != nil {
+		if errors.Is(err, config.ErrNotFound) {
+			return nil // Nothing scheduled yet
+		}
+		return fmt.Errorf("loading scheduled jobs: %w", err)
+	}
+
+	for _, job := range jjdata.Jobs {
+		s.jobs[job.ID] = job
+	}
+	if config.Verbose {
+		log.Printf("[VERBOSE] Loaded %d scheduled jobs", len(jjdata.Jobs))
+	}
+	return nil
+}
+
+/*
+ * Private methods for the scheduler, callers must hold the lock unless noted
+ */
+
+// nextWait returns how long to sleep before the next job is due. It takes the lock itself.
+func (s *Scheduler) nextWait() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := MaxWait
+	now := s.clock.Now()
+	for _, job := range s.jobs {
+		wait = min(wait, max(job.Due.Sub(now), 0))
+	}
+	return wait
+}
+
+// handler returns the handler for a kind of job. It takes the lock itself.
+func (s *Scheduler) handler(kind string) (Handler, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handler, ok := s.handlers[kind]
+	return handler, ok
+}
+
+// finish removes a job that ran, or reschedules it if its handler failed. A
+// job replaced or cancelled while it ran is left alone. It takes the lock itself.
+func (s *Scheduler) finish(job Job, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, job.ID)
+	current, ok := s.jobs[job.ID]
+	if !ok || current.Kind != job.Kind || !current.Due.Equal(job.Due) {
+		return
+	}
+
+	switch {
+	case err == nil:
+		delete(s.jobs, job.ID)
+	case current.Attempts+1 >= MaxAttempts:
+		log.Printf("[ERROR] Dropping job %q after %d failed attempts", job.ID, MaxAttempts)
+		delete(s.jobs, job.ID)
+	default:
+		current.Attempts++
+		current.Due = s.clock.Now().Add(RetryDelay)
+		s.jobs[job.ID] = current
+	}
+
+	if err := s.save(); err != nil {
+		log.Printf("[ERROR] Failed to save scheduled jobs: %v", err)
+	}
+}
+
+// poke wakes Run so it notices a newly scheduled job. It never blocks.
+func (s *Scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) sorted() []Job {
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int {
+		if c := a.Due.Compare(b.Due); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return jobs
+}
+
+func (s *Scheduler) save() error {
+	return config.Storage.Save("scheduler", "jobs", jobsJSON{Jobs: s.sorted()})
+}
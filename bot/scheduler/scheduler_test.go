@@ -0,0 +1,241 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+var start = time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// newScheduler returns a scheduler on a fake clock, saving to memory until the test ends.
+func newScheduler(t *testing.T) (*Scheduler, *FakeClock) {
+	t.Helper()
+
+	saved := config.Storage
+	config.Storage = config.NewMemoryStore()
+	t.Cleanup(func() { config.Storage = saved })
+
+	clock := NewFakeClock(start)
+	return New(clock), clock
+}
+
+func TestJobRemovedOnlyAfterSuccess(t *testing.T) {
+	s, clock := newScheduler(t)
+
+	fail := true
+	runs := 0
+	s.Handle("ping", func(Job) error {
+		runs++
+		if fail {
+			return errors.New("discord is down")
+		}
+		return nil
+	})
+	if err := s.Schedule(Job{ID: "a", Kind: "ping", Due: start.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour)
+	if ran := s.RunDue(); ran != 0 || runs != 1 {
+		t.Fatalf("RunDue() = %d after %d runs, want a single failed run", ran, runs)
+	}
+	jobs := s.Jobs()
+	if len(jobs) != 1 || jobs[0].Attempts != 1 || !jobs[0].Due.Equal(clock.Now().Add(RetryDelay)) {
+		t.Fatalf("failed job = %+v, want it kept and retried in %s", jobs, RetryDelay)
+	}
+
+	// Nothing runs again until the retry is due
+	if s.RunDue(); runs != 1 {
+		t.Errorf("retried the job early")
+	}
+
+	fail = false
+	clock.Advance(RetryDelay)
+	if ran := s.RunDue(); ran != 1 {
+		t.Errorf("RunDue() = %d, want 1", ran)
+	}
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Errorf("%d jobs pending after the job succeeded", len(jobs))
+	}
+}
+
+func TestJobDroppedAfterMaxAttempts(t *testing.T) {
+	s, clock := newScheduler(t)
+
+	runs := 0
+	s.Handle("ping", func(Job) error {
+		runs++
+		return errors.New("still down")
+	})
+	if err := s.Schedule(Job{ID: "a", Kind: "ping", Due: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range MaxAttempts + 2 {
+		s.RunDue()
+		clock.Advance(RetryDelay)
+	}
+	if runs != MaxAttempts {
+		t.Errorf("ran %d times, want %d", runs, MaxAttempts)
+	}
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Errorf("%d jobs pending after every attempt failed", len(jobs))
+	}
+}
+
+func TestUnhandledJobIsKept(t *testing.T) {
+	s, clock := newScheduler(t)
+
+	if err := s.Schedule(Job{ID: "a", Kind: "ping", Due: start}); err != nil {
+		t.Fatal(err)
+	}
+	s.RunDue()
+	if jobs := s.Jobs(); len(jobs) != 1 {
+		t.Fatalf("a job with no handler yet was dropped")
+	}
+
+	ran := false
+	s.Handle("ping", func(Job) error { ran = true; return nil })
+	clock.Advance(RetryDelay)
+	s.RunDue()
+	if !ran || len(s.Jobs()) != 0 {
+		t.Errorf("job ran %v, %d pending, want it run once its handler was registered", ran, len(s.Jobs()))
+	}
+}
+
+func TestExpiredJobIsDropped(t *testing.T) {
+	s, clock := newScheduler(t)
+
+	ran := false
+	s.Handle("ping", func(Job) error { ran = true; return nil })
+	if err := s.Schedule(Job{ID: "a", Kind: "ping", Due: start, Expires: start.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour)
+	s.RunDue()
+	if ran || len(s.Jobs()) != 0 {
+		t.Errorf("expired job ran %v, %d pending", ran, len(s.Jobs()))
+	}
+}
+
+func TestRescheduledWhileRunning(t *testing.T) {
+	s, clock := newScheduler(t)
+
+	s.Handle("ping", func(job Job) error {
+		// The job is moved while its handler runs, the new one must survive
+		return s.Schedule(Job{ID: job.ID, Kind: "ping", Due: job.Due.Add(24 * time.Hour)})
+	})
+	if err := s.Schedule(Job{ID: "a", Kind: "ping", Due: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Minute)
+	s.RunDue()
+	jobs := s.Jobs()
+	if len(jobs) != 1 || !jobs[0].Due.Equal(start.Add(24*time.Hour)) {
+		t.Errorf("jobs = %+v, want the rescheduled job", jobs)
+	}
+}
+
+func TestJobsSurviveReload(t *testing.T) {
+	s, clock := newScheduler(t)
+
+	s.Handle("ping", func(Job) error { return errors.New("crashed") })
+	for _, job := range []Job{
+		{ID: "a", Kind: "ping", Due: start.Add(time.Minute), Data: map[string]string{"n": "1"}},
+		{ID: "b", Kind: "ping", Due: start.Add(time.Hour)},
+	} {
+		if err := s.Schedule(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Minute)
+	s.RunDue() // Job a fails, as if the bot stopped mid-delivery
+
+	reloaded := New(clock)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	jobs := reloaded.Jobs()
+	if len(jobs) != 2 || jobs[0].ID != "a" || jobs[0].Data["n"] != "1" || jobs[0].Attempts != 1 || jobs[1].ID != "b" {
+		t.Fatalf("reloaded jobs = %+v", jobs)
+	}
+
+	var delivered []string
+	reloaded.Handle("ping", func(job Job) error {
+		delivered = append(delivered, job.ID)
+		return nil
+	})
+	clock.Advance(time.Hour)
+	if reloaded.RunDue(); len(delivered) != 2 {
+		t.Errorf("delivered %v after the reload, want both jobs", delivered)
+	}
+}
+
+// Run with -race: handlers are registered while jobs run.
+func TestHandleWhileRunning(t *testing.T) {
+	s, clock := newScheduler(t)
+
+	s.Handle("ping", func(Job) error { return nil })
+	for i := range 20 {
+		if err := s.Schedule(Job{ID: string(rune('a' + i)), Kind: "ping", Due: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Minute)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			s.Handle("pong", func(Job) error { return nil })
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		s.RunDue()
+	}()
+	wg.Wait()
+
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Errorf("%d jobs pending", len(jobs))
+	}
+}
+
+func TestRunReusesOneTimer(t *testing.T) {
+	s, clock := newScheduler(t)
+	s.Handle("ping", func(Job) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	// Every new job wakes Run, which must reset its timer rather than start another
+	for i := range 5 {
+		if err := s.Schedule(Job{ID: string(rune('a' + i)), Kind: "ping", Due: start.Add(10 * time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+		for len(s.wake) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if n := clock.Waiters(); n > 1 {
+		t.Errorf("%d timers waiting while running, want 1", n)
+	}
+
+	cancel()
+	<-done
+	if n := clock.Waiters(); n != 0 {
+		t.Errorf("%d timers still waiting after Run returned", n)
+	}
+}
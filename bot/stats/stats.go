@@ -0,0 +1,262 @@
+package stats
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+/*
+ * This package summarizes a guild's roll history and checks whether the dice
+ * behave fairly, using Pearson's chi-square test on the faces rolled
+ */
+
+const (
+	MinExpected  = 5    // Rolls expected on each face before the chi-square test means anything
+	Significance = 0.01 // p-value below which a die is reported as suspicious
+)
+
+// Player sums up one player's rolls.
+type Player struct {
+	UserID     string
+	Name       string
+	Duality    int // Action rolls made
+	Hope       int // Action rolls with Hope, including crits
+	Fear       int // Action rolls with Fear
+	Critical   int
+	DualitySum int // Sum of action roll totals
+	Dice       int // Dice expressions rolled
+	DiceSum    int // Sum of dice expression totals
+}
+
+// HopeRatio is the share of action rolls made with Hope.
+func (p Player) HopeRatio() float64 {
+	if p.Duality == 0 {
+		return 0
+	}
+	return float64(p.Hope) / float64(p.Duality)
+}
+
+func (p Player) DualityAverage() float64 {
+	if p.Duality == 0 {
+		return 0
+	}
+	return float64(p.DualitySum) / float64(p.Duality)
+}
+
+func (p Player) DiceAverage() float64 {
+	if p.Dice == 0 {
+		return 0
+	}
+	return float64(p.DiceSum) / float64(p.Dice)
+}
+
+// Die tallies every face rolled on dice of one size.
+type Die struct {
+	Sides  int
+	Counts []int // Counts[f-1] is how often face f came up
+}
+
+// Rolled returns how many dice were rolled.
+func (d Die) Rolled() int {
+	n := 0
+	for _, c := range d.Counts {
+		n += c
+	}
+	return n
+}
+
+// Average is the mean face rolled. A fair die averages (Sides+1)/2.
+func (d Die) Average() float64 {
+	n, sum := 0, 0
+	for i, c := range d.Counts {
+		n += c
+		sum += c * (i + 1)
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(sum) / float64(n)
+}
+
+// Testable reports whether enough dice were rolled for the chi-square test.
+func (d Die) Testable() bool {
+	return d.Sides > 1 && d.Rolled() >= MinExpected*d.Sides
+}
+
+// Fairness runs the chi-square test against a uniform die.
+func (d Die) Fairness() Test {
+	return ChiSquare(d.Counts)
+}
+
+type Summary struct {
+	Rolls   int
+	Players []Player // Ordered by name
+	Dice    []Die    // Ordered by size
+}
+
+// Summarize tallies rolls by player and by die size. Every recorded die
+// counts toward the distributions, dropped and rerolled ones included, since
+// each was a fair roll in its own right.
+func Summarize(rolls []config.RollRecord) Summary {
+	players := make(map[string]*Player)
+	dice := make(map[int]*Die)
+
+	for _, r := range rolls {
+		p, ok := players[r.UserID]
+		if !ok {
+			p = &Player{UserID: r.UserID}
+			players[r.UserID] = p
+		}
+		p.Name = r.UserName // Latest name wins
+
+		switch r.Kind {
+		case config.RollDuality:
+			p.Duality++
+			p.DualitySum += r.Total
+			if r.WithHope() {
+				p.Hope++
+			} else {
+				p.Fear++
+			}
+			if r.Critical {
+				p.Critical++
+			}
+		default:
+			p.Dice++
+			p.DiceSum += r.Total
+		}
+
+		for _, rolled := range r.Dice {
+			if rolled.Sides < 1 || rolled.Value < 1 || rolled.Value > rolled.Sides {
+				continue // Not a numbered die
+			}
+			d, ok := dice[rolled.Sides]
+			if !ok {
+				d = &Die{Sides: rolled.Sides, Counts: make([]int, rolled.Sides)}
+				dice[rolled.Sides] = d
+			}
+			d.Counts[rolled.Value-1]++
+		}
+	}
+
+	summary := Summary{Rolls: len(rolls)}
+	for _, p := range players {
+		summary.Players = append(summary.Players, *p)
+	}
+	slices.SortFunc(summary.Players, func(a, b Player) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	for _, d := range dice {
+		summary.Dice = append(summary.Dice, *d)
+	}
+	slices.SortFunc(summary.Dice, func(a, b Die) int { return a.Sides - b.Sides })
+	return summary
+}
+
+// Test is the result of a chi-square goodness-of-fit test.
+type Test struct {
+	ChiSquare float64
+	Freedom   int     // Degrees of freedom
+	P         float64 // Chance of a result at least this uneven from fair dice
+}
+
+// Fair reports whether the test found no evidence against fair dice.
+func (t Test) Fair() bool {
+	return t.P >= Significance
+}
+
+// ChiSquare tests observed face counts against a uniform distribution.
+func ChiSquare(counts []int) Test {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	if len(counts) < 2 || n == 0 {
+		return Test{P: 1}
+	}
+
+	expected := float64(n) / float64(len(counts))
+	var chi float64
+	for _, c := range counts {
+		diff := float64(c) - expected
+		chi += diff * diff / expected
+	}
+
+	freedom := len(counts) - 1
+	return Test{
+		ChiSquare: chi,
+		Freedom:   freedom,
+		P:         GammaQ(float64(freedom)/2, chi/2),
+	}
+}
+
+// GammaQ is the regularized upper incomplete gamma function Q(a, x), which
+// gives the chi-square p-value as Q(k/2, x/2). It uses the series expansion
+// below a+1 and a continued fraction above, as in Numerical Recipes.
+func GammaQ(a, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 1
+	case math.IsInf(x, 1):
+		return 0
+	case x < a+1:
+		return 1 - gammaSeries(a, x)
+	default:
+		return gammaFraction(a, x)
+	}
+}
+
+const (
+	gammaIterations = 500
+	gammaEpsilon    = 1e-14
+	gammaTiny       = 1e-300 // Keeps the continued fraction from dividing by zero
+)
+
+// gammaSeries is the regularized lower incomplete gamma function P(a, x) by its series.
+func gammaSeries(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	term := 1 / a
+	sum := term
+	for n := 1; n < gammaIterations; n++ {
+		term *= x / (a + float64(n))
+		sum += term
+		if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lgamma)
+}
+
+// gammaFraction is Q(a, x) by Lentz's method for its continued fraction.
+func gammaFraction(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / gammaTiny
+	d := 1 / b
+	h := d
+	for i := 1; i < gammaIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < gammaTiny {
+			d = gammaTiny
+		}
+		c = b + an/c
+		if math.Abs(c) < gammaTiny {
+			c = gammaTiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+	return h * math.Exp(-x+a*math.Log(x)-lgamma)
+}
@@ -0,0 +1,113 @@
+package stats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nerdwerx/daggerbot/config"
+)
+
+// Critical values from a chi-square table, with the upper tail probability of each.
+func TestGammaQ(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		freedom int
+		chi     float64
+		p       float64
+	}{
+		{5, 0.554, 0.99},
+		{5, 1.145, 0.95},
+		{5, 9.236, 0.10},
+		{5, 11.070, 0.05},
+		{5, 12.833, 0.025},
+		{5, 15.086, 0.01},
+		{5, 20.515, 0.001},
+		{19, 7.633, 0.99},
+		{19, 10.117, 0.95},
+		{19, 27.204, 0.10},
+		{19, 30.144, 0.05},
+		{19, 32.852, 0.025},
+		{19, 36.191, 0.01},
+		{19, 43.820, 0.001},
+	}
+
+	for _, tt := range tests {
+		got := GammaQ(float64(tt.freedom)/2, tt.chi/2)
+		if math.Abs(got-tt.p) > tt.p*0.002 {
+			t.Errorf("df=%d, chi-square %.3f: p = %.5f, want %.3f", tt.freedom, tt.chi, got, tt.p)
+		}
+	}
+
+	if p := GammaQ(2.5, 0); p != 1 {
+		t.Errorf("GammaQ at 0 = %v, want 1", p)
+	}
+	if p := GammaQ(2.5, math.Inf(1)); p != 0 {
+		t.Errorf("GammaQ at infinity = %v, want 0", p)
+	}
+}
+
+// counts returns the face counts of a die rolled 10 times a face, off by diffs.
+func counts(faces int, diffs ...int) []int {
+	c := make([]int, faces)
+	for i := range c {
+		c[i] = 10
+		if i < len(diffs) {
+			c[i] += diffs[i]
+		}
+	}
+	return c
+}
+
+func TestChiSquare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		counts  []int
+		chi     float64
+		freedom int
+		fair    bool
+	}{
+		{"even d6", counts(6), 0, 5, true},
+		{"d6 under the 1% value of 15.086", counts(6, 7, -7, 5, -5, 1, -1), 15, 5, true},
+		{"d6 over the 1% value", counts(6, 8, -8, 4, -4), 16, 5, false},
+		{"d20 under the 1% value of 36.191", counts(20, 10, -10, 8, -8, 4, -4), 36, 19, true},
+		{"d20 over the 1% value", counts(20, 10, -10, 8, -8, 4, -4, 2, -2, 1, -1), 37, 19, false},
+	}
+
+	for _, tt := range tests {
+		got := ChiSquare(tt.counts)
+		if math.Abs(got.ChiSquare-tt.chi) > 1e-9 || got.Freedom != tt.freedom {
+			t.Errorf("%s: chi-square %v with %d degrees of freedom, want %v with %d", tt.name, got.ChiSquare, got.Freedom, tt.chi, tt.freedom)
+		}
+		if got.Fair() != tt.fair {
+			t.Errorf("%s: Fair() = %v at p = %.4f, want %v", tt.name, got.Fair(), got.P, tt.fair)
+		}
+	}
+
+	if got := ChiSquare([]int{0, 0, 0}); got.P != 1 {
+		t.Errorf("no rolls gave p = %v, want 1", got.P)
+	}
+}
+
+func TestSummarizeCountsFaces(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	rolls := []config.RollRecord{
+		{UserID: "1", UserName: "Tam", Kind: config.RollDuality, Hope: 9, Fear: 3, Total: 14, Time: now,
+			Dice: []config.RolledDie{{Sides: 12, Value: 9, Label: "hope"}, {Sides: 12, Value: 3, Label: "fear"}}},
+		{UserID: "1", UserName: "Tam", Kind: config.RollDice, Total: 4, Time: now,
+			Dice: []config.RolledDie{{Sides: 6, Value: 1, Dropped: true}, {Sides: 6, Value: 4}}},
+	}
+
+	summary := Summarize(rolls)
+	if summary.Rolls != 2 || len(summary.Players) != 1 || len(summary.Dice) != 2 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if d6 := summary.Dice[0]; d6.Sides != 6 || d6.Rolled() != 2 {
+		t.Errorf("d6 = %+v, want both dice counted", d6)
+	}
+}
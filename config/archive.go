@@ -0,0 +1,103 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+/*
+ * When the bot is removed from a guild its data is archived rather than
+ * deleted, and restored if the bot is invited back
+ */
+
+// GuildKinds are the storage kinds holding a guild's data, keyed by guild ID.
+var GuildKinds = []string{"guild", "pools", "characters", "sessions", "rolls"}
+
+// GuildCollections return the storage kinds holding a guild's data as many
+// records, such as its pages of rolls.
+var GuildCollections = []func(guildID string) string{RollKind}
+
+const archivePrefix = "archived-" // Prefix for the storage kind of archived records
+
+// ArchiveGuild moves a guild's stored data aside.
+func ArchiveGuild(id string) error {
+	var errs []error
+	for _, kind := range GuildKinds {
+		if err := moveRecord(kind, archivePrefix+kind, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, collection := range GuildCollections {
+		if _, err := moveCollection(collection(id), archivePrefix+collection(id)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Printf("archived data for guild %s", id)
+	return nil
+}
+
+// RestoreGuild brings back a guild's archived data, reporting whether there was any.
+// Data saved since the archive was made is never overwritten.
+func RestoreGuild(id string) (bool, error) {
+	var doc json.RawMessage
+	if err := Storage.Load("guild", id, &doc); err == nil {
+		return false, nil // Current data takes precedence
+	}
+
+	restored := false
+	for _, kind := range GuildKinds {
+		if err := Storage.Load(archivePrefix+kind, id, &doc); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return restored, err
+		}
+		if err := moveRecord(archivePrefix+kind, kind, id); err != nil {
+			return restored, err
+		}
+		restored = true
+	}
+	for _, collection := range GuildCollections {
+		moved, err := moveCollection(archivePrefix+collection(id), collection(id))
+		if err != nil {
+			return restored, err
+		}
+		restored = restored || moved > 0
+	}
+	if restored {
+		log.Printf("restored archived data for guild %s", id)
+	}
+	return restored, nil
+}
+
+func moveRecord(from, to, id string) error {
+	var doc json.RawMessage
+	if err := Storage.Load(from, id, &doc); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := Storage.Save(to, id, doc); err != nil {
+		return err
+	}
+	return Storage.Delete(from, id)
+}
+
+// moveCollection moves every record of one kind to another, returning how many it moved.
+func moveCollection(from, to string) (int, error) {
+	ids, err := Storage.List(from)
+	if err != nil {
+		return 0, err
+	}
+	for n, id := range ids {
+		if err := moveRecord(from, to, id); err != nil {
+			return n, err
+		}
+	}
+	return len(ids), nil
+}
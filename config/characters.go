@@ -0,0 +1,426 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+ * This package stores Daggerheart player characters, per guild. Each
+ * character is owned by a Discord user, who may have one active character.
+ * Names only need to be unique per player, so characters are always looked
+ * up by their owner and name.
+ * Hope and marked Stress live in the guild's Pools so duality rolls and
+ * the character sheet always agree.
+ */
+
+var Traits = []string{"agility", "strength", "finesse", "instinct", "presence", "knowledge"} // Daggerheart character traits
+
+var (
+	ErrCharacterNotFound = errors.New("character not found")
+	ErrNoActiveCharacter = errors.New("no active character")
+)
+
+type Experience struct {
+	Name  string `json:"name"`
+	Bonus int    `json:"bonus"`
+}
+
+// Weapon is a character's primary weapon. Damage is a dice expression such as
+// d8+1, rolled once per point of Proficiency.
+type Weapon struct {
+	Name   string `json:"name"`
+	Trait  string `json:"trait"`
+	Damage string `json:"damage"`
+}
+
+type Character struct {
+	Name            string         `json:"name"`
+	OwnerID         string         `json:"owner_id"` // Discord user ID of the player
+	Class           string         `json:"class"`
+	Subclass        string         `json:"subclass"`
+	Ancestry        string         `json:"ancestry"`
+	Community       string         `json:"community"`
+	Level           int            `json:"level"`
+	Traits          map[string]int `json:"traits"`
+	Evasion         int            `json:"evasion"`
+	HP              int            `json:"hp"`       // Marked Hit Points
+	HPSlots         int            `json:"hp_slots"` // Total Hit Point slots
+	StressSlots     int            `json:"stress_slots"`
+	Armor           int            `json:"armor"` // Armor score
+	MajorThreshold  int            `json:"major_threshold"`
+	SevereThreshold int            `json:"severe_threshold"`
+	Proficiency     int            `json:"proficiency"`
+	Experiences     []Experience   `json:"experiences"`
+	Weapon          Weapon         `json:"weapon"`
+	Created         time.Time      `json:"created"`
+	Updated         time.Time      `json:"updated"`
+}
+
+// CharacterFields are the fields that can be changed with Character.Set
+var CharacterFields = append([]string{
+	"class", "subclass", "ancestry", "community", "level", "evasion", "hp", "hp_slots",
+	"stress_slots", "armor", "major", "severe", "proficiency", "weapon", "weapon_trait", "damage",
+}, Traits...)
+
+// numericFields are the fields, besides Traits, that Character.Set reads as
+// numbers, including their aliases.
+var numericFields = []string{
+	"level", "evasion", "hp", "hp_slots", "maxhp", "stress_slots", "maxstress", "armor",
+	"major", "major_threshold", "severe", "severe_threshold", "proficiency",
+}
+
+func NewCharacter(name, ownerID string) *Character {
+	now := time.Now()
+	c := &Character{
+		Name:        strings.TrimSpace(name),
+		OwnerID:     ownerID,
+		Level:       1,
+		Traits:      make(map[string]int, len(Traits)),
+		HPSlots:     6,
+		StressSlots: MaxStress,
+		Proficiency: 1,
+		Experiences: make([]Experience, 0),
+		Created:     now,
+		Updated:     now,
+	}
+	for _, t := range Traits {
+		c.Traits[t] = 0
+	}
+	return c
+}
+
+// Trait returns the modifier for a trait, and whether the trait exists.
+func (c *Character) Trait(name string) (int, bool) {
+	name = cleanString(name)
+	if !slices.Contains(Traits, name) {
+		return 0, false
+	}
+	return c.Traits[name], true
+}
+
+// Experience finds an Experience by name, ignoring case.
+func (c *Character) Experience(name string) (Experience, bool) {
+	for _, e := range c.Experiences {
+		if strings.EqualFold(e.Name, strings.TrimSpace(name)) {
+			return e, true
+		}
+	}
+	return Experience{}, false
+}
+
+// SetExperience adds or updates an Experience.
+func (c *Character) SetExperience(name string, bonus int) {
+	name = strings.TrimSpace(name)
+	for i, e := range c.Experiences {
+		if strings.EqualFold(e.Name, name) {
+			c.Experiences[i].Bonus = bonus
+			return
+		}
+	}
+	c.Experiences = append(c.Experiences, Experience{Name: name, Bonus: bonus})
+}
+
+// RemoveExperience deletes an Experience, reporting whether it existed.
+func (c *Character) RemoveExperience(name string) bool {
+	n := len(c.Experiences)
+	c.Experiences = slices.DeleteFunc(c.Experiences, func(e Experience) bool {
+		return strings.EqualFold(e.Name, strings.TrimSpace(name))
+	})
+	return len(c.Experiences) != n
+}
+
+// Set changes a single field from its string form.
+func (c *Character) Set(field, value string) error {
+	field = strings.ReplaceAll(cleanString(field), "-", "_")
+	value = strings.TrimSpace(value)
+
+	switch field {
+	case "class":
+		c.Class = value
+		return nil
+	case "subclass":
+		c.Subclass = value
+		return nil
+	case "ancestry":
+		c.Ancestry = value
+		return nil
+	case "community":
+		c.Community = value
+		return nil
+	case "weapon":
+		c.Weapon.Name = value
+		return nil
+	case "weapon_trait":
+		trait := cleanString(value)
+		if !slices.Contains(Traits, trait) {
+			return fmt.Errorf("%q is not a trait, use one of %s", value, strings.Join(Traits, ", "))
+		}
+		c.Weapon.Trait = trait
+		return nil
+	case "damage":
+		c.Weapon.Damage = strings.ToLower(strings.ReplaceAll(value, " ", ""))
+		return nil
+	}
+
+	if !slices.Contains(Traits, field) && !slices.Contains(numericFields, field) {
+		return fmt.Errorf("unknown field %q, use one of %s", field, strings.Join(CharacterFields, ", "))
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(value, "+"))
+	if err != nil {
+		return fmt.Errorf("%s must be a number, not %q", field, value)
+	}
+
+	if slices.Contains(Traits, field) {
+		c.Traits[field] = n
+		return nil
+	}
+
+	switch field {
+	case "level":
+		if n < 1 || n > 10 {
+			return fmt.Errorf("level must be between 1 and 10")
+		}
+		c.Level = n
+	case "evasion":
+		c.Evasion = n
+	case "hp":
+		c.HP = clamp(n, 0, c.HPSlots)
+	case "hp_slots", "maxhp":
+		c.HPSlots = max(n, 1)
+		c.HP = min(c.HP, c.HPSlots)
+	case "stress_slots", "maxstress":
+		c.StressSlots = max(n, 1)
+	case "armor":
+		c.Armor = max(n, 0)
+	case "major", "major_threshold":
+		c.MajorThreshold = n
+	case "severe", "severe_threshold":
+		c.SevereThreshold = n
+	case "proficiency":
+		c.Proficiency = max(n, 1)
+	default:
+		return fmt.Errorf("unrecognized character field %q", field)
+	}
+	return nil
+}
+
+type Characters struct {
+	guildID    string
+	mu         sync.RWMutex
+	characters map[string]*Character // Keyed by characterKey
+	active     map[string]string     // Owner ID to lowercase character name
+}
+
+type charactersJSON struct {
+	Characters []*Character      `json:"characters"`
+	Active     map[string]string `json:"active"`
+}
+
+func NewCharacters(guildID string) *Characters {
+	return &Characters{
+		guildID:    guildID,
+		characters: make(map[string]*Character),
+		active:     make(map[string]string),
+	}
+}
+
+// characterKey identifies a character by its owner and lowercase name.
+func characterKey(ownerID, name string) string {
+	return ownerID + "/" + cleanString(name)
+}
+
+// Create adds a new character, which becomes its owner's active character.
+func (cs *Characters) Create(c *Character) error {
+	name := cleanString(c.Name)
+	if name == "" {
+		return errors.New("character name cannot be empty")
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	key := characterKey(c.OwnerID, name)
+	if _, exists := cs.characters[key]; exists {
+		return fmt.Errorf("you already have a character named %q", c.Name)
+	}
+	cs.characters[key] = c
+	cs.active[c.OwnerID] = name
+	return cs.save()
+}
+
+// Get returns a copy of the owner's named character.
+func (cs *Characters) Get(ownerID, name string) (Character, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	c, ok := cs.characters[characterKey(ownerID, name)]
+	if !ok {
+		return Character{}, fmt.Errorf("%w: %q", ErrCharacterNotFound, name)
+	}
+	return c.clone(), nil
+}
+
+// Active returns a copy of the owner's active character.
+func (cs *Characters) Active(ownerID string) (Character, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	c, ok := cs.characters[characterKey(ownerID, cs.active[ownerID])]
+	if !ok {
+		return Character{}, ErrNoActiveCharacter
+	}
+	return c.clone(), nil
+}
+
+// StressSlots returns the Stress slots of the owner's active character, or 0 when they have none.
+func (cs *Characters) StressSlots(ownerID string) int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	if c, ok := cs.characters[characterKey(ownerID, cs.active[ownerID])]; ok {
+		return c.StressSlots
+	}
+	return 0
+}
+
+// SetActive makes one of the owner's characters their active one.
+func (cs *Characters) SetActive(ownerID, name string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if _, ok := cs.characters[characterKey(ownerID, name)]; !ok {
+		return fmt.Errorf("%w: %q", ErrCharacterNotFound, name)
+	}
+	cs.active[ownerID] = cleanString(name)
+	return cs.save()
+}
+
+// List returns copies of every character, optionally only those owned by ownerID.
+func (cs *Characters) List(ownerID string) []Character {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	list := make([]Character, 0, len(cs.characters))
+	for _, c := range cs.characters {
+		if ownerID == "" || c.OwnerID == ownerID {
+			list = append(list, c.clone())
+		}
+	}
+	slices.SortFunc(list, func(a, b Character) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return list
+}
+
+// Update applies fn to the owner's named character and saves it if fn succeeds.
+func (cs *Characters) Update(ownerID, name string, fn func(c *Character) error) (Character, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	c, ok := cs.characters[characterKey(ownerID, name)]
+	if !ok {
+		return Character{}, fmt.Errorf("%w: %q", ErrCharacterNotFound, name)
+	}
+
+	updated := c.clone()
+	if err := fn(&updated); err != nil {
+		return c.clone(), err
+	}
+	updated.Name, updated.OwnerID = c.Name, c.OwnerID // Identity never changes
+	updated.Updated = time.Now()
+	*c = updated
+
+	return c.clone(), cs.save()
+}
+
+// Delete removes the owner's named character.
+func (cs *Characters) Delete(ownerID, name string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	key := characterKey(ownerID, name)
+	if _, ok := cs.characters[key]; !ok {
+		return fmt.Errorf("%w: %q", ErrCharacterNotFound, name)
+	}
+	delete(cs.characters, key)
+	if cs.active[ownerID] == cleanString(name) {
+		delete(cs.active, ownerID)
+	}
+	return cs.save()
+}
+
+func (cs *Characters) Load() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var cjdata charactersJSON
+	if err := Storage.Load("characters", cs.guildID, &cjdata); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil // No characters yet
+		}
+		log.Printf("Failed to load characters: %v", err)
+		return err
+	}
+
+	for _, c := range cjdata.Characters {
+		if c.Traits == nil {
+			c.Traits = make(map[string]int, len(Traits))
+		}
+		cs.characters[characterKey(c.OwnerID, c.Name)] = c
+	}
+	for owner, key := range cjdata.Active {
+		cs.active[owner] = key
+	}
+
+	if Verbose {
+		log.Printf("[VERBOSE] Loaded %d characters for guild %s", len(cs.characters), cs.guildID)
+	}
+
+	return nil
+}
+
+/*
+ * Private methods for character management, callers must hold the lock
+ */
+
+func (c *Character) clone() Character {
+	copied := *c
+	copied.Traits = make(map[string]int, len(c.Traits))
+	for k, v := range c.Traits {
+		copied.Traits[k] = v
+	}
+	copied.Experiences = slices.Clone(c.Experiences)
+	return copied
+}
+
+func (cs *Characters) save() error {
+	cjdata := charactersJSON{
+		Characters: make([]*Character, 0, len(cs.characters)),
+		Active:     cs.active,
+	}
+	for _, c := range cs.characters {
+		cjdata.Characters = append(cjdata.Characters, c)
+	}
+	slices.SortFunc(cjdata.Characters, func(a, b *Character) int {
+		return cmp.Or(strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), strings.Compare(a.OwnerID, b.OwnerID))
+	})
+
+	if err := Storage.Save("characters", cs.guildID, cjdata); err != nil {
+		log.Printf("Failed to save characters: %v", err)
+		return err
+	}
+
+	if Debug {
+		log.Printf("[DEBUG] Saved %d characters for guild %s", len(cs.characters), cs.guildID)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCharactersAreKeyedByOwner(t *testing.T) {
+	memoryStorage(t)
+
+	chars := NewCharacters("1")
+	for _, owner := range []string{"10", "20"} {
+		if err := chars.Create(NewCharacter("Kai", owner)); err != nil {
+			t.Fatalf("creating Kai for %s: %v", owner, err)
+		}
+	}
+	if err := chars.Create(NewCharacter("kai", "10")); err == nil {
+		t.Error("a player created two characters with the same name")
+	}
+
+	if _, err := chars.Update("20", "Kai", func(c *Character) error { return c.Set("class", "Bard") }); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := chars.Get("10", "Kai"); c.Class != "" {
+		t.Errorf("updating one Kai changed the other's class to %q", c.Class)
+	}
+
+	reloaded := NewCharacters("1")
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := reloaded.Active("20"); err != nil || c.Class != "Bard" {
+		t.Errorf("reloaded active character for 20 = %+v, %v", c, err)
+	}
+
+	if err := reloaded.Delete("10", "Kai"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.Get("20", "Kai"); err != nil {
+		t.Errorf("deleting one Kai removed the other: %v", err)
+	}
+	if _, err := reloaded.Active("10"); !errors.Is(err, ErrNoActiveCharacter) {
+		t.Errorf("after deleting their character, Active = %v, want ErrNoActiveCharacter", err)
+	}
+}
+
+func TestSetUnknownField(t *testing.T) {
+	c := NewCharacter("Kai", "10")
+	for _, value := range []string{"Ranger", "3"} {
+		if err := c.Set("colour", value); err == nil || !strings.Contains(err.Error(), "unknown field") {
+			t.Errorf("Set(colour, %q) = %v, want an unknown field error", value, err)
+		}
+	}
+	if err := c.Set("level", "high"); err == nil || !strings.Contains(err.Error(), "must be a number") {
+		t.Errorf("Set(level, high) = %v, want a number error", err)
+	}
+}
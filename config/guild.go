@@ -2,13 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
 	"log"
-	"os"
 	"regexp"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -22,22 +23,33 @@ type Config struct {
 	admins  []*discordgo.Role // List of admin role IDs
 	gms     []*discordgo.Role // List of admin role IDs
 	players []*discordgo.Role // List of admin role IDs
+	extra   ConfigMap         // Unrecognized keys, kept so saving never drops them
 }
 
 type Guild struct {
-	ID     string           // Guild ID
-	Name   string           // Guild name
-	config *Config          // Guild-specific configuration
-	guild  *discordgo.Guild // guild data
+	ID      string                     // Guild ID
+	mu      sync.RWMutex               // Guards name, config, guild, extra, version and loadErr
+	name    string                     // Guild name
+	config  *Config                    // Guild-specific configuration, replaced rather than modified
+	guild   *discordgo.Guild           // guild data
+	pools   *Pools                     // Fear, Hope and Stress tracking
+	chars   *Characters                // Player characters
+	sess    *Sessions                  // Scheduled sessions
+	rolls   *RollLog                   // History of rolls made in the guild
+	extra   map[string]json.RawMessage // Unrecognized top-level keys from the saved configuration
+	version int                        // Schema version the configuration was loaded at, never saved if newer
+	loadErr error                      // Why the saved configuration couldn't be loaded, never overwritten while set
 }
 
 var ConfigKeys = []string{"prefix", "admins", "gms", "players"} // Keys used in the configuration
 type ConfigMap map[string][]string                              // JSON representation of the configuration
 
 type guildJSON struct {
-	ID     string    `json:"id"`
-	Name   string    `json:"name"`
-	Config ConfigMap `json:"config"`
+	Version int                        `json:"version"`
+	ID      string                     `json:"id"`
+	Name    string                     `json:"name"`
+	Config  ConfigMap                  `json:"config"`
+	Extra   map[string]json.RawMessage `json:"-"` // Unrecognized keys, see schema.go
 }
 
 func NewConfig() *Config {
@@ -46,16 +58,22 @@ func NewConfig() *Config {
 		admins:  make([]*discordgo.Role, 0),
 		gms:     make([]*discordgo.Role, 0),
 		players: make([]*discordgo.Role, 0),
+		extra:   make(ConfigMap),
 	}
 }
 
 func NewGuild(guild *discordgo.Guild) *Guild {
 	g := &Guild{
 		ID:     guild.ID,
-		Name:   guild.Name,
+		name:   guild.Name,
 		config: NewConfig(),
 		guild:  guild,
+		pools:  NewPools(guild.ID),
+		chars:  NewCharacters(guild.ID),
+		sess:   NewSessions(guild.ID),
+		rolls:  NewRollLog(guild.ID),
 	}
+	g.pools.SetStressSlots(g.chars.StressSlots)
 
 	if guild.Roles != nil {
 		for _, role := range guild.Roles {
@@ -65,7 +83,7 @@ func NewGuild(guild *discordgo.Guild) *Guild {
 		}
 	}
 	if Debug {
-		log.Printf("[DEBUG] New Guild created: %q with ID %s", g.Name, g.ID)
+		log.Printf("[DEBUG] New Guild created: %q with ID %s", g.Name(), g.ID)
 		log.Printf("[DEBUG] found guild roles: %v", g.RoleNames())
 	}
 
@@ -73,19 +91,95 @@ func NewGuild(guild *discordgo.Guild) *Guild {
 	if err := g.Load(); err != nil {
 		log.Printf("[ERROR] Failed to load guild configuration: %v", err)
 	}
+	if err := g.pools.Load(); err != nil {
+		log.Printf("[ERROR] Failed to load guild pools: %v", err)
+	}
+	if err := g.chars.Load(); err != nil {
+		log.Printf("[ERROR] Failed to load guild characters: %v", err)
+	}
+	if err := g.sess.Load(); err != nil {
+		log.Printf("[ERROR] Failed to load guild sessions: %v", err)
+	}
+	if err := g.rolls.Load(); err != nil {
+		log.Printf("[ERROR] Failed to load guild roll history: %v", err)
+	}
 
 	return g
 }
 
 func (g *Guild) Update(guild *discordgo.Guild) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if guild.Roles == nil && g.guild != nil {
+		updated := *guild
+		updated.Roles = g.guild.Roles // Partial updates don't carry roles
+		guild = &updated
+	}
 	g.guild = guild
-	g.ID = guild.ID
-	g.Name = guild.Name
+	g.name = guild.Name
 }
 
+// SetRole adds or replaces one of the guild's roles, keeping the configured
+// admin, GM and player roles pointing at its current version.
+func (g *Guild) SetRole(role *discordgo.Role) {
+	if role == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	data := *g.guild
+	data.Roles = slices.Clone(data.Roles)
+	if i := slices.IndexFunc(data.Roles, func(r *discordgo.Role) bool { return r.ID == role.ID }); i >= 0 {
+		data.Roles[i] = role
+	} else {
+		data.Roles = append(data.Roles, role)
+	}
+	g.guild = &data
+
+	c := g.config.clone()
+	for _, roles := range [][]*discordgo.Role{c.admins, c.gms, c.players} {
+		for i, r := range roles {
+			if r.ID == role.ID {
+				roles[i] = role
+			}
+		}
+	}
+	g.config = c
+}
+
+// RemoveRole drops a deleted role from the guild and from the configured
+// admin, GM and player roles, saving the configuration if it changed.
+func (g *Guild) RemoveRole(roleID string) error {
+	isRole := func(r *discordgo.Role) bool { return r.ID == roleID }
+
+	g.mu.Lock()
+	data := *g.guild
+	data.Roles = slices.DeleteFunc(slices.Clone(data.Roles), isRole)
+	g.guild = &data
+
+	c := g.config.clone()
+	before := len(c.admins) + len(c.gms) + len(c.players)
+	c.admins = slices.DeleteFunc(c.admins, isRole)
+	c.gms = slices.DeleteFunc(c.gms, isRole)
+	c.players = slices.DeleteFunc(c.players, isRole)
+	changed := len(c.admins)+len(c.gms)+len(c.players) != before
+	g.config = c
+	g.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	log.Printf("Removed deleted role %s from the configuration of guild %q", roleID, g.Name())
+	return g.Save()
+}
+
+// Roles iterates over a snapshot of the guild's roles.
 func (g *Guild) Roles() iter.Seq[*discordgo.Role] {
+	roles := g.data().Roles
 	return func(yield func(*discordgo.Role) bool) {
-		for _, r := range g.guild.Roles {
+		for _, r := range roles {
 			if !yield(r) {
 				return
 			}
@@ -94,7 +188,7 @@ func (g *Guild) Roles() iter.Seq[*discordgo.Role] {
 }
 
 func (g *Guild) RoleNames() []string {
-	rnames := make([]string, 0, len(g.guild.Roles))
+	rnames := make([]string, 0, len(g.data().Roles))
 	for r := range g.Roles() {
 		rnames = append(rnames, r.Name)
 	}
@@ -102,7 +196,7 @@ func (g *Guild) RoleNames() []string {
 }
 
 func (g *Guild) RoleIDs() []string {
-	rids := make([]string, 0, len(g.guild.Roles))
+	rids := make([]string, 0, len(g.data().Roles))
 	for r := range g.Roles() {
 		rids = append(rids, r.ID)
 	}
@@ -110,8 +204,9 @@ func (g *Guild) RoleIDs() []string {
 }
 
 func (g *Guild) AdminIDs() []string {
-	aid := make([]string, 0, len(g.config.admins))
-	for _, r := range g.config.admins {
+	admins := g.cfg().admins
+	aid := make([]string, 0, len(admins))
+	for _, r := range admins {
 		aid = append(aid, r.ID)
 	}
 	return aid
@@ -160,7 +255,7 @@ func (g *Guild) IsOwner(user *discordgo.User) bool {
 	if user == nil {
 		return false
 	}
-	return g.guild.OwnerID == user.ID
+	return g.data().OwnerID == user.ID
 }
 
 func (g *Guild) IsAdmin(member *discordgo.Member) bool {
@@ -173,14 +268,14 @@ func (g *Guild) IsAdmin(member *discordgo.Member) bool {
 	// Owners are always considered admins
 	if g.IsOwner(user) {
 		if Debug {
-			log.Printf("[DEBUG] User %q is the owner of guild %q", user.Username, g.Name)
+			log.Printf("[DEBUG] User %q is the owner of guild %q", user.Username, g.Name())
 		}
 		return true // Owner is always an admin
 	}
 
 	if len(member.Roles) == 0 {
 		if Debug {
-			log.Printf("[DEBUG] Member %q has no roles in guild %q\n", user.Username, g.Name)
+			log.Printf("[DEBUG] Member %q has no roles in guild %q\n", user.Username, g.Name())
 		}
 		return false // No user data available
 	}
@@ -188,12 +283,12 @@ func (g *Guild) IsAdmin(member *discordgo.Member) bool {
 	for _, r := range member.Roles {
 		role := g.FindRoleByID(r)
 		if role == nil {
-			log.Printf("[ERR] Role with ID %q not found in guild %q", r, g.Name)
+			log.Printf("[ERR] Role with ID %q not found in guild %q", r, g.Name())
 			continue // Skip if role not found -- should not happen
 		}
 		if slices.Contains(g.AdminIDs(), r) {
 			if Verbose {
-				log.Printf("[VERBOSE] User %q has admin role %q in guild %q", user.Username, role.Name, g.Name)
+				log.Printf("[VERBOSE] User %q has admin role %q in guild %q", user.Username, role.Name, g.Name())
 			}
 			return true // User has an admin role
 		}
@@ -206,110 +301,153 @@ func (g *Guild) IsAdminRole(role *discordgo.Role) bool {
 		return false
 	}
 
-	for _, r := range g.config.admins {
+	for _, r := range g.cfg().admins {
 		if r.ID == role.ID {
 			if Debug {
-				log.Printf("[DEBUG] Role %q is an admin role in guild %q", role.Name, g.Name)
+				log.Printf("[DEBUG] Role %q is an admin role in guild %q", role.Name, g.Name())
 			}
 			return true // Role is an admin role
 		}
 	}
 	if Debug {
-		log.Printf("[DEBUG] Role %q is not an admin role in guild %q", role.Name, g.Name)
+		log.Printf("[DEBUG] Role %q is not an admin role in guild %q", role.Name, g.Name())
 	}
 	return false // Role is not an admin role
 }
 
 func (g *Guild) Load() error {
 	if Debug {
-		log.Printf("[DEBUG] Loading configuration for guild %q (%s)", g.Name, g.ID)
+		log.Printf("[DEBUG] Loading configuration for guild %q (%s)", g.Name(), g.ID)
 	}
 
-	file, err := os.Open(fmt.Sprintf("guild_%s.json", g.ID))
-	if err != nil {
-		if os.IsNotExist(err) {
-			log.Printf("Configuration file for guild %s does not exist, creating new one", g.ID)
-			return g.Save() // Save a new configuration file if it doesn't exist
+	var doc map[string]json.RawMessage
+	if err := Storage.Load("guild", g.ID, &doc); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			log.Printf("Configuration for guild %s does not exist, creating new one", g.ID)
+			return g.Save() // Save a new configuration if it doesn't exist
 		}
-		log.Printf("Failed to open guild configuration file: %v", err)
-		return err
+		log.Printf("Failed to load guild configuration: %v", err)
+		return g.unloadable(err)
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Failed to close file: %v", err)
-		}
-	}()
 
-	decoder := json.NewDecoder(file)
-	var gjdata guildJSON
+	from, err := migrateGuild(doc)
+	if err != nil {
+		log.Printf("Failed to migrate guild configuration: %v", err)
+		return g.unloadable(err)
+	}
 
-	if err := decoder.Decode(&gjdata); err != nil {
-		log.Printf("Failed to decode guild configuration file: %v", err)
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return g.unloadable(err)
+	}
+	var gjdata guildJSON
+	if err := json.Unmarshal(data, &gjdata); err != nil {
+		log.Printf("Failed to decode guild configuration: %v", err)
+		return g.unloadable(err)
+	}
+
+	g.mu.Lock()
+	g.name = gjdata.Name
+	g.extra = gjdata.Extra
+	g.version = from
+	g.loadErr = nil
+	g.mu.Unlock()
+	if err := g.SetConfigMap(gjdata.Config); err != nil {
 		return err
 	}
 
-	g.ID = gjdata.ID
-	g.Name = gjdata.Name
-	g.SetConfigMap(gjdata.Config)
+	log.Printf("Successfully loaded configuration for guild %q", g.Name())
 
-	log.Printf("Successfully loaded configuration for guild %q", g.Name)
+	if from < SchemaVersion {
+		log.Printf("Upgraded configuration for guild %q from schema version %d to %d", g.Name(), from, SchemaVersion)
+		return g.Save()
+	}
 
 	return nil
 }
 
 func (g *Guild) Save() error {
 	if Debug {
-		log.Printf("[DEBUG] Saving configuration for guild %q (%s)", g.Name, g.ID)
+		log.Printf("[DEBUG] Saving configuration for guild %q (%s)", g.Name(), g.ID)
 	}
 
-	file, _ := os.OpenFile(fmt.Sprintf("guild_%s.json", g.ID), os.O_RDWR|os.O_CREATE, 0644)
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Failed to close file: %v", err)
-		}
-	}()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-
+	g.mu.RLock()
+	if g.loadErr != nil {
+		err := g.loadErr
+		g.mu.RUnlock()
+		log.Printf("Refusing to save guild configuration: %v", err)
+		return err
+	}
+	if g.version > SchemaVersion {
+		g.mu.RUnlock()
+		err := fmt.Errorf("%w: guild %s has schema version %d, this build writes %d", ErrNewerSchema, g.ID, g.version, SchemaVersion)
+		log.Printf("Refusing to save guild configuration: %v", err)
+		return err
+	}
 	guildData := guildJSON{
-		ID:     g.ID,
-		Name:   g.Name,
-		Config: g.GetConfigMap(),
+		Version: SchemaVersion,
+		ID:      g.ID,
+		Name:    g.name,
+		Extra:   g.extra,
 	}
+	g.mu.RUnlock()
+	guildData.Config = g.GetConfigMap()
 
-	if err := encoder.Encode(guildData); err != nil {
+	if err := Storage.Save("guild", g.ID, guildData); err != nil {
 		log.Printf("Failed to save guild configuration: %v", err)
 		return err
 	}
 
-	log.Printf("Guild %q configuration saved successfully", g.Name)
+	log.Printf("Guild %q configuration saved successfully", g.Name())
 
 	return nil
 }
 
+// unloadable marks the guild's saved configuration as unreadable, so the
+// defaults it runs on are never saved over it.
+func (g *Guild) unloadable(err error) error {
+	g.mu.Lock()
+	g.loadErr = fmt.Errorf("%w: guild %s: %w", ErrUnloadable, g.ID, err)
+	g.mu.Unlock()
+	return err
+}
+
 func (g *Guild) String() string {
-	return fmt.Sprintf("Guild: %q, Roles: %v", g.Name, g.RoleNames())
+	return fmt.Sprintf("Guild: %q, Roles: %v", g.Name(), g.RoleNames())
 }
 
+// Config returns a snapshot of the guild's configuration.
 func (g *Guild) Config() *Config {
-	if g.config == nil {
-		log.Printf("[WARN] Config is nil for guild %q, initializing with default values", g.Name)
-		g.config = NewConfig() // Ensure config is initialized
-	}
-	return g.config
+	return g.cfg()
+}
+
+func (g *Guild) Pools() *Pools {
+	return g.pools
+}
+
+func (g *Guild) Characters() *Characters {
+	return g.chars
+}
+
+func (g *Guild) Sessions() *Sessions {
+	return g.sess
+}
+
+func (g *Guild) Rolls() *RollLog {
+	return g.rolls
 }
 
 func (g *Guild) GetRoleConfig(key string) ([]*discordgo.Role, error) {
+	cfg := g.cfg()
 	switch cleanString(key) {
 	case "admins", "admin":
-		return g.config.admins, nil
+		return slices.Clone(cfg.admins), nil
 	case "gms", "gm":
-		return g.config.gms, nil
+		return slices.Clone(cfg.gms), nil
 	case "players", "player":
-		return g.config.players, nil
+		return slices.Clone(cfg.players), nil
 	default:
-		return nil, fmt.Errorf("unrecognized role config key %q for guild %q", key, g.Name)
+		return nil, fmt.Errorf("unrecognized role config key %q for guild %q", key, g.Name())
 	}
 }
 
@@ -318,67 +456,73 @@ func (g *Guild) SetRoleConfig(key string, values []*discordgo.Role) error {
 		return fmt.Errorf("neither config key nor value can be empty")
 	}
 	if Debug {
-		log.Printf("[DEBUG] Setting config key %q for guild %q to %v", key, g.Name, values)
+		log.Printf("[DEBUG] Setting config key %q for guild %q to %v", key, g.Name(), values)
 	}
+	values = slices.Clone(values)
 	switch cleanString(key) {
 	case "admins", "admin":
-		g.config.admins = values
+		g.updateConfig(func(c *Config) { c.admins = values })
 	case "gms", "gm":
-		g.config.gms = values
+		g.updateConfig(func(c *Config) { c.gms = values })
 	case "players", "player":
-		g.config.players = values
+		g.updateConfig(func(c *Config) { c.players = values })
 	default:
-		return fmt.Errorf("unrecognized config key %s for guild %q", key, g.Name)
+		return fmt.Errorf("unrecognized config key %s for guild %q", key, g.Name())
 	}
 	return g.Save()
 }
 
 func (g *Guild) Prefix() string {
-	return g.config.prefix
+	return g.cfg().prefix
 }
 
 func (g *Guild) SetPrefix(prefix string) {
 	if strings.TrimSpace(prefix) == "" {
-		log.Printf("[WARN] Attempted to set an empty prefix for guild %q, using default: !", g.Name)
-		g.config.prefix = Prefix // Reset to default if empty
-	} else {
-		g.config.prefix = strings.TrimSpace(prefix)
+		log.Printf("[WARN] Attempted to set an empty prefix for guild %q, using default: !", g.Name())
+		prefix = Prefix // Reset to default if empty
 	}
+	prefix = strings.TrimSpace(prefix)
+	g.updateConfig(func(c *Config) { c.prefix = prefix })
 	if Verbose {
-		log.Printf("[VERBOSE] Set prefix for guild %q to: %s", g.Name, g.config.prefix)
+		log.Printf("[VERBOSE] Set prefix for guild %q to: %s", g.Name(), prefix)
 	}
 	if err := g.Save(); err != nil {
-		log.Printf("[ERROR] Failed to save updated prefix for guild %q: %v", g.Name, err)
+		log.Printf("[ERROR] Failed to save updated prefix for guild %q: %v", g.Name(), err)
 	}
 }
 
 func (g *Guild) GetConfigMap() ConfigMap {
+	cfg := g.cfg()
 	config := make(ConfigMap)
 
-	config["prefix"] = []string{g.config.prefix}
+	for key, values := range cfg.extra {
+		config[key] = values
+	}
+
+	config["prefix"] = []string{cfg.prefix}
 
-	config["admins"] = make([]string, 0, len(g.config.admins))
-	if len(g.config.admins) > 0 {
-		adminIDs := make([]string, 0, len(g.config.admins))
-		for _, role := range g.config.admins {
+	config["admins"] = make([]string, 0, len(cfg.admins))
+	if len(cfg.admins) > 0 {
+		adminIDs := make([]string, 0, len(cfg.admins))
+		for _, role := range cfg.admins {
 			adminIDs = append(adminIDs, role.ID)
 		}
 		config["admins"] = adminIDs
 	}
 
-	config["gms"] = make([]string, 0, len(g.config.gms))
-	if len(g.config.gms) > 0 {
-		gmIDs := make([]string, 0, len(g.config.gms))
-		for _, role := range g.config.gms {
+	config["gms"] = make([]string, 0, len(cfg.gms))
+	if len(cfg.gms) > 0 {
+		gmIDs := make([]string, 0, len(cfg.gms))
+		for _, role := range cfg.gms {
 			gmIDs = append(gmIDs, role.ID)
 		}
 		config["gms"] = gmIDs
 	}
 
-	config["players"] = make([]string, 0, len(g.config.players))
-	if len(g.config.players) > 0 {
-		playerIDs := make([]string, 0, len(g.config.players))
-		for _, role := range g.config.players {
+	config["players"] = make([]string, 0, len(cfg.players))
+	if len(cfg.players) > 0 {
+		playerIDs := make([]string, 0, len(cfg.players))
+		for _, role := range cfg.players {
 			playerIDs = append(playerIDs, role.ID)
 		}
 		config["players"] = playerIDs
@@ -387,147 +531,207 @@ func (g *Guild) GetConfigMap() ConfigMap {
 	return config
 }
 
-func (g *Guild) SetConfigMap(config ConfigMap) {
+func (g *Guild) SetConfigMap(config ConfigMap) error {
 	if Debug {
-		log.Printf("[DEBUG] ConfigFromString called for guild %q with config: %v", g.Name, config)
+		log.Printf("[DEBUG] ConfigFromString called for guild %q with config: %v", g.Name(), config)
 	}
 
-	// Initialize the config with default values
-	g.config = NewConfig()
+	g.mu.RLock()
+	loadErr := g.loadErr
+	g.mu.RUnlock()
+	if loadErr != nil {
+		log.Printf("Refusing to replace guild configuration: %v", loadErr)
+		return loadErr
+	}
+
+	// Build the new config from default values, then swap it in
+	cfg := NewConfig()
 
 	for key, values := range config {
 		if len(values) == 0 {
-			log.Printf("no roles found in config for %s in guild %q, skipping", key, g.Name)
+			log.Printf("no roles found in config for %s in guild %q, skipping", key, g.Name())
 			continue // Skip if no admin roles are defined
 		}
 
 		switch cleanString(key) {
 		case "prefix":
 			if len(values) > 0 {
-				g.config.prefix = values[0] // Set the prefix from the config
+				cfg.prefix = values[0] // Set the prefix from the config
 				if Debug {
-					log.Printf("[DEBUG] Loaded prefix for guild %q: %s", g.Name, g.config.prefix)
+					log.Printf("[DEBUG] Loaded prefix for guild %q: %s", g.Name(), cfg.prefix)
 				}
 			} else {
-				log.Printf("no prefix found in config for guild %q, using default: %s", g.Name, g.config.prefix)
+				log.Printf("no prefix found in config for guild %q, using default: %s", g.Name(), cfg.prefix)
 			}
 		case "admins", "admin":
 			if Debug {
-				log.Printf("[DEBUG] Found %d admin roles in config for guild %q", len(values), g.Name)
+				log.Printf("[DEBUG] Found %d admin roles in config for guild %q", len(values), g.Name())
 			}
-			g.config.admins = g.getRoles(values)
-			if len(g.config.admins) == 0 {
-				log.Printf("no valid admin roles found in config for guild %q, using default admin role", g.Name)
+			cfg.admins = g.getRoles(values)
+			if len(cfg.admins) == 0 {
+				log.Printf("no valid admin roles found in config for guild %q, using default admin role", g.Name())
 				defaultAdminRole := g.FindRoleByName("admin")
 				if defaultAdminRole == nil {
 					defaultAdminRole = g.FindRoleByName("administrator")
 				}
 				if defaultAdminRole != nil {
-					g.config.admins = append(g.config.admins, defaultAdminRole)
-					log.Printf("added default admin role %q (%s) to guild %q", defaultAdminRole.Name, defaultAdminRole.ID, g.Name)
+					cfg.admins = append(cfg.admins, defaultAdminRole)
+					log.Printf("added default admin role %q (%s) to guild %q", defaultAdminRole.Name, defaultAdminRole.ID, g.Name())
 				} else {
-					log.Printf("[WARN] No default admin role found in guild %q, admins list will be empty", g.Name)
+					log.Printf("[WARN] No default admin role found in guild %q, admins list will be empty", g.Name())
 				}
 			}
 		case "gms", "gm":
 			if Debug {
-				log.Printf("[DEBUG] Found %d GM roles in config for guild %q", len(values), g.Name)
+				log.Printf("[DEBUG] Found %d GM roles in config for guild %q", len(values), g.Name())
 			}
-			g.config.gms = g.getRoles(values)
-			if len(g.config.gms) == 0 {
-				log.Printf("no valid GM roles found in config for guild %q, using default GM role", g.Name)
+			cfg.gms = g.getRoles(values)
+			if len(cfg.gms) == 0 {
+				log.Printf("no valid GM roles found in config for guild %q, using default GM role", g.Name())
 				defaultGMRole := g.FindRoleByName("gm")
 				if defaultGMRole != nil {
-					g.config.gms = append(g.config.gms, defaultGMRole)
-					log.Printf("added default GM role %q (%s) to guild %q", defaultGMRole.Name, defaultGMRole.ID, g.Name)
+					cfg.gms = append(cfg.gms, defaultGMRole)
+					log.Printf("added default GM role %q (%s) to guild %q", defaultGMRole.Name, defaultGMRole.ID, g.Name())
 				} else {
-					log.Printf("[WARN] No default GM role found in guild %q, GMs list will be empty", g.Name)
+					log.Printf("[WARN] No default GM role found in guild %q, GMs list will be empty", g.Name())
 				}
 			}
 		case "players", "player":
 			if Debug {
-				log.Printf("[DEBUG] Found %d Player roles in config for guild %q", len(values), g.Name)
+				log.Printf("[DEBUG] Found %d Player roles in config for guild %q", len(values), g.Name())
 			}
-			g.config.players = g.getRoles(values)
-			if len(g.config.players) == 0 {
-				log.Printf("No valid Player roles found in config for guild %q, using default Player role", g.Name)
+			cfg.players = g.getRoles(values)
+			if len(cfg.players) == 0 {
+				log.Printf("No valid Player roles found in config for guild %q, using default Player role", g.Name())
 				defaultPlayerRole := g.FindRoleByName("player")
 				if defaultPlayerRole != nil {
-					g.config.players = append(g.config.players, defaultPlayerRole)
-					log.Printf("added default Player role %q (%s) to guild %q", defaultPlayerRole.Name, defaultPlayerRole.ID, g.Name)
+					cfg.players = append(cfg.players, defaultPlayerRole)
+					log.Printf("added default Player role %q (%s) to guild %q", defaultPlayerRole.Name, defaultPlayerRole.ID, g.Name())
 				} else {
-					log.Printf("[WARN] No default Player role found in guild %q, Players list will be empty", g.Name)
+					log.Printf("[WARN] No default Player role found in guild %q, Players list will be empty", g.Name())
 				}
 			}
 		default:
 			if Debug {
-				log.Printf("[DEBUG] Unrecognized config key %q in guild %q, skipping", key, g.Name)
+				log.Printf("[DEBUG] Unrecognized config key %q in guild %q, keeping it as is", key, g.Name())
 			}
+			cfg.extra[key] = values
 		}
 	}
+
+	g.mu.Lock()
+	g.config = cfg
+	g.mu.Unlock()
+	return nil
 }
 
 func (g *Guild) ClearConfig(key string) error {
 	if Debug {
-		log.Printf("[DEBUG] Clearing config key %q for guild %q", key, g.Name)
+		log.Printf("[DEBUG] Clearing config key %q for guild %q", key, g.Name())
 	}
 
 	if key == "prefix" {
-		log.Printf("[WARN] Attempted to clear the prefix key for guild %q, resetting to default: !", g.Name)
-		g.config.prefix = Prefix // Reset to default if prefix is cleared
+		log.Printf("[WARN] Attempted to clear the prefix key for guild %q, resetting to default: !", g.Name())
+		g.updateConfig(func(c *Config) { c.prefix = Prefix }) // Reset to default if prefix is cleared
 		return g.Save()
 	}
 
 	switch key {
 	case "admins", "admin":
 		if Debug {
-			log.Printf("[DEBUG] Clearing admin roles for guild %q", g.Name)
+			log.Printf("[DEBUG] Clearing admin roles for guild %q", g.Name())
 		}
-		g.config.admins = make([]*discordgo.Role, 0) // Clear admin roles
+		g.updateConfig(func(c *Config) { c.admins = make([]*discordgo.Role, 0) }) // Clear admin roles
 	case "gms", "gm":
 		if Debug {
-			log.Printf("[DEBUG] Clearing GM roles for guild %q", g.Name)
+			log.Printf("[DEBUG] Clearing GM roles for guild %q", g.Name())
 		}
-		g.config.gms = make([]*discordgo.Role, 0) // Clear GM roles
+		g.updateConfig(func(c *Config) { c.gms = make([]*discordgo.Role, 0) }) // Clear GM roles
 	case "players", "player":
 		if Debug {
-			log.Printf("[DEBUG] Clearing Player roles for guild %q", g.Name)
+			log.Printf("[DEBUG] Clearing Player roles for guild %q", g.Name())
 		}
-		g.config.players = make([]*discordgo.Role, 0) // Clear Player roles
+		g.updateConfig(func(c *Config) { c.players = make([]*discordgo.Role, 0) }) // Clear Player roles
 	default:
-		log.Printf("[WARN] Attempted to clear unrecognized config key %q for guild %q", key, g.Name)
-		return fmt.Errorf("unrecognized config key %s for guild %q", key, g.Name)
+		log.Printf("[WARN] Attempted to clear unrecognized config key %q for guild %q", key, g.Name())
+		return fmt.Errorf("unrecognized config key %s for guild %q", key, g.Name())
 	}
 	return g.Save()
 }
 
+func (g *Guild) Name() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.name
+}
+
 /*
  * Private methods for Guild configuration management
  */
 
+// cfg returns the current configuration. It is never modified once
+// published, so callers may read it without holding the lock.
+func (g *Guild) cfg() *Config {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.config
+}
+
+// data returns the current Discord guild data, which is replaced rather than modified.
+func (g *Guild) data() *discordgo.Guild {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.guild
+}
+
+// updateConfig applies fn to a copy of the configuration and publishes it.
+// fn runs with the lock held and must not call other Guild methods.
+func (g *Guild) updateConfig(fn func(c *Config)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c := g.config.clone()
+	fn(c)
+	g.config = c
+}
+
+func (c *Config) clone() *Config {
+	copied := &Config{
+		prefix:  c.prefix,
+		admins:  slices.Clone(c.admins),
+		gms:     slices.Clone(c.gms),
+		players: slices.Clone(c.players),
+		extra:   make(ConfigMap, len(c.extra)),
+	}
+	for k, v := range c.extra {
+		copied.extra[k] = slices.Clone(v)
+	}
+	return copied
+}
+
 func (g *Guild) getRoles(values []string) []*discordgo.Role {
 	roles := make([]*discordgo.Role, 0, len(values))
 
 	if len(values) == 0 {
 		if Debug {
-			log.Printf("[DEBUG] No role IDs provided to GetRoles() for guild %q, returning empty list", g.Name)
+			log.Printf("[DEBUG] No role IDs provided to GetRoles() for guild %q, returning empty list", g.Name())
 		}
 		return roles // Return empty list if no role IDs provided
 	}
 
 	if Debug {
-		log.Printf("[DEBUG] Getting roles for guild %q with IDs: %v", g.Name, values)
+		log.Printf("[DEBUG] Getting roles for guild %q with IDs: %v", g.Name(), values)
 	}
 
 	// Iterate through the provided role IDs and find the corresponding roles
 	for _, id := range values {
 		if id == "" {
-			log.Printf("found blank role ID during load for guild %q, skipping", g.Name)
+			log.Printf("found blank role ID during load for guild %q, skipping", g.Name())
 			continue // Skip if role not found -- removed sometime after save
 		}
 		role := g.FindRoleByID(id)
 		if role == nil {
-			log.Printf("role with ID %s not found in guild %q during load", id, g.Name)
+			log.Printf("role with ID %s not found in guild %q during load", id, g.Name())
 			continue // Skip if role not found -- removed sometime after save
 		}
 		roles = append(roles, role)
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+/*
+ * Permission levels for commands. Each level includes the ones below it, so
+ * an admin may do anything a GM can, and a GM anything a player can.
+ */
+
+type Permission int
+
+const (
+	PermEveryone Permission = iota
+	PermPlayer
+	PermGM
+	PermAdmin
+	PermOwner
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermEveryone:
+		return "everyone"
+	case PermPlayer:
+		return "player"
+	case PermGM:
+		return "GM"
+	case PermAdmin:
+		return "admin"
+	case PermOwner:
+		return "owner"
+	default:
+		return "unknown"
+	}
+}
+
+// Permission returns the highest permission level the member holds.
+func (g *Guild) Permission(member *discordgo.Member) Permission {
+	if member == nil || member.User == nil {
+		return PermEveryone
+	}
+
+	cfg := g.cfg()
+	switch {
+	case g.IsOwner(member.User):
+		return PermOwner
+	case g.IsAdmin(member):
+		return PermAdmin
+	case hasRole(member, cfg.gms):
+		return PermGM
+	case len(cfg.players) == 0 || hasRole(member, cfg.players):
+		return PermPlayer // Until player roles are configured, every member is a player
+	default:
+		return PermEveryone
+	}
+}
+
+// HasPermission reports whether the member holds at least the given permission level.
+func (g *Guild) HasPermission(member *discordgo.Member, p Permission) bool {
+	return p == PermEveryone || g.Permission(member) >= p
+}
+
+// IsGM reports whether the member has a GM role, or is an admin.
+func (g *Guild) IsGM(member *discordgo.Member) bool {
+	return g.HasPermission(member, PermGM)
+}
+
+// IsPlayer reports whether the member has a player role, or is a GM or admin.
+// Every member is a player until player roles are configured.
+func (g *Guild) IsPlayer(member *discordgo.Member) bool {
+	return g.HasPermission(member, PermPlayer)
+}
+
+func hasRole(member *discordgo.Member, roles []*discordgo.Role) bool {
+	for _, r := range roles {
+		if slices.Contains(member.Roles, r.ID) {
+			return true
+		}
+	}
+	return false
+}
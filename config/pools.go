@@ -0,0 +1,378 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+/*
+ * This package tracks the GM's Fear pool and each player's Hope and Stress,
+ * per guild and per campaign within the guild
+ */
+
+const (
+	MaxFear         = 12        // Maximum Fear the GM can hold
+	MaxHope         = 6         // Maximum Hope a player can hold
+	MaxStress       = 6         // Default number of Stress slots
+	DefaultCampaign = "default" // Campaign used until a GM picks another
+)
+
+type PlayerPool struct {
+	Hope   int `json:"hope"`
+	Stress int `json:"stress"`
+}
+
+type Pool struct {
+	Fear    int                    `json:"fear"`
+	Players map[string]*PlayerPool `json:"players"` // Keyed by Discord user ID
+}
+
+type Pools struct {
+	guildID   string
+	mu        sync.Mutex
+	active    string
+	campaigns map[string]*Pool
+	slots     func(userID string) int // Stress slots of the user's active character, nil for MaxStress
+}
+
+type poolsJSON struct {
+	Active    string           `json:"active"`
+	Campaigns map[string]*Pool `json:"campaigns"`
+}
+
+// PoolChange describes what a duality roll or a pool command did to the pools.
+type PoolChange struct {
+	Hope          int // Hope gained by the roller
+	Fear          int // Fear gained by the GM
+	StressCleared int // Stress cleared by the roller
+	StressSlots   int // The roller's Stress slots
+	Player        PlayerPool
+	TotalFear     int
+}
+
+// Describe summarizes the change for the named roller, or returns "" if nothing changed.
+func (c PoolChange) Describe(roller string) string {
+	if c.Fear > 0 {
+		return fmt.Sprintf("The GM gains a Fear (%d/%d)", c.TotalFear, MaxFear)
+	}
+
+	parts := make([]string, 0, 2)
+	if c.Hope > 0 {
+		parts = append(parts, fmt.Sprintf("gains a Hope (%d/%d)", c.Player.Hope, MaxHope))
+	}
+	if c.StressCleared > 0 {
+		parts = append(parts, fmt.Sprintf("clears a Stress (%d/%d)", c.Player.Stress, c.StressSlots))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return roller + " " + strings.Join(parts, " and ")
+}
+
+func NewPools(guildID string) *Pools {
+	return &Pools{
+		guildID:   guildID,
+		active:    DefaultCampaign,
+		campaigns: map[string]*Pool{DefaultCampaign: newPool()},
+	}
+}
+
+func newPool() *Pool {
+	return &Pool{Players: make(map[string]*PlayerPool)}
+}
+
+// Campaign returns the name of the active campaign.
+func (p *Pools) Campaign() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active
+}
+
+// Campaigns returns the names of every campaign with tracked pools.
+func (p *Pools) Campaigns() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.campaigns))
+	for name := range p.campaigns {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetCampaign switches the active campaign, creating it if needed.
+func (p *Pools) SetCampaign(name string) error {
+	name = cleanString(name)
+	if name == "" {
+		return fmt.Errorf("campaign name cannot be empty")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.campaigns[name]; !ok {
+		p.campaigns[name] = newPool()
+	}
+	p.active = name
+	return p.save()
+}
+
+func (p *Pools) Fear() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pool().Fear
+}
+
+// AddFear adjusts the GM's Fear by n, clamped to the allowed range, and reports the change actually applied.
+func (p *Pools) AddFear(n int) (PoolChange, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pool := p.pool()
+	before := pool.Fear
+	pool.Fear = clamp(pool.Fear+n, 0, MaxFear)
+	return PoolChange{Fear: pool.Fear - before, TotalFear: pool.Fear}, p.save()
+}
+
+// SpendFear removes n Fear, failing if the GM does not have enough.
+func (p *Pools) SpendFear(n int) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pool := p.pool()
+	if n > pool.Fear {
+		return pool.Fear, fmt.Errorf("cannot spend %d Fear, only %d available", n, pool.Fear)
+	}
+	pool.Fear -= n
+	return pool.Fear, p.save()
+}
+
+// SetFear sets the GM's Fear, clamped to the allowed range.
+func (p *Pools) SetFear(n int) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pool := p.pool()
+	pool.Fear = clamp(n, 0, MaxFear)
+	return pool.Fear, p.save()
+}
+
+// Player returns a copy of the pools tracked for a user, empty if nothing is tracked yet.
+func (p *Pools) Player(userID string) PlayerPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pp, ok := p.pool().Players[userID]; ok {
+		return *pp
+	}
+	return PlayerPool{}
+}
+
+// SetStressSlots sets how a user's Stress slots are found, normally from their active character.
+func (p *Pools) SetStressSlots(slots func(userID string) int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.slots = slots
+}
+
+// StressSlots returns how much Stress a user can mark.
+func (p *Pools) StressSlots(userID string) int {
+	p.mu.Lock()
+	slots := p.slots
+	p.mu.Unlock()
+	if slots == nil {
+		return MaxStress
+	}
+	if n := slots(userID); n > 0 {
+		return n
+	}
+	return MaxStress
+}
+
+// Players returns a copy of every player's pools in the active campaign.
+func (p *Pools) Players() map[string]PlayerPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	players := make(map[string]PlayerPool, len(p.pool().Players))
+	for id, pp := range p.pool().Players {
+		players[id] = *pp
+	}
+	return players
+}
+
+// AddHope adjusts a player's Hope by n, clamped to the allowed range, and reports the change actually applied.
+func (p *Pools) AddHope(userID string, n int) (PoolChange, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp := p.player(userID)
+	before := pp.Hope
+	pp.Hope = clamp(pp.Hope+n, 0, MaxHope)
+	return PoolChange{Hope: pp.Hope - before, Player: *pp}, p.save()
+}
+
+// SpendHope removes n Hope from a player, failing if they do not have enough.
+func (p *Pools) SpendHope(userID string, n int) (PlayerPool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp := p.player(userID)
+	if n > pp.Hope {
+		return *pp, fmt.Errorf("cannot spend %d Hope, only %d available", n, pp.Hope)
+	}
+	pp.Hope -= n
+	return *pp, p.save()
+}
+
+// SetHope sets a player's Hope, clamped to the allowed range.
+func (p *Pools) SetHope(userID string, n int) (PlayerPool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp := p.player(userID)
+	pp.Hope = clamp(n, 0, MaxHope)
+	return *pp, p.save()
+}
+
+// AddStress adjusts a player's marked Stress by n, clamped to their Stress slots.
+func (p *Pools) AddStress(userID string, n int) (PlayerPool, error) {
+	slots := p.StressSlots(userID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp := p.player(userID)
+	pp.Stress = clamp(pp.Stress+n, 0, slots)
+	return *pp, p.save()
+}
+
+// SetStress sets a player's marked Stress, clamped to their Stress slots.
+func (p *Pools) SetStress(userID string, n int) (PlayerPool, error) {
+	slots := p.StressSlots(userID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp := p.player(userID)
+	pp.Stress = clamp(n, 0, slots)
+	return *pp, p.save()
+}
+
+// ClampStress lowers a player's marked Stress to their Stress slots, for when
+// the slots shrink. Players with nothing tracked are left untracked.
+func (p *Pools) ClampStress(userID string) (PlayerPool, error) {
+	slots := p.StressSlots(userID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp, ok := p.pool().Players[userID]
+	if !ok {
+		return PlayerPool{}, nil
+	}
+	if pp.Stress <= slots {
+		return *pp, nil
+	}
+	pp.Stress = slots
+	return *pp, p.save()
+}
+
+// RecordDuality applies the result of a duality roll: Fear goes to the GM,
+// Hope goes to the roller, and a critical success also clears a Stress.
+func (p *Pools) RecordDuality(userID string, withHope, critical bool) (PoolChange, error) {
+	change := PoolChange{StressSlots: p.StressSlots(userID)}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		pool = p.pool()
+		pp   = p.player(userID)
+	)
+
+	if withHope || critical {
+		if pp.Hope < MaxHope {
+			pp.Hope++
+			change.Hope = 1
+		}
+	} else if pool.Fear < MaxFear {
+		pool.Fear++
+		change.Fear = 1
+	}
+
+	if critical && pp.Stress > 0 {
+		pp.Stress--
+		change.StressCleared = 1
+	}
+
+	change.Player = *pp
+	change.TotalFear = pool.Fear
+
+	return change, p.save()
+}
+
+func (p *Pools) Load() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var pjdata poolsJSON
+	if err := Storage.Load("pools", p.guildID, &pjdata); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil // Nothing tracked yet
+		}
+		log.Printf("Failed to load pools: %v", err)
+		return err
+	}
+
+	if pjdata.Campaigns != nil {
+		p.campaigns = pjdata.Campaigns
+	}
+	if pjdata.Active != "" {
+		p.active = pjdata.Active
+	}
+
+	if Verbose {
+		log.Printf("[VERBOSE] Loaded pools for guild %s", p.guildID)
+	}
+
+	return nil
+}
+
+func (p *Pools) Save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.save()
+}
+
+/*
+ * Private methods for pool management, callers must hold the lock
+ */
+
+func (p *Pools) pool() *Pool {
+	pool, ok := p.campaigns[p.active]
+	if !ok {
+		pool = newPool()
+		p.campaigns[p.active] = pool
+	}
+	if pool.Players == nil {
+		pool.Players = make(map[string]*PlayerPool)
+	}
+	return pool
+}
+
+func (p *Pools) player(userID string) *PlayerPool {
+	pool := p.pool()
+	pp, ok := pool.Players[userID]
+	if !ok {
+		pp = &PlayerPool{}
+		pool.Players[userID] = pp
+	}
+	return pp
+}
+
+func (p *Pools) save() error {
+	if err := Storage.Save("pools", p.guildID, poolsJSON{Active: p.active, Campaigns: p.campaigns}); err != nil {
+		log.Printf("Failed to save pools: %v", err)
+		return err
+	}
+
+	if Debug {
+		log.Printf("[DEBUG] Saved pools for guild %s", p.guildID)
+	}
+
+	return nil
+}
+
+func clamp(n, low, high int) int {
+	return max(low, min(n, high))
+}
@@ -0,0 +1,99 @@
+package config
+
+import "testing"
+
+// memoryStorage swaps Storage for an empty MemoryStore until the test ends,
+// so tests that use it must not run in parallel.
+func memoryStorage(t *testing.T) *MemoryStore {
+	t.Helper()
+
+	saved := Storage
+	store := NewMemoryStore()
+	Storage = store
+	t.Cleanup(func() { Storage = saved })
+	return store
+}
+
+func TestStressUsesCharacterSlots(t *testing.T) {
+	memoryStorage(t)
+
+	pools, chars := NewPools("1"), NewCharacters("1")
+	pools.SetStressSlots(chars.StressSlots)
+
+	if pp, _ := pools.AddStress("10", 9); pp.Stress != MaxStress {
+		t.Errorf("without a character, Stress = %d, want the default %d", pp.Stress, MaxStress)
+	}
+
+	c := NewCharacter("Aria", "10")
+	c.StressSlots = 8
+	if err := chars.Create(c); err != nil {
+		t.Fatal(err)
+	}
+	if pp, _ := pools.AddStress("10", 9); pp.Stress != 8 {
+		t.Errorf("with 8 slots, Stress = %d, want 8", pp.Stress)
+	}
+	if pp, _ := pools.SetStress("10", 20); pp.Stress != 8 {
+		t.Errorf("with 8 slots, set Stress = %d, want 8", pp.Stress)
+	}
+	if got := pools.StressSlots("10"); got != 8 {
+		t.Errorf("StressSlots = %d, want 8", got)
+	}
+
+	if _, err := pools.SetStress("10", 1); err != nil {
+		t.Fatal(err)
+	}
+	change, _ := pools.RecordDuality("10", true, true)
+	if got, want := change.Describe("Aria"), "Aria gains a Hope (1/6) and clears a Stress (0/8)"; got != want {
+		t.Errorf("Describe = %q, want %q", got, want)
+	}
+}
+
+func TestPlayerDoesNotTrackOnRead(t *testing.T) {
+	memoryStorage(t)
+
+	pools := NewPools("1")
+	if pp := pools.Player("10"); pp != (PlayerPool{}) {
+		t.Errorf("untracked player = %+v, want empty pools", pp)
+	}
+	if players := pools.Players(); len(players) != 0 {
+		t.Errorf("reading a player tracked them: %v", players)
+	}
+
+	if _, err := pools.AddHope("10", 2); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewPools("1")
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if pp := reloaded.Player("10"); pp.Hope != 2 {
+		t.Errorf("reloaded Hope = %d, want 2", pp.Hope)
+	}
+}
+
+func TestAddReportsAppliedChange(t *testing.T) {
+	memoryStorage(t)
+
+	pools := NewPools("1")
+	if _, err := pools.SetFear(MaxFear - 2); err != nil {
+		t.Fatal(err)
+	}
+	change, err := pools.AddFear(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Fear != 2 || change.TotalFear != MaxFear {
+		t.Errorf("AddFear(20) from %d = %+v, want Fear 2 and TotalFear %d", MaxFear-2, change, MaxFear)
+	}
+
+	if _, err := pools.SetHope("10", MaxHope-1); err != nil {
+		t.Fatal(err)
+	}
+	change, err = pools.AddHope("10", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Hope != 1 || change.Player.Hope != MaxHope {
+		t.Errorf("AddHope(3) from %d = %+v, want Hope 1 and Player.Hope %d", MaxHope-1, change, MaxHope)
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+/*
+ * The guild registry is shared by every gateway event handler, so all access
+ * goes through its lock
+ */
+
+type Registry struct {
+	mu      sync.RWMutex
+	guilds  map[string]*Guild
+	pending map[string]*registration // Guilds being built, keyed by ID
+}
+
+// registration lets concurrent Register calls for the same guild wait for
+// the first one to build it, so it is only restored and loaded once.
+type registration struct {
+	done  chan struct{}
+	guild *Guild
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		guilds:  make(map[string]*Guild),
+		pending: make(map[string]*registration),
+	}
+}
+
+// Get returns the registered guild with the given ID.
+func (r *Registry) Get(id string) (*Guild, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.guilds[id]
+	return g, ok
+}
+
+// Register adds a guild, loading its saved data, or updates it if it's already registered.
+func (r *Registry) Register(g *discordgo.Guild) (*Guild, error) {
+	if g == nil {
+		return nil, errors.New("attempted to add a nil guild")
+	}
+
+	r.mu.Lock()
+	if existing, ok := r.guilds[g.ID]; ok {
+		r.mu.Unlock()
+		log.Printf("updating %q", g.Name)
+		existing.Update(g)
+		return existing, nil
+	}
+	if p, ok := r.pending[g.ID]; ok {
+		r.mu.Unlock()
+		<-p.done // Registered by another handler in the meantime
+		p.guild.Update(g)
+		return p.guild, nil
+	}
+	p := &registration{done: make(chan struct{})}
+	r.pending[g.ID] = p
+	r.mu.Unlock()
+
+	// Loading touches storage, so build the guild without holding the lock
+	if _, err := RestoreGuild(g.ID); err != nil {
+		log.Printf("[ERROR] Failed to restore archived data for guild %q: %v", g.Name, err)
+	}
+	p.guild = NewGuild(g)
+
+	r.mu.Lock()
+	r.guilds[g.ID] = p.guild
+	delete(r.pending, g.ID)
+	r.mu.Unlock()
+	close(p.done)
+
+	log.Printf("registered guild: %q (%s)", g.Name, g.ID)
+	return p.guild, nil
+}
+
+// Remove unregisters a guild and returns it.
+func (r *Registry) Remove(id string) (*Guild, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.guilds[id]
+	delete(r.guilds, id)
+	return g, ok
+}
+
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.guilds)
+}
+
+// All returns a snapshot of the registered guilds, sorted by ID.
+func (r *Registry) All() []*Guild {
+	r.mu.RLock()
+	guilds := make([]*Guild, 0, len(r.guilds))
+	for _, g := range r.guilds {
+		guilds = append(guilds, g)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(guilds, func(a, b *Guild) int { return strings.Compare(a.ID, b.ID) })
+	return guilds
+}
+
+// Save saves every registered guild's configuration.
+func (r *Registry) Save() error {
+	for _, g := range r.All() {
+		if err := g.Save(); err != nil {
+			log.Printf("error saving guild %q: %v", g.Name(), err)
+			return err
+		}
+	}
+	log.Println("all guild configurations saved successfully")
+	return nil
+}
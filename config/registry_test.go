@@ -0,0 +1,282 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+/*
+ * These tests are meant to be run with -race. Each one drives the registry
+ * or a guild from many goroutines and then checks the result is consistent.
+ */
+
+const workers = 16
+
+var (
+	adminRole  = &discordgo.Role{ID: "10", Name: "Admin"}
+	gmRole     = &discordgo.Role{ID: "20", Name: "GM"}
+	tableRole  = &discordgo.Role{ID: "21", Name: "Table GM"}
+	playerRole = &discordgo.Role{ID: "30", Name: "Player"}
+)
+
+func testGuild(id string) *discordgo.Guild {
+	return &discordgo.Guild{
+		ID:      id,
+		Name:    "Guild " + id,
+		OwnerID: "1",
+		Roles:   []*discordgo.Role{adminRole, gmRole, tableRole, playerRole},
+	}
+}
+
+func testMember(userID string, roles ...*discordgo.Role) *discordgo.Member {
+	m := &discordgo.Member{User: &discordgo.User{ID: userID}}
+	for _, r := range roles {
+		m.Roles = append(m.Roles, r.ID)
+	}
+	return m
+}
+
+func TestRegistryConcurrentRegister(t *testing.T) {
+	memoryStorage(t)
+
+	const guilds = 5
+	r := NewRegistry()
+	registered := make([][]*Guild, guilds)
+	for i := range registered {
+		registered[i] = make([]*Guild, workers)
+	}
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range guilds {
+				g, err := r.Register(testGuild(fmt.Sprint(100 + i)))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				registered[i][w] = g
+				r.All()
+				r.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if r.Len() != guilds {
+		t.Fatalf("registered %d guilds, want %d", r.Len(), guilds)
+	}
+	for i, gs := range registered {
+		want, ok := r.Get(fmt.Sprint(100 + i))
+		if !ok {
+			t.Fatalf("guild %d is missing", 100+i)
+		}
+		for w, g := range gs {
+			if g != want {
+				t.Errorf("worker %d got a different copy of guild %d", w, 100+i)
+			}
+		}
+	}
+
+	var removers sync.WaitGroup
+	removed := make(chan string, guilds*workers)
+	for range workers {
+		removers.Add(1)
+		go func() {
+			defer removers.Done()
+			for i := range guilds {
+				if g, ok := r.Remove(fmt.Sprint(100 + i)); ok {
+					removed <- g.ID
+				}
+			}
+		}()
+	}
+	removers.Wait()
+	close(removed)
+
+	if len(removed) != guilds || r.Len() != 0 {
+		t.Errorf("removed %d guilds, leaving %d, want %d removed once each", len(removed), r.Len(), guilds)
+	}
+}
+
+func TestConcurrentConfigEdits(t *testing.T) {
+	memoryStorage(t)
+
+	g := NewGuild(testGuild("1"))
+	if err := g.SetRoleConfig("admins", []*discordgo.Role{adminRole}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 20 {
+				switch (w + i) % 5 {
+				case 0:
+					_ = g.SetRoleConfig("gms", []*discordgo.Role{gmRole})
+				case 1:
+					_ = g.SetRoleConfig("gms", []*discordgo.Role{gmRole, tableRole})
+				case 2:
+					g.SetPrefix(fmt.Sprintf("%c", '!'+rune(w%3)))
+				case 3:
+					g.SetRole(&discordgo.Role{ID: tableRole.ID, Name: fmt.Sprintf("Table GM %d", i)})
+				case 4:
+					g.Update(testGuild("1"))
+				}
+				g.GetConfigMap()
+				g.RoleNames()
+				g.Prefix()
+			}
+		}()
+	}
+	wg.Wait()
+
+	gms, err := g.GetRoleConfig("gms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gms) < 1 || gms[0].ID != gmRole.ID {
+		t.Errorf("GM roles = %v, want the GM role first", g.RolesToNames(gms))
+	}
+	if admins := g.AdminIDs(); len(admins) != 1 || admins[0] != adminRole.ID {
+		t.Errorf("admin roles = %v, want only %s", admins, adminRole.ID)
+	}
+	if p := g.Prefix(); p != "!" && p != `"` && p != "#" {
+		t.Errorf("prefix = %q, want one of the prefixes set", p)
+	}
+}
+
+func TestConcurrentPermissionChecks(t *testing.T) {
+	memoryStorage(t)
+
+	g := NewGuild(testGuild("1"))
+	if err := g.SetRoleConfig("admins", []*discordgo.Role{adminRole}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetRoleConfig("players", []*discordgo.Role{playerRole}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		owner  = testMember("1")
+		admin  = testMember("2", adminRole)
+		gm     = testMember("3", gmRole, playerRole)
+		player = testMember("4", playerRole)
+		guest  = testMember("5")
+	)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				if w%4 == 0 {
+					// GM roles churn underneath the readers, but always include the GM role
+					roles := []*discordgo.Role{gmRole}
+					if i%2 == 0 {
+						roles = append(roles, tableRole)
+					}
+					if err := g.SetRoleConfig("gms", roles); err != nil {
+						t.Error(err)
+					}
+					continue
+				}
+
+				checks := []struct {
+					member *discordgo.Member
+					want   Permission
+				}{
+					{owner, PermOwner},
+					{admin, PermAdmin},
+					{player, PermPlayer},
+					{guest, PermEveryone},
+				}
+				for _, c := range checks {
+					if got := g.Permission(c.member); got != c.want {
+						t.Errorf("member %s has permission %v, want %v", c.member.User.ID, got, c.want)
+					}
+				}
+				// Before the first GM roles are set the GM is only a player
+				if got := g.Permission(gm); got != PermGM && got != PermPlayer {
+					t.Errorf("GM has permission %v", got)
+				}
+				if !g.IsAdmin(admin) || g.IsAdmin(player) {
+					t.Error("IsAdmin disagrees with the admin roles")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !g.IsGM(gm) || g.IsGM(player) {
+		t.Error("IsGM disagrees with the GM roles")
+	}
+}
+
+// loadCountingStore counts the guild documents loaded, holding each load
+// until gate is closed so concurrent registrations pile up behind it.
+type loadCountingStore struct {
+	*MemoryStore
+	mu    sync.Mutex
+	loads int
+	gate  chan struct{}
+}
+
+func (s *loadCountingStore) Load(kind, id string, v any) error {
+	if kind == "guild" {
+		s.mu.Lock()
+		s.loads++
+		s.mu.Unlock()
+		<-s.gate
+	}
+	return s.MemoryStore.Load(kind, id, v)
+}
+
+func (s *loadCountingStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
+
+func TestRegistryBuildsEachGuildOnce(t *testing.T) {
+	saved := Storage
+	store := &loadCountingStore{MemoryStore: NewMemoryStore(), gate: make(chan struct{})}
+	Storage = store
+	t.Cleanup(func() { Storage = saved })
+
+	r := NewRegistry()
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Register(testGuild("100")); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for store.count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // Let the other registrations catch up with the first
+	close(store.gate)
+	wg.Wait()
+	building := store.count()
+
+	store.loads = 0
+	if _, err := NewRegistry().Register(testGuild("101")); err != nil {
+		t.Fatal(err)
+	}
+	if once := store.count(); building != once {
+		t.Errorf("%d concurrent registrations loaded the guild %d times, want %d as for one", workers, building, once)
+	}
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+ * This package keeps a log of the rolls made in each guild, so GMs can look
+ * back over what happened at the table. Rolls are stored in pages of
+ * RollPageSize, so recording one only rewrites the latest page and a full
+ * history is a few dozen records rather than one per roll.
+ */
+
+const (
+	MaxRollHistory = 2000 // Rolls kept per guild, the oldest are dropped first
+	RollPageSize   = 100  // Rolls stored together in one record
+)
+
+const (
+	RollDuality = "duality" // A Daggerheart action roll
+	RollDice    = "dice"    // A dice expression
+)
+
+// RolledDie is one die that was rolled.
+type RolledDie struct {
+	Sides   int    `json:"sides"`
+	Value   int    `json:"value"`
+	Label   string `json:"label,omitempty"`   // "hope", "fear", "advantage" or "disadvantage" for duality dice
+	Dropped bool   `json:"dropped,omitempty"` // Not counted toward the total
+}
+
+type RollRecord struct {
+	ID         int         `json:"id"`
+	UserID     string      `json:"user_id"`
+	UserName   string      `json:"user_name"`
+	Character  string      `json:"character,omitempty"` // Active character when the roll was made
+	Kind       string      `json:"kind"`                // RollDuality or RollDice
+	Expression string      `json:"expression"`          // Roll as entered, e.g. "duality +2 dc 14" or "2d6+3"
+	Dice       []RolledDie `json:"dice"`
+	Total      int         `json:"total"`
+	Hope       int         `json:"hope,omitempty"`
+	Fear       int         `json:"fear,omitempty"`
+	Outcome    string      `json:"outcome,omitempty"` // e.g. "Success with Hope", duality rolls only
+	Critical   bool        `json:"critical,omitempty"`
+	Private    bool        `json:"private,omitempty"` // Made with proll
+	ChannelID  string      `json:"channel_id"`
+	SessionID  string      `json:"session_id,omitempty"` // Session running in the channel, when the roller was in it
+	Time       time.Time   `json:"time"`
+}
+
+// WithHope reports whether a duality roll was made with Hope.
+func (r RollRecord) WithHope() bool {
+	return r.Kind == RollDuality && r.Hope >= r.Fear
+}
+
+type RollLog struct {
+	guildID string
+	mu      sync.RWMutex
+	next    int
+	rolls   []RollRecord // Oldest first
+}
+
+// rollPageJSON holds the rolls with IDs in one page, oldest first.
+type rollPageJSON struct {
+	Rolls []RollRecord `json:"rolls"`
+}
+
+// rollLogJSON is how the log was stored before it was paged, as a single
+// "rolls" record per guild. It is migrated on load.
+type rollLogJSON struct {
+	Next  int          `json:"next"`
+	Rolls []RollRecord `json:"rolls"`
+}
+
+// RollKind is the storage kind holding a guild's rolls, keyed by page number.
+func RollKind(guildID string) string {
+	return "roll-" + guildID
+}
+
+// rollPage is the page holding a roll ID. Roll 1 starts page 1.
+func rollPage(id int) int {
+	return (id-1)/RollPageSize + 1
+}
+
+func NewRollLog(guildID string) *RollLog {
+	return &RollLog{
+		guildID: guildID,
+		next:    1,
+	}
+}
+
+// Record adds rolls to the log, assigning their IDs.
+func (rl *RollLog) Record(records ...RollRecord) ([]RollRecord, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for i := range records {
+		records[i].ID = rl.next
+		rl.next++
+		rl.rolls = append(rl.rolls, cloneRoll(records[i]))
+	}
+
+	return records, rl.save(records, rl.trim())
+}
+
+// Recent returns up to n of the latest rolls matching keep, oldest first. A
+// nil keep matches every roll, and n <= 0 returns them all.
+func (rl *RollLog) Recent(n int, keep func(RollRecord) bool) []RollRecord {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	var found []RollRecord
+	for i := len(rl.rolls) - 1; i >= 0 && (n <= 0 || len(found) < n); i-- {
+		if keep == nil || keep(rl.rolls[i]) {
+			found = append(found, cloneRoll(rl.rolls[i]))
+		}
+	}
+	slices.Reverse(found)
+	return found
+}
+
+func (rl *RollLog) Load() error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	pages, err := Storage.List(RollKind(rl.guildID))
+	if err != nil {
+		log.Printf("Failed to list roll history: %v", err)
+		return err
+	}
+	rl.rolls = make([]RollRecord, 0, len(pages)*RollPageSize)
+	for _, page := range pages {
+		var pdata rollPageJSON
+		if err := Storage.Load(RollKind(rl.guildID), page, &pdata); err != nil {
+			log.Printf("Failed to load roll page %s: %v", page, err)
+			return err
+		}
+		rl.rolls = append(rl.rolls, pdata.Rolls...)
+	}
+	slices.SortFunc(rl.rolls, func(a, b RollRecord) int { return cmp.Compare(a.ID, b.ID) })
+	if len(rl.rolls) > 0 {
+		rl.next = max(rl.next, rl.rolls[len(rl.rolls)-1].ID+1)
+	}
+	rl.trim() // The oldest page keeps dropped rolls until all of them are
+
+	if err := rl.migrate(); err != nil {
+		log.Printf("Failed to migrate roll history: %v", err)
+		return err
+	}
+
+	if Verbose {
+		log.Printf("[VERBOSE] Loaded %d rolls for guild %s", len(rl.rolls), rl.guildID)
+	}
+
+	return nil
+}
+
+/*
+ * Private methods for the roll log, callers must hold the lock
+ */
+
+func cloneRoll(r RollRecord) RollRecord {
+	r.Dice = slices.Clone(r.Dice)
+	return r
+}
+
+// trim drops the oldest rolls beyond MaxRollHistory, returning them.
+func (rl *RollLog) trim() []RollRecord {
+	over := len(rl.rolls) - MaxRollHistory
+	if over <= 0 {
+		return nil
+	}
+	dropped := slices.Clone(rl.rolls[:over])
+	rl.rolls = slices.Delete(rl.rolls, 0, over)
+	return dropped
+}
+
+// save rewrites the pages holding the added rolls and deletes the pages
+// left empty by the dropped ones, leaving the rest untouched.
+func (rl *RollLog) save(added, dropped []RollRecord) error {
+	var errs []error
+	for _, page := range rollPages(added) {
+		pdata := rollPageJSON{Rolls: rl.page(page)}
+		if err := Storage.Save(RollKind(rl.guildID), strconv.Itoa(page), pdata); err != nil {
+			errs = append(errs, fmt.Errorf("saving roll page %d: %w", page, err))
+		}
+	}
+	for _, page := range rollPages(dropped) {
+		if len(rl.page(page)) > 0 {
+			continue // Still holds rolls that are kept
+		}
+		if err := Storage.Delete(RollKind(rl.guildID), strconv.Itoa(page)); err != nil {
+			errs = append(errs, fmt.Errorf("deleting roll page %d: %w", page, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.Printf("Failed to save roll history: %v", err)
+		return err
+	}
+
+	if Debug {
+		log.Printf("[DEBUG] Saved %d and dropped %d rolls for guild %s", len(added), len(dropped), rl.guildID)
+	}
+
+	return nil
+}
+
+// page returns the kept rolls in a page.
+func (rl *RollLog) page(page int) []RollRecord {
+	start, _ := slices.BinarySearchFunc(rl.rolls, (page-1)*RollPageSize+1, func(r RollRecord, id int) int { return cmp.Compare(r.ID, id) })
+	end, _ := slices.BinarySearchFunc(rl.rolls, page*RollPageSize+1, func(r RollRecord, id int) int { return cmp.Compare(r.ID, id) })
+	return rl.rolls[start:end]
+}
+
+// rollPages returns the distinct pages holding rolls, in order.
+func rollPages(rolls []RollRecord) []int {
+	pages := make([]int, 0, len(rolls))
+	for _, r := range rolls {
+		pages = append(pages, rollPage(r.ID))
+	}
+	slices.Sort(pages)
+	return slices.Compact(pages)
+}
+
+// migrate moves rolls from the single record they used to be kept in to
+// pages, then deletes the old record.
+func (rl *RollLog) migrate() error {
+	var rldata rollLogJSON
+	if err := Storage.Load("rolls", rl.guildID, &rldata); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil // Nothing stored the old way
+		}
+		return err
+	}
+
+	var added []RollRecord
+	for _, r := range rldata.Rolls {
+		if _, found := slices.BinarySearchFunc(rl.rolls, r.ID, func(have RollRecord, id int) int { return cmp.Compare(have.ID, id) }); !found {
+			added = append(added, r)
+		}
+	}
+	rl.rolls = append(rl.rolls, added...)
+	slices.SortFunc(rl.rolls, func(a, b RollRecord) int { return cmp.Compare(a.ID, b.ID) })
+	rl.next = max(rl.next, rldata.Next)
+	if len(rl.rolls) > 0 {
+		rl.next = max(rl.next, rl.rolls[len(rl.rolls)-1].ID+1)
+	}
+
+	if err := rl.save(added, rl.trim()); err != nil {
+		return err
+	}
+	log.Printf("migrated %d rolls for guild %s to pages", len(added), rl.guildID)
+	return Storage.Delete("rolls", rl.guildID)
+}
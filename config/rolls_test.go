@@ -0,0 +1,151 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// countingStore counts the records saved and deleted, by kind.
+type countingStore struct {
+	*MemoryStore
+	saves, deletes map[string]int
+}
+
+func (s *countingStore) Save(kind, id string, v any) error {
+	s.saves[kind]++
+	return s.MemoryStore.Save(kind, id, v)
+}
+
+func (s *countingStore) Delete(kind, id string) error {
+	s.deletes[kind]++
+	return s.MemoryStore.Delete(kind, id)
+}
+
+func countingStorage(t *testing.T) *countingStore {
+	t.Helper()
+
+	saved := Storage
+	store := &countingStore{MemoryStore: NewMemoryStore(), saves: make(map[string]int), deletes: make(map[string]int)}
+	Storage = store
+	t.Cleanup(func() { Storage = saved })
+	return store
+}
+
+func roll(total int) RollRecord {
+	return RollRecord{UserID: "11", Kind: RollDice, Expression: "1d20", Total: total, Time: time.Now()}
+}
+
+func TestRecordSavesOnlyTheLatestPage(t *testing.T) {
+	store := countingStorage(t)
+	kind := RollKind("200")
+
+	rl := NewRollLog("200")
+	for i := range MaxRollHistory + RollPageSize + 3 {
+		if _, err := rl.Record(roll(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := MaxRollHistory + RollPageSize + 3; store.saves[kind] != want || store.deletes[kind] != 1 {
+		t.Errorf("recording %d rolls saved %d pages and deleted %d, want one save per roll and the first page deleted",
+			want, store.saves[kind], store.deletes[kind])
+	}
+	pages, _ := Storage.List(kind)
+	if want := MaxRollHistory/RollPageSize + 1; len(pages) != want {
+		t.Errorf("stored %d pages, want %d", len(pages), want)
+	}
+
+	reloaded := NewRollLog("200")
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	rolls := reloaded.Recent(0, nil)
+	first, last := RollPageSize+4, MaxRollHistory+RollPageSize+3
+	if len(rolls) != MaxRollHistory || rolls[0].ID != first || rolls[len(rolls)-1].ID != last {
+		t.Fatalf("reloaded %d rolls, #%d to #%d, want %d from #%d to #%d", len(rolls), rolls[0].ID, rolls[len(rolls)-1].ID, MaxRollHistory, first, last)
+	}
+	added, err := reloaded.Record(roll(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added[0].ID != last+1 {
+		t.Errorf("after reloading the next roll is #%d, want #%d", added[0].ID, last+1)
+	}
+}
+
+func TestLoadMigratesSingleRecord(t *testing.T) {
+	store := countingStorage(t)
+
+	legacy := rollLogJSON{Next: 12}
+	for id := 1; id <= 3; id++ {
+		r := roll(id)
+		r.ID = id
+		legacy.Rolls = append(legacy.Rolls, r)
+	}
+	if err := Storage.Save("rolls", "200", legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	rl := NewRollLog("200")
+	if err := rl.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if rolls := rl.Recent(0, nil); len(rolls) != 3 || rolls[2].Total != 3 {
+		t.Errorf("migrated rolls = %+v", rolls)
+	}
+	pages, _ := Storage.List(RollKind("200"))
+	if len(pages) != 1 {
+		t.Errorf("stored pages %v, want one", pages)
+	}
+	var doc rollLogJSON
+	if err := Storage.Load("rolls", "200", &doc); err == nil {
+		t.Error("the old single record was kept")
+	}
+	if added, _ := rl.Record(roll(4)); added[0].ID != 12 {
+		t.Errorf("the next roll after migrating is #%d, want #12", added[0].ID)
+	}
+
+	// Loading again finds nothing more to migrate
+	saves := store.saves[RollKind("200")]
+	if err := NewRollLog("200").Load(); err != nil {
+		t.Fatal(err)
+	}
+	if store.saves[RollKind("200")] != saves {
+		t.Error("loading migrated rolls saved them again")
+	}
+}
+
+func TestArchiveMovesRolls(t *testing.T) {
+	memoryStorage(t)
+
+	rl := NewRollLog("200")
+	for i := range 3 {
+		if _, err := rl.Record(roll(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Storage.Save("guild", "200", map[string]string{"name": "Harness"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ArchiveGuild("200"); err != nil {
+		t.Fatal(err)
+	}
+	if pages, _ := Storage.List(RollKind("200")); len(pages) != 0 {
+		t.Errorf("roll pages %v left behind after archiving", pages)
+	}
+
+	restored, err := RestoreGuild("200")
+	if err != nil || !restored {
+		t.Fatalf("RestoreGuild() = %v, %v", restored, err)
+	}
+	if pages, _ := Storage.List(RollKind("200")); len(pages) != 1 {
+		t.Fatalf("restored pages %v, want one", pages)
+	}
+	reloaded := NewRollLog("200")
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if rolls := reloaded.Recent(0, nil); len(rolls) != 3 || rolls[2].ID != 3 {
+		t.Errorf("restored rolls %+v, want #1 to #3", rolls)
+	}
+}
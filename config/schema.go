@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+)
+
+/*
+ * Versioning for the persisted guild configuration. Every saved document
+ * carries a schema version; older documents are upgraded one version at a
+ * time by the registered migrations when they are loaded. Keys this version
+ * of the bot doesn't recognize are kept and written back untouched. A
+ * document from a newer build is loaded as best it can be, but never saved.
+ *
+ * Only the guild document is versioned. Pools, characters, sessions, users
+ * and scheduler jobs are deliberately left out: none of them has changed
+ * shape yet, and since a missing version already means version 1, each can
+ * gain a version and migrations of its own the first time it does, without
+ * rewriting what is stored today. Roll history moves its older layouts to
+ * the current one itself when it loads.
+ */
+
+const SchemaVersion = 2 // Version written by this build
+
+var (
+	// ErrNewerSchema is returned when saving a configuration loaded from a newer build.
+	ErrNewerSchema = errors.New("configuration was saved by a newer version of the bot")
+	// ErrUnloadable is returned when changing a configuration that couldn't be read or migrated.
+	ErrUnloadable = errors.New("saved configuration could not be loaded")
+)
+
+// A Migration upgrades a raw guild document from version From to From+1.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(doc map[string]json.RawMessage) error
+}
+
+var migrations = make(map[int]Migration)
+
+// RegisterMigration adds a migration to the registry. Each version may only be migrated from once.
+func RegisterMigration(m Migration) {
+	if _, exists := migrations[m.From]; exists {
+		log.Fatalf("migration from schema version %d is already registered", m.From)
+	}
+	migrations[m.From] = m
+}
+
+// migrateGuild upgrades doc in place to SchemaVersion and returns the version it started at.
+// Documents saved before versioning existed have no version and are treated as version 1.
+// Version 2 is the same shape with the version recorded and unrecognized keys kept.
+func migrateGuild(doc map[string]json.RawMessage) (int, error) {
+	version := 1
+	if raw, ok := doc["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return 0, fmt.Errorf("reading schema version: %w", err)
+		}
+	}
+	from := version
+
+	if version > SchemaVersion {
+		log.Printf("[WARN] Guild configuration has schema version %d, newer than this build supports (%d)", version, SchemaVersion)
+		return from, nil
+	}
+
+	for ; version < SchemaVersion; version++ {
+		m, ok := migrations[version]
+		if !ok {
+			return from, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if Verbose {
+			log.Printf("[VERBOSE] Migrating guild configuration from version %d: %s", version, m.Description)
+		}
+		if err := m.Apply(doc); err != nil {
+			return from, fmt.Errorf("migrating from schema version %d: %w", version, err)
+		}
+	}
+
+	doc["version"], _ = json.Marshal(version)
+	return from, nil
+}
+
+// guildFields are the top-level keys guildJSON understands.
+var guildFields = []string{"version", "id", "name", "config"}
+
+func (gj guildJSON) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(guildFields)+len(gj.Extra))
+	for k, v := range gj.Extra {
+		doc[k] = v
+	}
+	doc["version"] = gj.Version
+	doc["id"] = gj.ID
+	doc["name"] = gj.Name
+	doc["config"] = gj.Config
+	return json.Marshal(doc)
+}
+
+func (gj *guildJSON) UnmarshalJSON(data []byte) error {
+	type plain guildJSON // Drops the methods so decoding doesn't recurse
+	if err := json.Unmarshal(data, (*plain)(gj)); err != nil {
+		return err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for k, v := range doc {
+		if slices.Contains(guildFields, k) {
+			continue
+		}
+		if gj.Extra == nil {
+			gj.Extra = make(map[string]json.RawMessage)
+		}
+		gj.Extra[k] = v
+	}
+	return nil
+}
+
+func init() {
+	RegisterMigration(Migration{
+		From:        1,
+		Description: "record the schema version",
+		Apply: func(doc map[string]json.RawMessage) error {
+			return nil // Unversioned documents already use the version 2 keys, migrateGuild adds the version
+		},
+	})
+}
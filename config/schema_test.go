@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// loadFixture stores a guild document from testdata and loads it into a new guild.
+func loadFixture(t *testing.T, name string) (*Guild, *MemoryStore) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memoryStorage(t)
+	if err := store.Save("guild", "1", json.RawMessage(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewGuild(&discordgo.Guild{
+		ID:   "1",
+		Name: "Old Table",
+		Roles: []*discordgo.Role{
+			{ID: "10", Name: "Admin"},
+			{ID: "20", Name: "GM"},
+			{ID: "30", Name: "Player"},
+		},
+	}), store
+}
+
+func TestMigrateFixtures(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra bool // Whether the fixture has unrecognized keys to keep
+	}{
+		{"guild-unversioned.json", false},
+		{"guild-v2.json", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, store := loadFixture(t, tt.name)
+
+			got := g.GetConfigMap()
+			want := ConfigMap{"prefix": {"?"}, "admins": {"10"}, "gms": {"20"}, "players": {"30"}}
+			if tt.extra {
+				want["theme"] = []string{"dark"}
+			}
+			if len(got) != len(want) {
+				t.Errorf("config = %v, want %v", got, want)
+			}
+			for key, values := range want {
+				if !slices.Equal(got[key], values) {
+					t.Errorf("config %q = %v, want %v", key, got[key], values)
+				}
+			}
+
+			if err := g.Save(); err != nil {
+				t.Fatal(err)
+			}
+			var doc map[string]json.RawMessage
+			if err := store.Load("guild", "1", &doc); err != nil {
+				t.Fatal(err)
+			}
+			if string(doc["version"]) != "2" {
+				t.Errorf("saved version = %s, want %d", doc["version"], SchemaVersion)
+			}
+			if _, ok := doc["notes"]; ok != tt.extra {
+				t.Errorf("saved unknown key %q = %v, want %v: %s", "notes", ok, tt.extra, doc)
+			}
+		})
+	}
+}
+
+func TestNewerSchemaIsNotSaved(t *testing.T) {
+	g, store := loadFixture(t, "guild-v3.json")
+
+	if gms, _ := g.GetRoleConfig("gms"); len(gms) != 1 || gms[0].ID != "20" {
+		t.Errorf("GM roles = %v, a newer configuration should still be read as best it can", gms)
+	}
+
+	if err := g.Save(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Save() = %v, want ErrNewerSchema", err)
+	}
+	if err := g.SetRoleConfig("gms", []*discordgo.Role{{ID: "30", Name: "Player"}}); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("SetRoleConfig() = %v, want ErrNewerSchema", err)
+	}
+
+	var saved json.RawMessage
+	if err := store.Load("guild", "1", &saved); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(filepath.Join("testdata", "guild-v3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := json.Compact(&want, original); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, want.Bytes()) {
+		t.Errorf("the newer document was rewritten:\n got %s\nwant %s", saved, want.Bytes())
+	}
+}
+
+func TestUnloadableIsNotSaved(t *testing.T) {
+	g, store := loadFixture(t, "guild-corrupt.json")
+
+	if err := g.Save(); !errors.Is(err, ErrUnloadable) {
+		t.Errorf("Save() = %v, want ErrUnloadable", err)
+	}
+	if err := g.SetConfigMap(ConfigMap{"prefix": {"!"}}); !errors.Is(err, ErrUnloadable) {
+		t.Errorf("SetConfigMap() = %v, want ErrUnloadable", err)
+	}
+	if err := g.SetRoleConfig("gms", []*discordgo.Role{{ID: "30", Name: "Player"}}); !errors.Is(err, ErrUnloadable) {
+		t.Errorf("SetRoleConfig() = %v, want ErrUnloadable", err)
+	}
+
+	var saved json.RawMessage
+	if err := store.Load("guild", "1", &saved); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(filepath.Join("testdata", "guild-corrupt.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := json.Compact(&want, original); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, want.Bytes()) {
+		t.Errorf("the unloadable document was overwritten:\n got %s\nwant %s", saved, want.Bytes())
+	}
+}
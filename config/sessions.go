@@ -0,0 +1,373 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+ * This package schedules West Marches sessions, per guild. A GM posts a
+ * session with a player limit; players sign up until it is full, after which
+ * they join a waitlist that is promoted in order as players drop out.
+ */
+
+const (
+	MaxSessionPlayers = 50            // Upper limit for a session's player count
+	SessionLength     = 3 * time.Hour // Length shown in calendars for a session that hasn't ended yet
+)
+
+type SessionStatus string
+
+const (
+	SessionOpen      SessionStatus = "open"      // Taking sign-ups
+	SessionClosed    SessionStatus = "closed"    // Sign-ups closed, the session still runs
+	SessionCancelled SessionStatus = "cancelled" // Will not run
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type Session struct {
+	ID         string        `json:"id"`
+	Title      string        `json:"title"`
+	GMID       string        `json:"gm_id"` // Discord user ID of the GM running the session
+	Start      time.Time     `json:"start"`
+	MaxPlayers int           `json:"max_players"`
+	Players    []string      `json:"players"`  // User IDs on the roster, in sign-up order
+	Waitlist   []string      `json:"waitlist"` // User IDs waiting for a place, in sign-up order
+	Left       []string      `json:"left"`     // User IDs who dropped off the roster, so their calendars show the session cancelled
+	Status     SessionStatus `json:"status"`
+	ChannelID  string        `json:"channel_id"`      // Channel holding the announcement message
+	MessageID  string        `json:"message_id"`      // Announcement message with the sign-up buttons
+	Ended      time.Time     `json:"ended,omitempty"` // When the GM ended the session, zero until then
+	Sequence   int           `json:"sequence"`        // Revision of the title, times, status and leavers, for calendar updates
+	Created    time.Time     `json:"created"`
+	Updated    time.Time     `json:"updated"`
+}
+
+// SignUp describes where a join or leave left a player.
+type SignUp int
+
+const (
+	SignUpRoster    SignUp = iota // On the roster
+	SignUpWaitlist                // On the waitlist
+	SignUpUnchanged               // Already signed up
+)
+
+func (s *Session) Full() bool {
+	return len(s.Players) >= s.MaxPlayers
+}
+
+// Open reports whether the session is taking sign-ups.
+func (s *Session) Open() bool {
+	return s.Status == SessionOpen
+}
+
+// Running reports whether the session has started and not been ended or cancelled.
+func (s *Session) Running(now time.Time) bool {
+	return s.Status != SessionCancelled && s.Ended.IsZero() && !now.Before(s.Start)
+}
+
+// End returns when the session ended, or its calendar length after the
+// start while it hasn't.
+func (s *Session) End() time.Time {
+	if !s.Ended.IsZero() {
+		return s.Ended
+	}
+	return s.Start.Add(SessionLength)
+}
+
+type Sessions struct {
+	guildID  string
+	mu       sync.RWMutex
+	next     int
+	sessions map[string]*Session
+}
+
+type sessionsJSON struct {
+	Next     int        `json:"next"`
+	Sessions []*Session `json:"sessions"`
+}
+
+func NewSessions(guildID string) *Sessions {
+	return &Sessions{
+		guildID:  guildID,
+		next:     1,
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Create schedules a new session and assigns its ID.
+func (ss *Sessions) Create(title, gmID string, start time.Time, maxPlayers int) (Session, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return Session{}, errors.New("a session needs a title")
+	}
+	if maxPlayers < 1 || maxPlayers > MaxSessionPlayers {
+		return Session{}, fmt.Errorf("max players must be between 1 and %d", MaxSessionPlayers)
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	now := time.Now()
+	s := &Session{
+		ID:         strconv.Itoa(ss.next),
+		Title:      title,
+		GMID:       gmID,
+		Start:      start,
+		MaxPlayers: maxPlayers,
+		Players:    make([]string, 0, maxPlayers),
+		Waitlist:   make([]string, 0),
+		Left:       make([]string, 0),
+		Status:     SessionOpen,
+		Created:    now,
+		Updated:    now,
+	}
+	ss.sessions[s.ID] = s
+	ss.next++
+
+	return s.clone(), ss.save()
+}
+
+// Get returns a copy of the session.
+func (ss *Sessions) Get(id string) (Session, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	s, ok := ss.sessions[strings.TrimPrefix(strings.TrimSpace(id), "#")]
+	if !ok {
+		return Session{}, fmt.Errorf("%w: #%s", ErrSessionNotFound, id)
+	}
+	return s.clone(), nil
+}
+
+// List returns copies of the sessions ordered by start time. Closed and
+// cancelled sessions are only included when all is set.
+func (ss *Sessions) List(all bool) []Session {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	list := make([]Session, 0, len(ss.sessions))
+	for _, s := range ss.sessions {
+		if all || s.Open() {
+			list = append(list, s.clone())
+		}
+	}
+	slices.SortFunc(list, func(a, b Session) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return list
+}
+
+// Join signs a player up, putting them on the waitlist once the session is full.
+func (ss *Sessions) Join(id, userID string) (Session, SignUp, error) {
+	var result SignUp
+	s, err := ss.Update(id, func(s *Session) error {
+		switch {
+		case slices.Contains(s.Players, userID), slices.Contains(s.Waitlist, userID):
+			result = SignUpUnchanged
+		case !s.Open():
+			return fmt.Errorf("session #%s is %s", s.ID, s.Status)
+		case s.Full():
+			s.Waitlist = append(s.Waitlist, userID)
+			result = SignUpWaitlist
+		default:
+			s.addPlayer(userID)
+			result = SignUpRoster
+		}
+		return nil
+	})
+	return s, result, err
+}
+
+// Leave removes a player from the roster or waitlist. When a place on the
+// roster of an open session opens up, the first player on the waitlist is
+// promoted and returned. Closed and cancelled sessions promote nobody.
+func (ss *Sessions) Leave(id, userID string) (Session, string, error) {
+	var promoted string
+	s, err := ss.Update(id, func(s *Session) error {
+		if i := slices.Index(s.Waitlist, userID); i >= 0 {
+			s.Waitlist = slices.Delete(s.Waitlist, i, i+1)
+			return nil
+		}
+		i := slices.Index(s.Players, userID)
+		if i < 0 {
+			return fmt.Errorf("you aren't signed up for session #%s", s.ID)
+		}
+		s.Players = slices.Delete(s.Players, i, i+1)
+		s.Left = append(s.Left, userID)
+		if s.Open() {
+			promoted = s.promote()
+		}
+		return nil
+	})
+	return s, promoted, err
+}
+
+// Running returns the session running in a channel at now. Starting a
+// session ends any earlier one in the same channel.
+func (ss *Sessions) Running(channelID string, now time.Time) (Session, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	var latest *Session
+	for _, s := range ss.sessions {
+		if s.ChannelID != channelID || s.Status == SessionCancelled || now.Before(s.Start) {
+			continue
+		}
+		if latest == nil || s.Start.After(latest.Start) {
+			latest = s
+		}
+	}
+	if latest == nil || !latest.Running(now) {
+		return Session{}, false
+	}
+	return latest.clone(), true
+}
+
+// End marks a running session as over at now.
+func (ss *Sessions) End(id string, now time.Time) (Session, error) {
+	return ss.Update(id, func(s *Session) error {
+		switch {
+		case s.Status == SessionCancelled:
+			return fmt.Errorf("session #%s is cancelled", s.ID)
+		case !s.Ended.IsZero():
+			return fmt.Errorf("session #%s has already ended", s.ID)
+		case now.Before(s.Start):
+			return fmt.Errorf("session #%s hasn't started yet", s.ID)
+		}
+		s.Ended = now
+		return nil
+	})
+}
+
+// SetStatus closes or cancels a session, or reopens it.
+func (ss *Sessions) SetStatus(id string, status SessionStatus) (Session, error) {
+	return ss.Update(id, func(s *Session) error {
+		s.Status = status
+		return nil
+	})
+}
+
+// SetAnnouncement records the message that carries the session's sign-up buttons.
+func (ss *Sessions) SetAnnouncement(id, channelID, messageID string) (Session, error) {
+	return ss.Update(id, func(s *Session) error {
+		s.ChannelID, s.MessageID = channelID, messageID
+		return nil
+	})
+}
+
+// Update applies fn to the session and saves it if fn succeeds.
+func (ss *Sessions) Update(id string, fn func(s *Session) error) (Session, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	s, ok := ss.sessions[strings.TrimPrefix(strings.TrimSpace(id), "#")]
+	if !ok {
+		return Session{}, fmt.Errorf("%w: #%s", ErrSessionNotFound, id)
+	}
+
+	updated := s.clone()
+	if err := fn(&updated); err != nil {
+		return s.clone(), err
+	}
+	updated.ID = s.ID // Identity never changes
+	if updated.Title != s.Title || !updated.Start.Equal(s.Start) || !updated.Ended.Equal(s.Ended) || updated.Status != s.Status || !slices.Equal(updated.Left, s.Left) {
+		updated.Sequence = s.Sequence + 1
+	}
+	updated.Updated = time.Now()
+	*s = updated
+
+	return s.clone(), ss.save()
+}
+
+func (ss *Sessions) Load() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var sjdata sessionsJSON
+	if err := Storage.Load("sessions", ss.guildID, &sjdata); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil // No sessions yet
+		}
+		log.Printf("Failed to load sessions: %v", err)
+		return err
+	}
+
+	for _, s := range sjdata.Sessions {
+		ss.sessions[s.ID] = s
+	}
+	if sjdata.Next > ss.next {
+		ss.next = sjdata.Next
+	}
+
+	if Verbose {
+		log.Printf("[VERBOSE] Loaded %d sessions for guild %s", len(ss.sessions), ss.guildID)
+	}
+
+	return nil
+}
+
+/*
+ * Private methods for session management, callers must hold the lock
+ */
+
+// promote moves the first waitlisted player onto the roster if there is room, returning them.
+func (s *Session) promote() string {
+	if len(s.Waitlist) == 0 || s.Full() {
+		return ""
+	}
+	promoted := s.Waitlist[0]
+	s.addPlayer(promoted)
+	s.Waitlist = slices.Delete(s.Waitlist, 0, 1)
+	return promoted
+}
+
+// addPlayer puts a player on the roster, and takes them off the list of leavers if they left before.
+func (s *Session) addPlayer(userID string) {
+	s.Players = append(s.Players, userID)
+	s.Left = slices.DeleteFunc(s.Left, func(id string) bool { return id == userID })
+}
+
+func (s *Session) clone() Session {
+	copied := *s
+	copied.Players = slices.Clone(s.Players)
+	copied.Waitlist = slices.Clone(s.Waitlist)
+	copied.Left = slices.Clone(s.Left)
+	return copied
+}
+
+func (ss *Sessions) save() error {
+	sjdata := sessionsJSON{
+		Next:     ss.next,
+		Sessions: make([]*Session, 0, len(ss.sessions)),
+	}
+	for _, s := range ss.sessions {
+		sjdata.Sessions = append(sjdata.Sessions, s)
+	}
+	slices.SortFunc(sjdata.Sessions, func(a, b *Session) int {
+		x, _ := strconv.Atoi(a.ID)
+		y, _ := strconv.Atoi(b.ID)
+		return x - y
+	})
+
+	if err := Storage.Save("sessions", ss.guildID, sjdata); err != nil {
+		log.Printf("Failed to save sessions: %v", err)
+		return err
+	}
+
+	if Debug {
+		log.Printf("[DEBUG] Saved %d sessions for guild %s", len(ss.sessions), ss.guildID)
+	}
+
+	return nil
+}
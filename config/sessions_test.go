@@ -0,0 +1,87 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLeavePromotesOnlyWhenOpen(t *testing.T) {
+	tests := []struct {
+		status   SessionStatus
+		promoted string
+		players  []string
+		waitlist []string
+	}{
+		{SessionOpen, "12", []string{"11", "12"}, []string{"13"}},
+		{SessionClosed, "", []string{"11"}, []string{"12", "13"}},
+		{SessionCancelled, "", []string{"11"}, []string{"12", "13"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			memoryStorage(t)
+
+			ss := NewSessions("1")
+			s, err := ss.Create("Into the Mire", "2", time.Now().Add(24*time.Hour), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{"10", "11", "12", "13"} {
+				if _, _, err := ss.Join(s.ID, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := ss.SetStatus(s.ID, tt.status); err != nil {
+				t.Fatal(err)
+			}
+
+			s, promoted, err := ss.Leave(s.ID, "10")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if promoted != tt.promoted {
+				t.Errorf("promoted %q, want %q", promoted, tt.promoted)
+			}
+			if !slices.Equal(s.Players, tt.players) || !slices.Equal(s.Waitlist, tt.waitlist) {
+				t.Errorf("roster %v and waitlist %v, want %v and %v", s.Players, s.Waitlist, tt.players, tt.waitlist)
+			}
+		})
+	}
+}
+
+func TestRunningSession(t *testing.T) {
+	memoryStorage(t)
+
+	now := time.Now()
+	ss := NewSessions("1")
+	earlier, _ := ss.Create("Earlier", "2", now.Add(-3*time.Hour), 4)
+	later, _ := ss.Create("Later", "2", now.Add(-time.Hour), 4)
+	soon, _ := ss.Create("Soon", "2", now.Add(time.Hour), 4)
+	for _, s := range []Session{earlier, later, soon} {
+		if _, err := ss.SetAnnouncement(s.ID, "30", "40"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s, ok := ss.Running("30", now); !ok || s.ID != later.ID {
+		t.Errorf("running = #%s, %v, want the later session #%s", s.ID, ok, later.ID)
+	}
+	if _, ok := ss.Running("31", now); ok {
+		t.Error("a session is running in a channel none was posted in")
+	}
+
+	if _, err := ss.End(soon.ID, now); err == nil {
+		t.Error("ended a session that hasn't started")
+	}
+	ended, err := ss.End(later.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ended.End().Equal(now) || ended.Sequence == later.Sequence {
+		t.Errorf("ended session ends %v at sequence %d, want %v and a new revision", ended.End(), ended.Sequence, now)
+	}
+	if s, ok := ss.Running("30", now); ok {
+		t.Errorf("after ending #%s, #%s is running, want none as it replaced #%s", later.ID, s.ID, earlier.ID)
+	}
+}
@@ -0,0 +1,441 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+/*
+ * Persistent storage for guild data. Every record is addressed by a kind,
+ * such as "guild" or "pools", and an ID, usually the guild's. Records are
+ * stored as JSON whichever backend is in use.
+ */
+
+const (
+	StorageJSON = "json" // One JSON file per record in the data directory
+	StorageBolt = "bolt" // A single embedded bbolt database in the data directory
+
+	BoltFile = "daggerbot.db" // Name of the bbolt database file
+
+	DefaultBackups = 3 // Previous versions of each JSON file kept as <file>.1 to <file>.N
+)
+
+var ErrNotFound = errors.New("record not found")
+
+type Store interface {
+	Load(kind, id string, v any) error // Decodes the record into v, or returns ErrNotFound
+	Save(kind, id string, v any) error
+	Delete(kind, id string) error // Deleting a missing record is not an error
+	List(kind string) ([]string, error)
+	Close() error
+}
+
+// Storage is the store used by all guild data. It defaults to JSON files in
+// the working directory until OpenStorage is called.
+var Storage Store = NewJSONStore(".")
+
+// OpenStorage opens the named backend in dir and makes it the active Storage.
+func OpenStorage(backend, dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating data directory %q: %w", dir, err)
+	}
+
+	var (
+		store Store
+		err   error
+	)
+	switch cleanString(backend) {
+	case "", StorageJSON:
+		store = NewJSONStore(dir)
+	case StorageBolt, "bbolt":
+		store, err = NewBoltStore(filepath.Join(dir, BoltFile))
+	default:
+		return fmt.Errorf("unknown storage backend %q, use %q or %q", backend, StorageJSON, StorageBolt)
+	}
+	if err != nil {
+		return err
+	}
+
+	if Verbose {
+		log.Printf("[VERBOSE] Using %s storage in %q", cleanString(backend), dir)
+	}
+	Storage = store
+	return nil
+}
+
+// JSONStore keeps each record in its own <kind>_<id>.json file. Files are
+// replaced atomically, and the previous Backups versions are kept alongside.
+type JSONStore struct {
+	Backups int
+
+	dir string
+	mu  sync.Mutex
+}
+
+func NewJSONStore(dir string) *JSONStore {
+	return &JSONStore{dir: dir, Backups: DefaultBackups}
+}
+
+// Load decodes a record. A file that is missing or corrupt falls back to the
+// newest valid backup, so one bad write never loses a guild's data.
+func (s *JSONStore) Load(kind, id string, v any) error {
+	path, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err == nil && json.Valid(data) {
+		return json.Unmarshal(data, v)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	missing := os.IsNotExist(err)
+
+	for n := 1; n <= s.Backups; n++ {
+		backup := backupPath(path, n)
+		data, err := os.ReadFile(backup)
+		if err != nil || !json.Valid(data) {
+			continue
+		}
+		if missing {
+			log.Printf("[ERROR] %s is missing, restoring from backup %s", path, backup)
+		} else {
+			log.Printf("[ERROR] %s is corrupt, restoring from backup %s", path, backup)
+		}
+		return json.Unmarshal(data, v)
+	}
+
+	if missing {
+		return ErrNotFound
+	}
+	log.Printf("[ERROR] %s is corrupt and no valid backup was found", path)
+	return fmt.Errorf("decoding %s: invalid JSON", path)
+}
+
+// Save writes the record to a temporary file, syncs it, rotates the backups
+// and renames it into place, so a crash never leaves a partial file behind.
+func (s *JSONStore) Save(kind, id string, v any) error {
+	path, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := writeTemp(path, append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp) // Fails harmlessly once renamed
+	}()
+
+	if err := s.rotate(path); err != nil {
+		log.Printf("[WARN] Failed to back up %s: %v", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(s.dir)
+}
+
+func (s *JSONStore) Delete(kind, id string) error {
+	path, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := 1; n <= s.Backups; n++ {
+		if err := os.Remove(backupPath(path, n)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JSONStore) List(kind string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, kind+"_*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(matches))
+	for _, m := range matches {
+		id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), kind+"_"), ".json")
+		if validKey(id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+func (s *JSONStore) path(kind, id string) (string, error) {
+	if !validKey(kind) || !validKey(id) {
+		return "", fmt.Errorf("invalid storage key %q/%q", kind, id)
+	}
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%s.json", kind, id)), nil
+}
+
+// rotate shifts the existing backups up by one and copies the current file
+// to backup 1. The oldest backup beyond Backups is discarded.
+func (s *JSONStore) rotate(path string) error {
+	if s.Backups < 1 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Nothing to back up yet
+		}
+		return err
+	}
+	if !json.Valid(data) {
+		return nil // Never let a corrupt file push out a good backup
+	}
+
+	for n := s.Backups - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Written like the record itself, so a crash mid-rotation can't leave a
+	// partial newest backup behind
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp) // Fails harmlessly once renamed
+	}()
+	return os.Rename(tmp, backupPath(path, 1))
+}
+
+// writeTemp writes data to a synced temporary file next to path and returns
+// its name, ready to be renamed into place.
+func writeTemp(path string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// syncDir flushes a directory so a rename within it survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			log.Printf("Failed to close directory: %v", err)
+		}
+	}()
+	return d.Sync()
+}
+
+// BoltStore keeps every record in one bbolt database, with a bucket per kind.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Load(kind, id string, v any) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(kind))
+		if bucket == nil {
+			return ErrNotFound
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("decoding %s/%s: %w", kind, id, err)
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) Save(kind, id string, v any) error {
+	if !validKey(kind) || !validKey(id) {
+		return fmt.Errorf("invalid storage key %q/%q", kind, id)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(kind))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), data)
+	})
+}
+
+func (s *BoltStore) Delete(kind, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(kind))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) List(kind string) ([]string, error) {
+	ids := make([]string, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(kind))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, _ []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	return ids, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// MemoryStore keeps records in memory, encoded as JSON like the other
+// backends. Nothing survives a restart, so it is meant for tests and harnesses.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]map[string][]byte)}
+}
+
+func (s *MemoryStore) Load(kind, id string, v any) error {
+	s.mu.Lock()
+	data, ok := s.records[kind][id]
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding %s/%s: %w", kind, id, err)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Save(kind, id string, v any) error {
+	if !validKey(kind) || !validKey(id) {
+		return fmt.Errorf("invalid storage key %q/%q", kind, id)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records[kind] == nil {
+		s.records[kind] = make(map[string][]byte)
+	}
+	s.records[kind][id] = data
+	return nil
+}
+
+func (s *MemoryStore) Delete(kind, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records[kind], id)
+	return nil
+}
+
+func (s *MemoryStore) List(kind string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.records[kind]))
+	for id := range s.records[kind] {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// validKey reports whether a kind or ID is safe to use as part of a file name.
+func validKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, `/\_`) && key != "." && key != ".."
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONStoreRotatesBackups(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := NewJSONStore(dir)
+	store.Backups = 2
+
+	for _, v := range []string{"one", "two", "three"} {
+		if err := store.Save("guild", "1", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "guild_1.json")
+	for file, want := range map[string]string{path: "three", path + ".1": "two", path + ".2": "one"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(data)); got != `"`+want+`"` {
+			t.Errorf("%s = %s, want %q", filepath.Base(file), got, want)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("files left behind: %v", names)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+ * Per-user settings that follow a player across guilds, such as the time zone
+ * their scheduling commands are read in. Each user is stored as its own record.
+ */
+
+type UserSettings struct {
+	ID       string `json:"id"`
+	TimeZone string `json:"timezone"` // IANA zone name, empty for UTC
+}
+
+// Location returns the user's time zone, falling back to UTC.
+func (u UserSettings) Location() *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+type UserStore struct {
+	mu    sync.Mutex
+	users map[string]*UserSettings
+}
+
+var Users = NewUserStore() // Settings for every user the bot has seen
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: make(map[string]*UserSettings)}
+}
+
+// Get returns a copy of the user's settings, loading them on first use.
+func (us *UserStore) Get(userID string) UserSettings {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return *us.load(userID)
+}
+
+// SetTimeZone validates and stores the user's time zone. An empty name resets it to UTC.
+func (us *UserStore) SetTimeZone(userID, name string) (UserSettings, error) {
+	name = strings.TrimSpace(name)
+	if name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil || strings.EqualFold(name, "local") {
+			return UserSettings{}, fmt.Errorf("%q is not a time zone I know, try a name like Europe/Berlin", name)
+		}
+		name = loc.String()
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	u := us.load(userID)
+	u.TimeZone = name
+	return *u, us.save(u)
+}
+
+/*
+ * Private methods for user settings, callers must hold the lock
+ */
+
+func (us *UserStore) load(userID string) *UserSettings {
+	if u, ok := us.users[userID]; ok {
+		return u
+	}
+
+	u := &UserSettings{ID: userID}
+	if err := Storage.Load("users", userID, u); err != nil && !errors.Is(err, ErrNotFound) {
+		log.Printf("Failed to load settings for user %s: %v", userID, err)
+	}
+	u.ID = userID
+	us.users[userID] = u
+	return u
+}
+
+func (us *UserStore) save(u *UserSettings) error {
+	if err := Storage.Save("users", u.ID, u); err != nil {
+		log.Printf("Failed to save settings for user %s: %v", u.ID, err)
+		return err
+	}
+	if Debug {
+		log.Printf("[DEBUG] Saved settings for user %s", u.ID)
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
+	"os/signal"
 
 	"github.com/joho/godotenv"
 	"github.com/nerdwerx/daggerbot/bot"
@@ -11,8 +13,12 @@ import (
 )
 
 func main() {
-	if err := bot.Run(); err != nil {
-		log.Fatal("Error starting bot:", err)
+	// keep bot running untill we're interrupted (ctrl + C)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := bot.Run(ctx); err != nil {
+		log.Fatal("Error running bot: ", err)
 	}
 }
 